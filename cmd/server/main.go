@@ -14,8 +14,12 @@ import (
 	"firegoals/internal/config"
 	"firegoals/internal/db"
 	api "firegoals/internal/http"
+	"firegoals/internal/jobs"
+	"firegoals/internal/notify"
 	"firegoals/internal/repo"
 	"firegoals/internal/service"
+	"firegoals/internal/storage"
+	"firegoals/internal/webpush"
 )
 
 func main() {
@@ -36,7 +40,20 @@ func main() {
 	repository := repo.New(pool)
 	svc := service.New(repository, authManager)
 
-	handler := &api.API{Repo: repository, Service: svc, Auth: authManager, Origins: parseOrigins(cfg.CORSOrigin)}
+	handler := &api.API{Repo: repository, Service: svc, Auth: authManager, Origins: parseOrigins(cfg.CORSOrigin), MaxUploadBytes: cfg.MaxUploadBytes, PublicURL: cfg.PublicURL}
+	if cfg.S3Bucket != "" {
+		handler.Storage = storage.S3{Client: &http.Client{Timeout: time.Minute}, Endpoint: cfg.S3Endpoint, Bucket: cfg.S3Bucket, Region: cfg.S3Region, AccessKeyID: cfg.S3AccessKeyID, SecretAccessKey: cfg.S3SecretAccessKey}
+	} else {
+		handler.Storage = storage.Local{Dir: cfg.UploadDir}
+	}
+	var pushSender *webpush.Sender
+	if cfg.VAPIDSubject != "" {
+		pushSender, err = vapidSender(ctx, repository, cfg)
+		if err != nil {
+			log.Fatalf("failed to load vapid keys: %v", err)
+		}
+		handler.VAPIDPublicKey = pushSender.VAPIDPublicKey
+	}
 
 	srv := &http.Server{
 		Addr:              ":" + cfg.Port,
@@ -44,6 +61,23 @@ func main() {
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+	channels := map[string]notify.Channel{"webhook": notify.WebhookChannel{}}
+	if cfg.SMTPAddr != "" {
+		channels["email"] = notify.EmailChannel{Mailer: notify.SMTPMailer{Addr: cfg.SMTPAddr, From: cfg.SMTPFrom, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword}}
+	}
+	if pushSender != nil {
+		channels["webpush"] = notify.WebPushChannel{Store: repository, Sender: pushSender}
+	}
+	dispatcher := &notify.Dispatcher{Outbox: repository, Channels: channels}
+	builtinJobs, err := jobs.Builtin(repository, jobs.Settings{PenaltyInterval: cfg.PenaltyInterval, PurgeRetention: cfg.PurgeRetention, Dispatcher: dispatcher})
+	if err != nil {
+		log.Fatalf("failed to configure jobs: %v", err)
+	}
+	runner := &jobs.Runner{Repo: repository, Jobs: builtinJobs}
+	go runner.Run(jobsCtx)
+
 	go func() {
 		log.Printf("server listening on %s", cfg.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -54,6 +88,7 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
+	stopJobs()
 
 	ctxShutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -62,6 +97,23 @@ func main() {
 	}
 }
 
+// vapidSender uses the configured VAPID key pair, or the one stored in the
+// database, generating it on first start.
+func vapidSender(ctx context.Context, repository *repo.Repo, cfg config.Config) (*webpush.Sender, error) {
+	publicKey, privateKey := cfg.VAPIDPublicKey, cfg.VAPIDPrivateKey
+	if publicKey == "" {
+		generatedPublic, generatedPrivate, err := webpush.GenerateVAPIDKeys()
+		if err != nil {
+			return nil, err
+		}
+		publicKey, privateKey, err = repository.EnsureVAPIDKeys(ctx, generatedPublic, generatedPrivate)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &webpush.Sender{VAPIDPublicKey: publicKey, VAPIDPrivateKey: privateKey, Subject: cfg.VAPIDSubject}, nil
+}
+
 func parseOrigins(raw string) []string {
 	if raw == "" {
 		return nil
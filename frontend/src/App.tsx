@@ -141,8 +141,7 @@ export default function App() {
   }
 
   async function refreshPurchases(workspaceId: string) {
-    const data = await listRewardPurchases(workspaceId);
-    setPurchases(data.purchases ?? []);
+    setPurchases(await listRewardPurchases(workspaceId));
   }
 
   async function refreshMembers(workspaceId: string) {
@@ -242,7 +241,14 @@ export default function App() {
       await updateTask(snapshot.workspaceId, task.id, {
         title: task.title,
         description: task.description,
-        value: task.value
+        value: task.value,
+        due_date: task.due_date,
+        status: task.status,
+        is_recurring: task.is_recurring,
+        recurrence_weekdays: task.recurrence_weekdays,
+        start_date: task.start_date,
+        end_date: task.end_date,
+        timezone: task.timezone
       });
     } catch (error) {
       setStatus("Не удалось сохранить задачу");
@@ -212,8 +212,20 @@ export async function listTaskInstances(workspaceId: string, from: string, to: s
   );
 }
 
-export async function listRewardPurchases(workspaceId: string) {
-  return apiFetch<{ purchases: RewardPurchase[] }>(`/rewards/purchases?workspace_id=${workspaceId}`);
+type Page<T> = { items: T[]; next_cursor: string | null; total: number };
+
+export async function listRewardPurchases(workspaceId: string): Promise<RewardPurchase[]> {
+  const purchases: RewardPurchase[] = [];
+  let cursor: string | null = null;
+  do {
+    const query: string = cursor ? `&cursor=${encodeURIComponent(cursor)}` : "";
+    const page: Page<RewardPurchase> = await apiFetch<Page<RewardPurchase>>(
+      `/rewards/purchases?workspace_id=${workspaceId}&limit=200${query}`
+    );
+    purchases.push(...(page.items ?? []));
+    cursor = page.next_cursor;
+  } while (cursor);
+  return purchases;
 }
 
 export async function updateSettings(settings: { theme: string; last_active_workspace?: string | null }) {
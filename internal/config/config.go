@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -10,6 +13,35 @@ type Config struct {
 	JWTSecret   string
 	Port        string
 	CORSOrigin  string
+	// PublicURL is the externally reachable base URL of the API, used in
+	// calendar feed links; derived from each request when empty.
+	PublicURL string
+
+	// PenaltyInterval is how often missed occurrences are checked for penalties.
+	PenaltyInterval time.Duration
+	// PurgeRetention is how long soft-deleted rows are kept before being purged.
+	PurgeRetention time.Duration
+
+	// SMTP settings for email notifications; email is disabled without SMTPAddr.
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
+
+	// Web Push settings; push is disabled without VAPIDSubject. Without a
+	// configured key pair one is generated and stored in the database.
+	VAPIDSubject    string
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+
+	// Uploads are stored in S3Bucket when set, otherwise under UploadDir.
+	UploadDir         string
+	MaxUploadBytes    int64
+	S3Endpoint        string
+	S3Bucket          string
+	S3Region          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
 }
 
 func Load() Config {
@@ -18,6 +50,59 @@ func Load() Config {
 		JWTSecret:   os.Getenv("JWT_SECRET"),
 		Port:        os.Getenv("PORT"),
 		CORSOrigin:  os.Getenv("CORS_ORIGIN"),
+		PublicURL:   strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"),
+
+		SMTPAddr:     os.Getenv("SMTP_ADDR"),
+		SMTPFrom:     os.Getenv("SMTP_FROM"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+
+		VAPIDSubject:    os.Getenv("VAPID_SUBJECT"),
+		VAPIDPublicKey:  os.Getenv("VAPID_PUBLIC_KEY"),
+		VAPIDPrivateKey: os.Getenv("VAPID_PRIVATE_KEY"),
+
+		UploadDir:         os.Getenv("UPLOAD_DIR"),
+		S3Endpoint:        os.Getenv("S3_ENDPOINT"),
+		S3Bucket:          os.Getenv("S3_BUCKET"),
+		S3Region:          os.Getenv("S3_REGION"),
+		S3AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+	}
+	if (cfg.VAPIDPublicKey == "") != (cfg.VAPIDPrivateKey == "") {
+		log.Fatal("VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY must be set together")
+	}
+	cfg.PenaltyInterval = 15 * time.Minute
+	if raw := os.Getenv("PENALTY_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval <= 0 {
+			log.Fatalf("invalid PENALTY_INTERVAL %q", raw)
+		}
+		cfg.PenaltyInterval = interval
+	}
+	cfg.PurgeRetention = 30 * 24 * time.Hour
+	if raw := os.Getenv("PURGE_RETENTION_DAYS"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 1 {
+			log.Fatalf("invalid PURGE_RETENTION_DAYS %q", raw)
+		}
+		cfg.PurgeRetention = time.Duration(days) * 24 * time.Hour
+	}
+	if cfg.UploadDir == "" {
+		cfg.UploadDir = "data/uploads"
+	}
+	cfg.MaxUploadBytes = 10 << 20
+	if raw := os.Getenv("UPLOAD_MAX_MB"); raw != "" {
+		mb, err := strconv.Atoi(raw)
+		if err != nil || mb < 1 {
+			log.Fatalf("invalid UPLOAD_MAX_MB %q", raw)
+		}
+		cfg.MaxUploadBytes = int64(mb) << 20
+	}
+	if cfg.S3Region == "" {
+		cfg.S3Region = "us-east-1"
+	}
+	if cfg.S3Bucket != "" && cfg.S3Endpoint == "" {
+		cfg.S3Endpoint = "https://s3." + cfg.S3Region + ".amazonaws.com"
 	}
 	if cfg.Port == "" {
 		cfg.Port = "8080"
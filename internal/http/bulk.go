@@ -0,0 +1,165 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/repo"
+)
+
+// maxBulkOperations bounds the operations of one bulk request.
+const maxBulkOperations = 200
+
+type bulkRequest struct {
+	WorkspaceID string          `json:"workspace_id"`
+	Operations  []bulkOperation `json:"operations"`
+}
+
+// bulkOperation is one step of a bulk request. Op selects which of the other
+// fields apply: complete (occurrence_date, force), delete, move (goal_id, null
+// detaches), retag (tag_ids) and reschedule (due_date or snooze).
+type bulkOperation struct {
+	Op             string   `json:"op"`
+	TaskID         string   `json:"task_id"`
+	OccurrenceDate string   `json:"occurrence_date"`
+	Force          bool     `json:"force"`
+	GoalID         *string  `json:"goal_id"`
+	TagIDs         []string `json:"tag_ids"`
+	DueDate        string   `json:"due_date"`
+	Snooze         string   `json:"snooze"`
+}
+
+type bulkResult struct {
+	Index     int      `json:"index"`
+	Op        string   `json:"op"`
+	TaskID    string   `json:"task_id"`
+	Status    string   `json:"status"`
+	Earned    *float64 `json:"earned,omitempty"`
+	Completed *bool    `json:"completed,omitempty"`
+	Code      string   `json:"code,omitempty"`
+	Message   string   `json:"message,omitempty"`
+}
+
+// errBulkValidation marks an operation rejected before reaching the repo.
+type errBulkValidation string
+
+func (e errBulkValidation) Error() string { return string(e) }
+
+// handleBulkTasks applies each operation on its own, in order, exactly as the
+// single-task endpoint would, and reports a result per operation. A failed
+// operation does not roll back the others; completing twice earns once.
+func (a *API) handleBulkTasks(w http.ResponseWriter, r *http.Request) {
+	var req bulkRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBulkOperations {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Between 1 and 200 operations required")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	results := make([]bulkResult, 0, len(req.Operations))
+	earned, failed := 0.0, 0
+	for i, op := range req.Operations {
+		result := bulkResult{Index: i, Op: op.Op, TaskID: op.TaskID, Status: "ok"}
+		value, completed, err := a.applyBulkOperation(r.Context(), req.WorkspaceID, userID, op)
+		if err != nil {
+			result.Status = "error"
+			result.Code, result.Message = bulkError(err)
+			failed++
+		} else if op.Op == "complete" {
+			result.Earned, result.Completed = &value, &completed
+			earned += value
+		}
+		results = append(results, result)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"results": results, "succeeded": len(results) - failed, "failed": failed, "earned": earned,
+	})
+}
+
+func (a *API) applyBulkOperation(ctx context.Context, workspaceID, userID string, op bulkOperation) (float64, bool, error) {
+	if op.TaskID == "" {
+		return 0, false, errBulkValidation("task_id required")
+	}
+	switch op.Op {
+	case "complete":
+		var occurrenceDate *time.Time
+		if op.OccurrenceDate != "" {
+			parsed, err := time.Parse("2006-01-02", op.OccurrenceDate)
+			if err != nil {
+				return 0, false, errBulkValidation("Invalid occurrence date")
+			}
+			occurrenceDate = &parsed
+		}
+		return a.Repo.CompleteTask(ctx, op.TaskID, workspaceID, userID, occurrenceDate, op.Force)
+	case "delete":
+		return 0, false, a.Repo.DeleteTask(ctx, op.TaskID, workspaceID)
+	case "move":
+		return 0, false, a.Repo.MoveTask(ctx, op.TaskID, workspaceID, op.GoalID)
+	case "retag":
+		valid, err := a.Repo.AreWorkspaceTags(ctx, workspaceID, op.TagIDs)
+		if err != nil {
+			return 0, false, err
+		}
+		if !valid {
+			return 0, false, errBulkValidation("Tags must belong to the workspace")
+		}
+		return 0, false, a.Repo.SetTaskTags(ctx, op.TaskID, workspaceID, op.TagIDs)
+	case "reschedule":
+		if op.Snooze != "" {
+			if !slices.Contains(repo.SnoozePresets, op.Snooze) {
+				return 0, false, errBulkValidation("snooze must be tomorrow or next_week")
+			}
+			_, err := a.Repo.SnoozeTask(ctx, op.TaskID, workspaceID, userID, op.Snooze, time.Now())
+			return 0, false, err
+		}
+		dueDate, err := time.Parse("2006-01-02", op.DueDate)
+		if err != nil {
+			return 0, false, errBulkValidation("due_date must be YYYY-MM-DD")
+		}
+		_, err = a.Repo.RescheduleTask(ctx, op.TaskID, workspaceID, userID, dueDate)
+		return 0, false, err
+	default:
+		return 0, false, errBulkValidation("op must be complete, delete, move, retag or reschedule")
+	}
+}
+
+// bulkError maps an operation error to the code and message the single-task
+// endpoints use.
+func bulkError(err error) (string, string) {
+	var validation errBulkValidation
+	switch {
+	case errors.As(err, &validation):
+		return "VALIDATION_ERROR", validation.Error()
+	case errors.Is(err, repo.ErrNotFound):
+		return "NOT_FOUND", "Task not found"
+	case errors.Is(err, repo.ErrOccurrenceDate):
+		return "VALIDATION_ERROR", "Occurrence date required"
+	case errors.Is(err, repo.ErrNotAssignee):
+		return "NOT_ASSIGNEE", "Only assignees or owners can complete this task"
+	case errors.Is(err, repo.ErrChecklistOpen):
+		return "CHECKLIST_INCOMPLETE", "All checklist items must be checked first"
+	case errors.Is(err, repo.ErrBlocked):
+		return "TASK_BLOCKED", "Tasks blocking this one are not done; pass force to complete anyway"
+	case errors.Is(err, repo.ErrInvalidGoal):
+		return "VALIDATION_ERROR", "goal_id must be a goal of the workspace"
+	case errors.Is(err, repo.ErrRecurringTask):
+		return "VALIDATION_ERROR", "Recurring tasks have no due date to reschedule"
+	case errors.Is(err, repo.ErrTaskDone):
+		return "TASK_DONE", "Done tasks cannot be rescheduled"
+	default:
+		return "INTERNAL_ERROR", "Operation failed"
+	}
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type checklistItemRequest struct {
+	WorkspaceID string  `json:"workspace_id"`
+	Title       string  `json:"title"`
+	Value       float64 `json:"value"`
+}
+
+type checklistOrderRequest struct {
+	WorkspaceID string   `json:"workspace_id"`
+	ItemIDs     []string `json:"item_ids"`
+}
+
+type checklistToggleRequest struct {
+	WorkspaceID string `json:"workspace_id"`
+	Done        bool   `json:"done"`
+}
+
+func (a *API) handleListChecklist(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	items, err := a.Repo.ListChecklistItems(r.Context(), taskID, workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list checklist")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (a *API) handleCreateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	var req checklistItemRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Title == "" || req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id and title required")
+		return
+	}
+	if req.Value < 0 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Value must not be negative")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	id, err := a.Repo.CreateChecklistItem(r.Context(), taskID, req.WorkspaceID, req.Title, req.Value)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Task not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create checklist item")
+		return
+	}
+	writeJSON(w, http.StatusCreated, entityResponse{ID: id})
+}
+
+func (a *API) handleReorderChecklist(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	var req checklistOrderRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	if err := a.Repo.ReorderChecklistItems(r.Context(), taskID, req.WorkspaceID, req.ItemIDs); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Checklist item not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to reorder checklist")
+		return
+	}
+	writeJSON(w, http.StatusOK, entityResponse{ID: taskID})
+}
+
+func (a *API) handleToggleChecklistItem(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	itemID := chi.URLParam(r, "itemID")
+	var req checklistToggleRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	amount, changed, err := a.Repo.ToggleChecklistItem(r.Context(), itemID, taskID, req.WorkspaceID, userID, req.Done)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Checklist item not found")
+		case errors.Is(err, repo.ErrBalanceSpent):
+			writeError(w, http.StatusConflict, "BALANCE_SPENT", "Огоньки за пункт уже потрачены")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update checklist item")
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"done": req.Done, "changed": changed, "earned": amount})
+}
+
+func (a *API) handleDeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	itemID := chi.URLParam(r, "itemID")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	if err := a.Repo.DeleteChecklistItem(r.Context(), itemID, taskID, workspaceID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Checklist item not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to delete checklist item")
+		return
+	}
+	writeJSON(w, http.StatusOK, entityResponse{ID: itemID})
+}
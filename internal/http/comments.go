@@ -0,0 +1,135 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxCommentLength bounds a comment body in bytes.
+const maxCommentLength = 10000
+
+type commentRequest struct {
+	WorkspaceID string `json:"workspace_id"`
+	Body        string `json:"body"`
+}
+
+// The comment handlers serve both /tasks/{id}/comments and /goals/{id}/comments;
+// entityType selects which.
+
+func (a *API) handleListComments(entityType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entityID := chi.URLParam(r, "id")
+		workspaceID := r.URL.Query().Get("workspace_id")
+		if !a.authorizeWorkspace(w, r, workspaceID) {
+			return
+		}
+		comments, err := a.Repo.ListComments(r.Context(), workspaceID, entityType, entityID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list comments")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"comments": comments})
+	}
+}
+
+func (a *API) handleCreateComment(entityType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entityID := chi.URLParam(r, "id")
+		req, ok := decodeCommentRequest(w, r)
+		if !ok || !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+			return
+		}
+		userID, _ := auth.UserIDFromContext(r.Context())
+		id, err := a.Repo.CreateComment(r.Context(), req.WorkspaceID, entityType, entityID, userID, req.Body)
+		if err != nil {
+			writeCommentError(w, err, "Failed to create comment")
+			return
+		}
+		writeJSON(w, http.StatusCreated, entityResponse{ID: id})
+	}
+}
+
+func (a *API) handleUpdateComment(entityType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entityID := chi.URLParam(r, "id")
+		commentID := chi.URLParam(r, "commentID")
+		req, ok := decodeCommentRequest(w, r)
+		if !ok || !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+			return
+		}
+		userID, _ := auth.UserIDFromContext(r.Context())
+		if err := a.Repo.UpdateComment(r.Context(), commentID, req.WorkspaceID, entityType, entityID, userID, req.Body); err != nil {
+			writeCommentError(w, err, "Failed to update comment")
+			return
+		}
+		writeJSON(w, http.StatusOK, entityResponse{ID: commentID})
+	}
+}
+
+func (a *API) handleDeleteComment(entityType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entityID := chi.URLParam(r, "id")
+		commentID := chi.URLParam(r, "commentID")
+		workspaceID := r.URL.Query().Get("workspace_id")
+		if !a.authorizeWorkspace(w, r, workspaceID) {
+			return
+		}
+		userID, _ := auth.UserIDFromContext(r.Context())
+		if err := a.Repo.DeleteComment(r.Context(), commentID, workspaceID, entityType, entityID, userID); err != nil {
+			writeCommentError(w, err, "Failed to delete comment")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
+func (a *API) handleListCommentEdits(entityType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entityID := chi.URLParam(r, "id")
+		commentID := chi.URLParam(r, "commentID")
+		workspaceID := r.URL.Query().Get("workspace_id")
+		if !a.authorizeWorkspace(w, r, workspaceID) {
+			return
+		}
+		edits, err := a.Repo.ListCommentEdits(r.Context(), commentID, workspaceID, entityType, entityID)
+		if err != nil {
+			writeCommentError(w, err, "Failed to list comment history")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"edits": edits})
+	}
+}
+
+func decodeCommentRequest(w http.ResponseWriter, r *http.Request) (commentRequest, bool) {
+	var req commentRequest
+	if !decodeJSON(w, r, &req) {
+		return req, false
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.WorkspaceID == "" || req.Body == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id and body required")
+		return req, false
+	}
+	if len(req.Body) > maxCommentLength {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Comment is too long")
+		return req, false
+	}
+	return req, true
+}
+
+func writeCommentError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "Not found")
+	case errors.Is(err, repo.ErrNotAuthor):
+		writeError(w, http.StatusForbidden, "NOT_AUTHOR", "Only the author can change this comment")
+	default:
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", message)
+	}
+}
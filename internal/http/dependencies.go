@@ -0,0 +1,28 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *API) handleGoalTaskOrder(w http.ResponseWriter, r *http.Request) {
+	goalID := chi.URLParam(r, "id")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	tasks, err := a.Repo.GoalTaskOrder(r.Context(), goalID, workspaceID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Goal not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to order tasks")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tasks": tasks})
+}
@@ -0,0 +1,228 @@
+package http
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/ical"
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// feedPast and feedFuture bound the instances rendered into a feed.
+	feedPast   = 90 * 24 * time.Hour
+	feedFuture = 365 * 24 * time.Hour
+	feedProdID = "-//FireGoals//Tasks//EN"
+)
+
+// icalWeekdays maps recurrence_weekdays (0 = Sunday) to RRULE BYDAY codes.
+var icalWeekdays = [7]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func (a *API) handleGetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	feed, err := a.Repo.GetCalendarFeed(r.Context(), workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Calendar feed not enabled")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to load calendar feed")
+		return
+	}
+	writeJSON(w, http.StatusOK, feed)
+}
+
+// handleCreateCalendarFeed issues a new feed token; an existing one stops
+// working. The token is only shown in this response.
+func (a *API) handleCreateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	data, err := randomBytes(32)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to generate token")
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(data)
+	createdAt, err := a.Repo.SetCalendarFeed(r.Context(), workspaceID, userID, token)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create calendar feed")
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"workspace_id": workspaceID,
+		"token":        token,
+		"url":          a.publicURL(r) + "/feeds/" + token + ".ics",
+		"created_at":   createdAt,
+	})
+}
+
+func (a *API) handleDeleteCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	if err := a.Repo.DeleteCalendarFeed(r.Context(), workspaceID, userID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Calendar feed not enabled")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to revoke calendar feed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleCalendarFeed serves the feed to calendar clients, which cannot send
+// a bearer token; the secret in the URL identifies the member instead.
+func (a *API) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	feed, err := a.Repo.CalendarFeedByToken(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Calendar feed not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to load calendar feed")
+		return
+	}
+	r = r.WithContext(auth.WithUserID(r.Context(), feed.UserID))
+	filter, ok := a.parseTaskFilter(w, r, feed.WorkspaceID)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	instances, err := a.Repo.ListTaskInstances(r.Context(), feed.WorkspaceID, today.Add(-feedPast), today.Add(feedFuture), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list tasks")
+		return
+	}
+	cal := taskCalendar(feed.WorkspaceName, instances, r.URL.Query().Get("type") == "todos", now)
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	_ = cal.Encode(w)
+}
+
+// publicURL is the base of links handed out to clients.
+func (a *API) publicURL(r *http.Request) string {
+	if a.PublicURL != "" {
+		return a.PublicURL
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// taskCalendar renders task instances as all-day VEVENTs, or VTODOs when
+// todos is set. A task keeps the same UID across feeds and refreshes. A
+// recurring task becomes one component with an RRULE; its skipped
+// occurrences are excluded with EXDATE and done ones are overridden with a
+// RECURRENCE-ID component so clients can show them as completed.
+func taskCalendar(name string, instances []map[string]any, todos bool, now time.Time) ical.Calendar {
+	kind := "VEVENT"
+	if todos {
+		kind = "VTODO"
+	}
+	cal := ical.Calendar{ProdID: feedProdID, Name: name}
+
+	var order []string
+	byTask := map[string][]map[string]any{}
+	for _, instance := range instances {
+		id, _ := instance["id"].(string)
+		if _, seen := byTask[id]; !seen {
+			order = append(order, id)
+		}
+		byTask[id] = append(byTask[id], instance)
+	}
+
+	for _, id := range order {
+		occurrences := byTask[id]
+		task := occurrences[0]
+		uid := id + "@firegoals"
+		if recurring, _ := task["is_recurring"].(bool); !recurring {
+			date, _ := time.Parse("2006-01-02", task["occurrence_date"].(string))
+			status, _ := task["occurrence_status"].(string)
+			doneAt, _ := task["done_at"].(*time.Time)
+			cal.Components = append(cal.Components, ical.Component{Name: kind, Props: instanceProps(task, uid, date, status, doneAt, todos, now)})
+			continue
+		}
+
+		start, _ := time.Parse("2006-01-02", task["occurrence_date"].(string))
+		props := instanceProps(task, uid, start, "pending", nil, todos, now)
+		props = append(props, ical.Raw("RRULE", recurrenceRule(task)))
+		var overrides []ical.Component
+		for _, occurrence := range occurrences {
+			date, _ := time.Parse("2006-01-02", occurrence["occurrence_date"].(string))
+			switch occurrence["occurrence_status"] {
+			case "skipped":
+				props = append(props, ical.Date("EXDATE", date))
+			case "done":
+				override := instanceProps(occurrence, uid, date, "done", nil, todos, now)
+				override = append(override, ical.Date("RECURRENCE-ID", date))
+				overrides = append(overrides, ical.Component{Name: kind, Props: override})
+			}
+		}
+		cal.Components = append(cal.Components, ical.Component{Name: kind, Props: props})
+		cal.Components = append(cal.Components, overrides...)
+	}
+	return cal
+}
+
+// instanceProps are the properties shared by one-off tasks, recurring masters
+// and their overrides.
+func instanceProps(task map[string]any, uid string, date time.Time, status string, doneAt *time.Time, todos bool, now time.Time) []ical.Prop {
+	title, _ := task["title"].(string)
+	props := []ical.Prop{ical.Raw("UID", uid), ical.DateTime("DTSTAMP", now)}
+	if todos {
+		props = append(props, ical.Date("DTSTART", date), ical.Date("DUE", date.AddDate(0, 0, 1)), ical.Text("SUMMARY", title))
+		if status == "done" {
+			props = append(props, ical.Raw("STATUS", "COMPLETED"))
+			if doneAt != nil {
+				props = append(props, ical.DateTime("COMPLETED", *doneAt))
+			}
+		} else {
+			props = append(props, ical.Raw("STATUS", "NEEDS-ACTION"))
+		}
+	} else {
+		if status == "done" {
+			title = "✓ " + title
+		}
+		props = append(props, ical.Date("DTSTART", date), ical.Date("DTEND", date.AddDate(0, 0, 1)), ical.Text("SUMMARY", title), ical.Raw("TRANSP", "TRANSPARENT"))
+	}
+	if description, _ := task["description"].(string); strings.TrimSpace(description) != "" {
+		props = append(props, ical.Text("DESCRIPTION", description))
+	}
+	return props
+}
+
+// recurrenceRule expresses a weekly recurring task as an RRULE.
+func recurrenceRule(task map[string]any) string {
+	weekdays, _ := task["recurrence_weekdays"].([]int)
+	days := make([]string, 0, len(weekdays))
+	for _, day := range weekdays {
+		if day >= 0 && day < len(icalWeekdays) {
+			days = append(days, icalWeekdays[day])
+		}
+	}
+	rule := "FREQ=WEEKLY;BYDAY=" + strings.Join(days, ",")
+	if endDate, _ := task["end_date"].(*time.Time); endDate != nil {
+		rule += ";UNTIL=" + ical.FormatDate(*endDate)
+	}
+	return rule
+}
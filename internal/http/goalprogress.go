@@ -0,0 +1,28 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *API) handleGoalProgress(w http.ResponseWriter, r *http.Request) {
+	goalID := chi.URLParam(r, "id")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	progress, err := a.Repo.GoalProgress(r.Context(), goalID, workspaceID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Goal not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to compute goal progress")
+		return
+	}
+	writeJSON(w, http.StatusOK, progress)
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -109,6 +110,39 @@ type taskRequest struct {
 	Timezone    *string    `json:"timezone"`
 	Value       float64    `json:"value"`
 	Status      string     `json:"status"`
+
+	// AssigneeIDs, TagIDs, BlockedBy and AttachmentIDs replace the task's
+	// assignees, tags, blockers and attachments when present; omit them to
+	// keep the current lists.
+	AssigneeIDs        []string `json:"assignee_ids"`
+	TagIDs             []string `json:"tag_ids"`
+	BlockedBy          []string `json:"blocked_by"`
+	AttachmentIDs      []string `json:"attachment_ids"`
+	AssigneeOnly       bool     `json:"assignee_only"`
+	RequireChecklist   bool     `json:"require_checklist"`
+	Priority           int      `json:"priority"`
+	StreakBonusPercent float64  `json:"streak_bonus_percent"`
+
+	// Target and Unit make the task measurable; MaxPayoutPercent defaults to 100.
+	Target           *float64 `json:"target"`
+	Unit             *string  `json:"unit"`
+	MaxPayoutPercent *float64 `json:"max_payout_percent"`
+	Penalty          float64  `json:"penalty"`
+	PayPerHour       bool     `json:"pay_per_hour"`
+
+	PostponeDecayPercent float64 `json:"postpone_decay_percent"`
+}
+
+func (req taskRequest) options() repo.TaskOptions {
+	maxPayoutPercent := 100.0
+	if req.MaxPayoutPercent != nil {
+		maxPayoutPercent = *req.MaxPayoutPercent
+	}
+	return repo.TaskOptions{AssigneeOnly: req.AssigneeOnly, RequireChecklist: req.RequireChecklist, Priority: req.Priority, StreakBonusPercent: req.StreakBonusPercent, Target: req.Target, Unit: req.Unit, MaxPayoutPercent: maxPayoutPercent, Penalty: req.Penalty, PayPerHour: req.PayPerHour, PostponeDecayPercent: req.PostponeDecayPercent}
+}
+
+func (req taskRequest) links() repo.TaskLinks {
+	return repo.TaskLinks{AssigneeIDs: req.AssigneeIDs, TagIDs: req.TagIDs, BlockedBy: req.BlockedBy, AttachmentIDs: req.AttachmentIDs}
 }
 
 type rewardRequest struct {
@@ -119,6 +153,9 @@ type rewardRequest struct {
 	IsShared      bool    `json:"is_shared"`
 	CooldownHours *int    `json:"cooldown_hours"`
 	OneTime       bool    `json:"one_time"`
+
+	// AttachmentIDs replaces the reward's attachments when present.
+	AttachmentIDs []string `json:"attachment_ids"`
 }
 
 type settingsRequest struct {
@@ -142,6 +179,9 @@ type achievementRequest struct {
 	Description string     `json:"description"`
 	ImageURL    *string    `json:"image_url"`
 	AchievedAt  *time.Time `json:"achieved_at"`
+
+	// AttachmentIDs replaces the achievement's attachments when present.
+	AttachmentIDs []string `json:"attachment_ids"`
 }
 
 type workspaceBalanceResponse struct {
@@ -406,12 +446,16 @@ func (a *API) handleListGoals(w http.ResponseWriter, r *http.Request) {
 	if !a.authorizeWorkspace(w, r, workspaceID) {
 		return
 	}
-	goals, err := a.Repo.ListGoals(r.Context(), workspaceID)
+	page, ok := parsePageRequest(w, r)
+	if !ok {
+		return
+	}
+	goals, err := a.Repo.ListGoals(r.Context(), workspaceID, page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list goals")
+		writeListError(w, err, "Failed to list goals")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"goals": goals})
+	writeJSON(w, http.StatusOK, goals)
 }
 
 func (a *API) handleCreateGoal(w http.ResponseWriter, r *http.Request) {
@@ -488,6 +532,10 @@ func (a *API) handleListTasks(w http.ResponseWriter, r *http.Request) {
 	if !a.authorizeWorkspace(w, r, workspaceID) {
 		return
 	}
+	filter, ok := a.parseTaskFilter(w, r, workspaceID)
+	if !ok {
+		return
+	}
 	fromStr := r.URL.Query().Get("from")
 	toStr := r.URL.Query().Get("to")
 	if fromStr != "" && toStr != "" {
@@ -501,7 +549,7 @@ func (a *API) handleListTasks(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid to date")
 			return
 		}
-		instances, err := a.Repo.ListTaskInstances(r.Context(), workspaceID, from, to)
+		instances, err := a.Repo.ListTaskInstances(r.Context(), workspaceID, from, to, filter)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list tasks")
 			return
@@ -509,12 +557,175 @@ func (a *API) handleListTasks(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]any{"instances": instances})
 		return
 	}
-	tasks, err := a.Repo.ListTasks(r.Context(), workspaceID)
+	page, ok := parsePageRequest(w, r)
+	if !ok {
+		return
+	}
+	tasks, err := a.Repo.ListTasks(r.Context(), workspaceID, filter, page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list tasks")
+		writeListError(w, err, "Failed to list tasks")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"tasks": tasks})
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+// parseTaskFilter reads the optional task filters from the query string:
+// assignee ("me" stands for the caller), tag, priority, status, goal_id,
+// due_before, due_after, recurring and q (text search).
+func (a *API) parseTaskFilter(w http.ResponseWriter, r *http.Request, workspaceID string) (repo.TaskFilter, bool) {
+	query := r.URL.Query()
+	filter := repo.TaskFilter{Status: query.Get("status"), Text: strings.TrimSpace(query.Get("q"))}
+	var ok bool
+	if assignee := query.Get("assignee"); assignee != "" {
+		if assignee == "me" {
+			assignee, _ = auth.UserIDFromContext(r.Context())
+		} else {
+			members, err := a.Repo.AreWorkspaceMembers(r.Context(), workspaceID, []string{assignee})
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to check assignee")
+				return filter, false
+			}
+			if !members {
+				writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Assignee is not a workspace member")
+				return filter, false
+			}
+		}
+		filter.AssigneeID = &assignee
+	}
+	if tag := query.Get("tag"); tag != "" {
+		valid, err := a.Repo.AreWorkspaceTags(r.Context(), workspaceID, []string{tag})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to check tag")
+			return filter, false
+		}
+		if !valid {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Unknown tag")
+			return filter, false
+		}
+		filter.TagID = &tag
+	}
+	if raw := query.Get("priority"); raw != "" {
+		priority, err := strconv.Atoi(raw)
+		if err != nil || !validPriority(priority) {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid priority")
+			return filter, false
+		}
+		filter.Priority = &priority
+	}
+	if goalID := query.Get("goal_id"); goalID != "" {
+		valid, err := a.Repo.AreWorkspaceGoals(r.Context(), workspaceID, []string{goalID})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to check goal")
+			return filter, false
+		}
+		if !valid {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Unknown goal")
+			return filter, false
+		}
+		filter.GoalID = &goalID
+	}
+	if filter.DueBefore, ok = parseDateParam(w, r, "due_before"); !ok {
+		return filter, false
+	}
+	if filter.DueAfter, ok = parseDateParam(w, r, "due_after"); !ok {
+		return filter, false
+	}
+	if raw := query.Get("recurring"); raw != "" {
+		recurring, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid recurring flag")
+			return filter, false
+		}
+		filter.Recurring = &recurring
+	}
+	return filter, true
+}
+
+// parseDateParam parses an optional YYYY-MM-DD query param.
+func parseDateParam(w http.ResponseWriter, r *http.Request, name string) (*time.Time, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, true
+	}
+	parsed, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid "+name+" date")
+		return nil, false
+	}
+	return &parsed, true
+}
+
+func validPriority(priority int) bool {
+	return priority >= 0 && priority <= 3
+}
+
+// validateTaskRefs rejects tasks whose priority is out of range or whose
+// assignees or tags do not belong to the workspace.
+func (a *API) validateTaskRefs(w http.ResponseWriter, r *http.Request, req taskRequest) bool {
+	if !validPriority(req.Priority) {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Priority must be between 0 and 3")
+		return false
+	}
+	if req.StreakBonusPercent < 0 || req.StreakBonusPercent > 100 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Streak bonus percent must be between 0 and 100")
+		return false
+	}
+	if req.Penalty < 0 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Penalty must not be negative")
+		return false
+	}
+	if req.PostponeDecayPercent < 0 || req.PostponeDecayPercent > 100 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Postpone decay percent must be between 0 and 100")
+		return false
+	}
+	if req.Target != nil && *req.Target <= 0 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Target must be positive")
+		return false
+	}
+	if req.PayPerHour && req.Target != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "A task cannot both pay per hour and have a target")
+		return false
+	}
+	if req.MaxPayoutPercent != nil && *req.MaxPayoutPercent < 100 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Max payout percent must be at least 100")
+		return false
+	}
+	if !a.validateAssignees(w, r, req.WorkspaceID, req.AssigneeIDs) {
+		return false
+	}
+	valid, err := a.Repo.AreWorkspaceTags(r.Context(), req.WorkspaceID, req.TagIDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to check tags")
+		return false
+	}
+	if !valid {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Tags must belong to the workspace")
+		return false
+	}
+	valid, err = a.Repo.AreWorkspaceTasks(r.Context(), req.WorkspaceID, req.BlockedBy)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to check dependencies")
+		return false
+	}
+	if !valid {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "blocked_by must list tasks of the workspace")
+		return false
+	}
+	return a.validateAttachments(w, r, req.WorkspaceID, req.AttachmentIDs)
+}
+
+// validateAssignees rejects assignee lists containing users outside the workspace.
+func (a *API) validateAssignees(w http.ResponseWriter, r *http.Request, workspaceID string, assigneeIDs []string) bool {
+	members, err := a.Repo.AreWorkspaceMembers(r.Context(), workspaceID, assigneeIDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to check assignees")
+		return false
+	}
+	if !members {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Assignees must be workspace members")
+		return false
+	}
+	return true
 }
 
 func (a *API) handleCreateTask(w http.ResponseWriter, r *http.Request) {
@@ -529,13 +740,23 @@ func (a *API) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
 		return
 	}
+	if !a.validateTaskRefs(w, r, req) {
+		return
+	}
 	status := req.Status
 	if status == "" {
 		status = "open"
 	}
-	id, err := a.Repo.CreateTask(r.Context(), req.WorkspaceID, req.GoalID, req.Title, req.Description, req.DueDate.ToTimePtr(), req.RepeatRule, req.Value, status, req.IsRecurring, req.Weekdays, req.StartDate.ToTimePtr(), req.EndDate.ToTimePtr(), req.Timezone)
+	id, err := a.Repo.CreateTask(r.Context(), req.WorkspaceID, req.GoalID, req.Title, req.Description, req.DueDate.ToTimePtr(), req.RepeatRule, req.Value, status, req.IsRecurring, req.Weekdays, req.StartDate.ToTimePtr(), req.EndDate.ToTimePtr(), req.Timezone, req.options(), req.links())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create task")
+		switch {
+		case errors.Is(err, repo.ErrInvalidDependency):
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "blocked_by must list tasks of the workspace")
+		case errors.Is(err, repo.ErrDependencyCycle):
+			writeError(w, http.StatusConflict, "DEPENDENCY_CYCLE", "Dependencies would form a cycle")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create task")
+		}
 		return
 	}
 	writeJSON(w, http.StatusCreated, entityResponse{ID: id})
@@ -554,12 +775,24 @@ func (a *API) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
 		return
 	}
-	if err := a.Repo.UpdateTask(r.Context(), id, req.WorkspaceID, req.GoalID, req.Title, req.Description, req.DueDate.ToTimePtr(), req.RepeatRule, req.Value, req.Status, req.IsRecurring, req.Weekdays, req.StartDate.ToTimePtr(), req.EndDate.ToTimePtr(), req.Timezone); err != nil {
-		if errors.Is(err, repo.ErrNotFound) {
+	if !a.validateTaskRefs(w, r, req) {
+		return
+	}
+	if err := a.Repo.UpdateTask(r.Context(), id, req.WorkspaceID, req.GoalID, req.Title, req.Description, req.DueDate.ToTimePtr(), req.RepeatRule, req.Value, req.Status, req.IsRecurring, req.Weekdays, req.StartDate.ToTimePtr(), req.EndDate.ToTimePtr(), req.Timezone, req.options(), req.links()); err != nil {
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
 			writeError(w, http.StatusNotFound, "NOT_FOUND", "Task not found")
-			return
+		case errors.Is(err, repo.ErrDueDateChange):
+			writeError(w, http.StatusConflict, "USE_RESCHEDULE", "Change the due date with POST /tasks/{id}/reschedule")
+		case errors.Is(err, repo.ErrValueDecayed):
+			writeError(w, http.StatusConflict, "VALUE_DECAYED", "The value of a postponed task cannot be changed")
+		case errors.Is(err, repo.ErrInvalidDependency):
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "blocked_by must list tasks of the workspace")
+		case errors.Is(err, repo.ErrDependencyCycle):
+			writeError(w, http.StatusConflict, "DEPENDENCY_CYCLE", "Dependencies would form a cycle")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update task")
 		}
-		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update task")
 		return
 	}
 	writeJSON(w, http.StatusOK, entityResponse{ID: id})
@@ -591,6 +824,7 @@ func (a *API) handleCompleteTask(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		WorkspaceID    string `json:"workspace_id"`
 		OccurrenceDate string `json:"occurrence_date"`
+		Force          bool   `json:"force"`
 	}
 	if !decodeJSON(w, r, &req) {
 		return
@@ -612,33 +846,152 @@ func (a *API) handleCompleteTask(w http.ResponseWriter, r *http.Request) {
 		occurrenceDate = &parsed
 	}
 	userID, _ := auth.UserIDFromContext(r.Context())
-	value, completed, err := a.Repo.CompleteTask(r.Context(), id, req.WorkspaceID, userID, occurrenceDate)
+	value, completed, err := a.Repo.CompleteTask(r.Context(), id, req.WorkspaceID, userID, occurrenceDate, req.Force)
 	if err != nil {
 		if errors.Is(err, repo.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "NOT_FOUND", "Task not found")
 			return
 		}
-		if err.Error() == "occurrence date required" {
+		if errors.Is(err, repo.ErrOccurrenceDate) {
 			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Occurrence date required")
 			return
 		}
+		if errors.Is(err, repo.ErrNotAssignee) {
+			writeError(w, http.StatusForbidden, "NOT_ASSIGNEE", "Only assignees or owners can complete this task")
+			return
+		}
+		if errors.Is(err, repo.ErrChecklistOpen) {
+			writeError(w, http.StatusConflict, "CHECKLIST_INCOMPLETE", "All checklist items must be checked first")
+			return
+		}
+		if errors.Is(err, repo.ErrBlocked) {
+			writeError(w, http.StatusConflict, "TASK_BLOCKED", "Tasks blocking this one are not done; pass force to complete anyway")
+			return
+		}
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to complete task")
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"earned": value, "completed": completed})
 }
 
+func (a *API) handleLogTaskProgress(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req struct {
+		WorkspaceID    string  `json:"workspace_id"`
+		OccurrenceDate string  `json:"occurrence_date"`
+		Amount         float64 `json:"amount"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	if req.Amount <= 0 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Amount must be positive")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	var occurrenceDate *time.Time
+	if req.OccurrenceDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.OccurrenceDate)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid occurrence date")
+			return
+		}
+		occurrenceDate = &parsed
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	progress, earned, completed, err := a.Repo.LogTaskProgress(r.Context(), id, req.WorkspaceID, userID, occurrenceDate, req.Amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Task not found")
+		case errors.Is(err, repo.ErrOccurrenceDate):
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Occurrence date required")
+		case errors.Is(err, repo.ErrNotMeasurable):
+			writeError(w, http.StatusBadRequest, "NOT_MEASURABLE", "Task has no target")
+		case errors.Is(err, repo.ErrNotAssignee):
+			writeError(w, http.StatusForbidden, "NOT_ASSIGNEE", "Only assignees or owners can log progress")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to log progress")
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"progress": progress, "earned": earned, "completed": completed})
+}
+
+func (a *API) handleUncompleteTask(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req struct {
+		WorkspaceID    string `json:"workspace_id"`
+		OccurrenceDate string `json:"occurrence_date"`
+		Force          bool   `json:"force"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	var occurrenceDate *time.Time
+	if req.OccurrenceDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.OccurrenceDate)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid occurrence date")
+			return
+		}
+		occurrenceDate = &parsed
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	if req.Force {
+		role, err := a.Repo.GetWorkspaceRole(r.Context(), userID, req.WorkspaceID)
+		if err != nil || role != "owner" {
+			writeError(w, http.StatusForbidden, "FORBIDDEN", "Only owner can force")
+			return
+		}
+	}
+	value, uncompleted, err := a.Repo.UncompleteTask(r.Context(), id, req.WorkspaceID, userID, occurrenceDate, req.Force)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Task not found")
+		case errors.Is(err, repo.ErrOccurrenceDate):
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Occurrence date required")
+		case errors.Is(err, repo.ErrBalanceSpent):
+			writeError(w, http.StatusConflict, "BALANCE_SPENT", "Огоньки за задачу уже потрачены")
+		case errors.Is(err, repo.ErrNotAssignee):
+			writeError(w, http.StatusForbidden, "NOT_ASSIGNEE", "Only assignees or owners can uncomplete this task")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to uncomplete task")
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reversed": value, "uncompleted": uncompleted})
+}
+
 func (a *API) handleListRewards(w http.ResponseWriter, r *http.Request) {
 	workspaceID := r.URL.Query().Get("workspace_id")
 	if !a.authorizeWorkspace(w, r, workspaceID) {
 		return
 	}
-	rewards, err := a.Repo.ListRewards(r.Context(), workspaceID)
+	page, ok := parsePageRequest(w, r)
+	if !ok {
+		return
+	}
+	rewards, err := a.Repo.ListRewards(r.Context(), workspaceID, page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list rewards")
+		writeListError(w, err, "Failed to list rewards")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"rewards": rewards})
+	writeJSON(w, http.StatusOK, rewards)
 }
 
 func (a *API) handleCreateReward(w http.ResponseWriter, r *http.Request) {
@@ -653,7 +1006,10 @@ func (a *API) handleCreateReward(w http.ResponseWriter, r *http.Request) {
 	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
 		return
 	}
-	id, err := a.Repo.CreateReward(r.Context(), req.WorkspaceID, req.Title, req.Description, req.Cost, req.IsShared, req.CooldownHours, req.OneTime)
+	if !a.validateAttachments(w, r, req.WorkspaceID, req.AttachmentIDs) {
+		return
+	}
+	id, err := a.Repo.CreateReward(r.Context(), req.WorkspaceID, req.Title, req.Description, req.Cost, req.IsShared, req.CooldownHours, req.OneTime, req.AttachmentIDs)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create reward")
 		return
@@ -674,6 +1030,9 @@ func (a *API) handleUpdateReward(w http.ResponseWriter, r *http.Request) {
 	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
 		return
 	}
+	if !a.validateAttachments(w, r, req.WorkspaceID, req.AttachmentIDs) {
+		return
+	}
 	if err := a.Repo.UpdateReward(r.Context(), id, req.WorkspaceID, req.Title, req.Description, req.Cost, req.IsShared, req.CooldownHours, req.OneTime); err != nil {
 		if errors.Is(err, repo.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "NOT_FOUND", "Reward not found")
@@ -682,6 +1041,9 @@ func (a *API) handleUpdateReward(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update reward")
 		return
 	}
+	if req.AttachmentIDs != nil && !a.setAttachments(w, r, "reward", id, req.WorkspaceID, req.AttachmentIDs) {
+		return
+	}
 	writeJSON(w, http.StatusOK, entityResponse{ID: id})
 }
 
@@ -738,7 +1100,6 @@ func (a *API) handleBuyReward(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to buy reward")
 			return
 		}
-		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"spent": cost})
 }
@@ -749,12 +1110,16 @@ func (a *API) handleListRewardPurchases(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	userID, _ := auth.UserIDFromContext(r.Context())
-	purchases, err := a.Repo.ListRewardPurchases(r.Context(), workspaceID, userID)
+	page, ok := parsePageRequest(w, r)
+	if !ok {
+		return
+	}
+	purchases, err := a.Repo.ListRewardPurchases(r.Context(), workspaceID, userID, page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list purchases")
+		writeListError(w, err, "Failed to list purchases")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"purchases": purchases})
+	writeJSON(w, http.StatusOK, purchases)
 }
 
 func (a *API) handleListAchievements(w http.ResponseWriter, r *http.Request) {
@@ -762,12 +1127,16 @@ func (a *API) handleListAchievements(w http.ResponseWriter, r *http.Request) {
 	if !a.authorizeWorkspace(w, r, workspaceID) {
 		return
 	}
-	achievements, err := a.Repo.ListAchievements(r.Context(), workspaceID)
+	page, ok := parsePageRequest(w, r)
+	if !ok {
+		return
+	}
+	achievements, err := a.Repo.ListAchievements(r.Context(), workspaceID, page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list achievements")
+		writeListError(w, err, "Failed to list achievements")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"achievements": achievements})
+	writeJSON(w, http.StatusOK, achievements)
 }
 
 func (a *API) handleCreateAchievement(w http.ResponseWriter, r *http.Request) {
@@ -782,7 +1151,10 @@ func (a *API) handleCreateAchievement(w http.ResponseWriter, r *http.Request) {
 	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
 		return
 	}
-	id, err := a.Repo.CreateAchievement(r.Context(), req.WorkspaceID, req.Title, req.Description, req.ImageURL)
+	if !a.validateAttachments(w, r, req.WorkspaceID, req.AttachmentIDs) {
+		return
+	}
+	id, err := a.Repo.CreateAchievement(r.Context(), req.WorkspaceID, req.Title, req.Description, req.ImageURL, req.AttachmentIDs)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create achievement")
 		return
@@ -803,6 +1175,9 @@ func (a *API) handleUpdateAchievement(w http.ResponseWriter, r *http.Request) {
 	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
 		return
 	}
+	if !a.validateAttachments(w, r, req.WorkspaceID, req.AttachmentIDs) {
+		return
+	}
 	if err := a.Repo.UpdateAchievement(r.Context(), id, req.WorkspaceID, req.Title, req.Description, req.ImageURL, req.AchievedAt); err != nil {
 		if errors.Is(err, repo.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "NOT_FOUND", "Achievement not found")
@@ -811,6 +1186,9 @@ func (a *API) handleUpdateAchievement(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update achievement")
 		return
 	}
+	if req.AttachmentIDs != nil && !a.setAttachments(w, r, "achievement", id, req.WorkspaceID, req.AttachmentIDs) {
+		return
+	}
 	writeJSON(w, http.StatusOK, entityResponse{ID: id})
 }
 
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *API) handleTaskHistory(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	from, ok := parseDateParam(w, r, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseDateParam(w, r, "to")
+	if !ok {
+		return
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "to must not be before from")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	history, err := a.Repo.TaskHistory(r.Context(), taskID, workspaceID, from, to)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Task not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to load task history")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"history": history})
+}
@@ -0,0 +1,179 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/ical"
+	"firegoals/internal/repo"
+)
+
+// maxImportItems bounds the events and todos of one imported file.
+const maxImportItems = 1000
+
+type importedTask struct {
+	UID          string      `json:"uid"`
+	Kind         string      `json:"kind"`
+	TaskID       *string     `json:"task_id"`
+	Title        string      `json:"title"`
+	Description  string      `json:"description"`
+	DueDate      *time.Time  `json:"due_date"`
+	IsRecurring  bool        `json:"is_recurring"`
+	Weekdays     []int       `json:"recurrence_weekdays"`
+	StartDate    *time.Time  `json:"start_date"`
+	EndDate      *time.Time  `json:"end_date"`
+	Timezone     *string     `json:"timezone"`
+	SkippedDates []time.Time `json:"skipped_dates"`
+}
+
+type skippedItem struct {
+	UID    string `json:"uid"`
+	Kind   string `json:"kind"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// handleImportICS turns the events and todos of an iCalendar file into tasks.
+// The file is the request body, or the "file" part of a multipart form.
+// Items that cannot be expressed as tasks, or were imported before, are
+// reported instead; with dry_run nothing is created.
+func (a *API) handleImportICS(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	workspaceID := query.Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	dryRun := false
+	if raw := query.Get("dry_run"); raw != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid dry_run")
+			return
+		}
+	}
+	var loc *time.Location
+	if name := query.Get("timezone"); name != "" {
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid timezone")
+			return
+		}
+	}
+	data, ok := a.readImportFile(w, r)
+	if !ok {
+		return
+	}
+	cal, err := ical.Parse(bytes.NewReader(data))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_CALENDAR", err.Error())
+		return
+	}
+	entries, rejected := cal.Entries(loc)
+	if len(entries)+len(rejected) > maxImportItems {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "At most 1000 events and todos per import")
+		return
+	}
+
+	tasks := make([]repo.ImportTask, len(entries))
+	for i, entry := range entries {
+		task := repo.ImportTask{UID: entry.UID, Title: entry.Summary, Description: entry.Description, SkippedDates: entry.Exceptions}
+		if entry.TimeZone != "" {
+			task.Timezone = &entry.TimeZone
+		}
+		if entry.Weekdays == nil {
+			task.DueDate = entry.Date
+		} else {
+			task.IsRecurring, task.StartDate, task.EndDate = true, entry.Date, entry.End
+			for _, day := range entry.Weekdays {
+				task.Weekdays = append(task.Weekdays, int(day))
+			}
+		}
+		tasks[i] = task
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	results, err := a.Repo.ImportTasks(r.Context(), workspaceID, userID, tasks, dryRun)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to import tasks")
+		return
+	}
+
+	imported := []importedTask{}
+	skipped := []skippedItem{}
+	for _, item := range rejected {
+		skipped = append(skipped, skippedItem{UID: item.UID, Kind: item.Kind, Title: item.Summary, Reason: item.Reason})
+	}
+	for i, task := range tasks {
+		if results[i].Duplicate {
+			skipped = append(skipped, skippedItem{UID: task.UID, Kind: entries[i].Kind, Title: task.Title, Reason: "already imported"})
+			continue
+		}
+		item := importedTask{
+			UID: task.UID, Kind: entries[i].Kind, Title: task.Title, Description: task.Description, DueDate: task.DueDate,
+			IsRecurring: task.IsRecurring, Weekdays: task.Weekdays, StartDate: task.StartDate, EndDate: task.EndDate, Timezone: task.Timezone,
+			SkippedDates: task.SkippedDates,
+		}
+		if results[i].TaskID != "" {
+			item.TaskID = &results[i].TaskID
+		}
+		if item.SkippedDates == nil {
+			item.SkippedDates = []time.Time{}
+		}
+		imported = append(imported, item)
+	}
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, map[string]any{"dry_run": dryRun, "imported": len(imported), "tasks": imported, "skipped": skipped})
+}
+
+// readImportFile reads the uploaded calendar, bounded like other uploads.
+func (a *API) readImportFile(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	limit := a.maxUploadBytes()
+	r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(limit); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", "File is too large")
+				return nil, false
+			}
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Multipart form with a file required")
+			return nil, false
+		}
+		defer r.MultipartForm.RemoveAll()
+		part, _, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "File required")
+			return nil, false
+		}
+		defer part.Close()
+		body = part
+	}
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", "File is too large")
+			return nil, false
+		}
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Failed to read file")
+		return nil, false
+	}
+	if int64(len(data)) > limit {
+		writeError(w, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", "File is too large")
+		return nil, false
+	}
+	if len(data) == 0 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "File required")
+		return nil, false
+	}
+	return data, true
+}
@@ -9,14 +9,22 @@ import (
 
 	"firegoals/internal/auth"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// loggingMiddleware logs the route pattern rather than the path, so that
+// secrets in the path such as calendar feed tokens stay out of the logs.
+// Requests that match no route are logged with their path.
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
+		route := r.URL.Path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		log.Printf("%s %s %s", r.Method, route, time.Since(start))
 	})
 }
 
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/netguard"
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type reminderRequest struct {
+	AtTime        *string `json:"at_time"`
+	MinutesBefore *int    `json:"minutes_before"`
+}
+
+type remindersRequest struct {
+	WorkspaceID string            `json:"workspace_id"`
+	Reminders   []reminderRequest `json:"reminders"`
+}
+
+type notificationSettingsRequest struct {
+	Timezone        *string `json:"timezone"`
+	QuietHoursStart *string `json:"quiet_hours_start"`
+	QuietHoursEnd   *string `json:"quiet_hours_end"`
+	EmailEnabled    bool    `json:"email_enabled"`
+	WebhookURL      *string `json:"webhook_url"`
+}
+
+func (a *API) handleListReminders(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	reminders, err := a.Repo.ListTaskReminders(r.Context(), taskID, workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list reminders")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reminders": reminders})
+}
+
+func (a *API) handleSetReminders(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	var req remindersRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	reminders := make([]repo.Reminder, 0, len(req.Reminders))
+	for _, item := range req.Reminders {
+		if (item.AtTime == nil) == (item.MinutesBefore == nil) {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Each reminder needs either at_time or minutes_before")
+			return
+		}
+		if item.AtTime != nil && !validClock(*item.AtTime) {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "at_time must be HH:MM")
+			return
+		}
+		if item.MinutesBefore != nil && (*item.MinutesBefore < 0 || *item.MinutesBefore > 7*24*60) {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "minutes_before must be between 0 and 10080")
+			return
+		}
+		reminders = append(reminders, repo.Reminder{AtTime: item.AtTime, MinutesBefore: item.MinutesBefore})
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	if err := a.Repo.SetTaskReminders(r.Context(), taskID, req.WorkspaceID, reminders); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Task not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to set reminders")
+		return
+	}
+	writeJSON(w, http.StatusOK, entityResponse{ID: taskID})
+}
+
+func (a *API) handleGetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Missing user")
+		return
+	}
+	settings, err := a.Repo.GetNotificationSettings(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to load notification settings")
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func (a *API) handleUpdateNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Missing user")
+		return
+	}
+	var req notificationSettingsRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if (req.QuietHoursStart == nil) != (req.QuietHoursEnd == nil) {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Quiet hours need both start and end")
+		return
+	}
+	if req.QuietHoursStart != nil && (!validClock(*req.QuietHoursStart) || !validClock(*req.QuietHoursEnd)) {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Quiet hours must be HH:MM")
+		return
+	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Unknown timezone")
+			return
+		}
+	}
+	if req.WebhookURL != nil && *req.WebhookURL != "" {
+		if err := netguard.CheckURL(*req.WebhookURL); err != nil {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Webhook URL must be https on a public host")
+			return
+		}
+	}
+	settings := repo.NotificationSettings{
+		Timezone: req.Timezone, QuietStart: req.QuietHoursStart, QuietEnd: req.QuietHoursEnd, EmailEnabled: req.EmailEnabled, WebhookURL: req.WebhookURL,
+	}
+	if err := a.Repo.UpsertNotificationSettings(r.Context(), userID, settings); err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update notification settings")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// validClock reports whether value is a 24-hour "HH:MM" time.
+func validClock(value string) bool {
+	_, err := time.Parse("15:04", value)
+	return err == nil && len(value) == 5
+}
@@ -0,0 +1,184 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type skipRequest struct {
+	WorkspaceID string `json:"workspace_id"`
+	Reason      string `json:"reason"`
+}
+
+type vacationRequest struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Reason    string `json:"reason"`
+}
+
+// maxSkipReason bounds skip and vacation reasons.
+const maxSkipReason = 500
+
+func (a *API) handleSkipOccurrence(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	date, ok := parseOccurrencePath(w, r)
+	if !ok {
+		return
+	}
+	var req skipRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) > maxSkipReason {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Reason is too long")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	if err := a.Repo.SkipOccurrence(r.Context(), taskID, req.WorkspaceID, userID, date, req.Reason); err != nil {
+		if errors.Is(err, repo.ErrOccurrenceClosed) {
+			writeError(w, http.StatusConflict, "OCCURRENCE_CLOSED", "Occurrence is already done or missed")
+			return
+		}
+		writeOccurrenceError(w, err, "Failed to skip occurrence")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (a *API) handleUnskipOccurrence(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	date, ok := parseOccurrencePath(w, r)
+	if !ok {
+		return
+	}
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	if err := a.Repo.UnskipOccurrence(r.Context(), taskID, workspaceID, userID, date); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Skipped occurrence not found")
+			return
+		}
+		writeOccurrenceError(w, err, "Failed to unskip occurrence")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (a *API) handleListVacations(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	vacations, err := a.Repo.ListVacations(r.Context(), workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list vacations")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"vacations": vacations})
+}
+
+func (a *API) handleCreateVacation(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "id")
+	var req vacationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "start_date must be YYYY-MM-DD")
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil || endDate.Before(startDate) {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "end_date must be YYYY-MM-DD on or after start_date")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) > maxSkipReason {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Reason is too long")
+		return
+	}
+	if !a.authorizeWorkspaceOwner(w, r, workspaceID, "Only owner can manage vacations") {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	id, err := a.Repo.CreateVacation(r.Context(), workspaceID, userID, startDate, endDate, req.Reason)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create vacation")
+		return
+	}
+	writeJSON(w, http.StatusCreated, entityResponse{ID: id})
+}
+
+func (a *API) handleDeleteVacation(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "id")
+	vacationID := chi.URLParam(r, "vacationID")
+	if !a.authorizeWorkspaceOwner(w, r, workspaceID, "Only owner can manage vacations") {
+		return
+	}
+	if err := a.Repo.DeleteVacation(r.Context(), vacationID, workspaceID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Vacation not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to delete vacation")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// authorizeWorkspaceOwner is authorizeWorkspace restricted to owners.
+func (a *API) authorizeWorkspaceOwner(w http.ResponseWriter, r *http.Request, workspaceID, message string) bool {
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return false
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	role, err := a.Repo.GetWorkspaceRole(r.Context(), userID, workspaceID)
+	if err != nil || role != "owner" {
+		writeError(w, http.StatusForbidden, "FORBIDDEN", message)
+		return false
+	}
+	return true
+}
+
+// parseOccurrencePath parses the {date} URL parameter.
+func parseOccurrencePath(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	date, err := time.Parse("2006-01-02", chi.URLParam(r, "date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid occurrence date")
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// writeOccurrenceError maps the errors shared by skipping and unskipping.
+func writeOccurrenceError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "Task not found")
+	case errors.Is(err, repo.ErrNotOccurrence):
+		writeError(w, http.StatusBadRequest, "NOT_AN_OCCURRENCE", "Date is not a scheduled occurrence of this recurring task")
+	case errors.Is(err, repo.ErrNotAssignee):
+		writeError(w, http.StatusForbidden, "NOT_ASSIGNEE", "Only assignees or owners can skip this task")
+	default:
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", message)
+	}
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"firegoals/internal/repo"
+)
+
+// parsePageRequest reads limit, sort and cursor from the query string.
+func parsePageRequest(w http.ResponseWriter, r *http.Request) (repo.PageRequest, bool) {
+	query := r.URL.Query()
+	page := repo.PageRequest{Sort: query.Get("sort"), Cursor: query.Get("cursor")}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > repo.MaxPageLimit {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "limit must be between 1 and "+strconv.Itoa(repo.MaxPageLimit))
+			return repo.PageRequest{}, false
+		}
+		page.Limit = limit
+	}
+	return page, true
+}
+
+// writeListError reports a failed paginated listing; message describes
+// unexpected failures.
+func writeListError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repo.ErrInvalidSort):
+		writeError(w, http.StatusBadRequest, "INVALID_SORT", "Sorting by this field is not supported")
+	case errors.Is(err, repo.ErrInvalidCursor):
+		writeError(w, http.StatusBadRequest, "INVALID_CURSOR", "Cursor is invalid or belongs to another sort")
+	default:
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", message)
+	}
+}
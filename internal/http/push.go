@@ -0,0 +1,89 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/netguard"
+	"firegoals/internal/repo"
+	"firegoals/internal/webpush"
+)
+
+type pushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+func (a *API) handleVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
+	if a.VAPIDPublicKey == "" {
+		writeError(w, http.StatusNotFound, "PUSH_DISABLED", "Web Push is not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"public_key": a.VAPIDPublicKey})
+}
+
+func (a *API) handleCreatePushSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Missing user")
+		return
+	}
+	var req pushSubscriptionRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if err := netguard.CheckURL(req.Endpoint); err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Endpoint must be https on a public host")
+		return
+	}
+	sub := webpush.Subscription{Endpoint: req.Endpoint, P256dh: req.Keys.P256dh, Auth: req.Keys.Auth}
+	if err := sub.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid subscription keys")
+		return
+	}
+	var userAgent *string
+	if value := r.UserAgent(); value != "" {
+		userAgent = &value
+	}
+	id, err := a.Repo.SavePushSubscription(r.Context(), userID, sub.Endpoint, sub.P256dh, sub.Auth, userAgent)
+	if err != nil {
+		if errors.Is(err, repo.ErrSubscriptionTaken) {
+			writeError(w, http.StatusConflict, "SUBSCRIPTION_TAKEN", "Endpoint is subscribed by another user")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to save push subscription")
+		return
+	}
+	writeJSON(w, http.StatusCreated, entityResponse{ID: id})
+}
+
+func (a *API) handleDeletePushSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Missing user")
+		return
+	}
+	var req struct {
+		Endpoint string `json:"endpoint"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Endpoint == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Endpoint required")
+		return
+	}
+	if err := a.Repo.DeletePushSubscription(r.Context(), userID, req.Endpoint); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Push subscription not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to delete push subscription")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
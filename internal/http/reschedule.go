@@ -0,0 +1,90 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// rescheduleRequest moves a task either to DueDate or by a Snooze preset;
+// exactly one of them is required.
+type rescheduleRequest struct {
+	WorkspaceID string `json:"workspace_id"`
+	DueDate     string `json:"due_date"`
+	Snooze      string `json:"snooze"`
+}
+
+func (a *API) handleRescheduleTask(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req rescheduleRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	if (req.DueDate == "") == (req.Snooze == "") {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Either due_date or snooze required")
+		return
+	}
+	var dueDate time.Time
+	if req.DueDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.DueDate)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "due_date must be YYYY-MM-DD")
+			return
+		}
+		dueDate = parsed
+	} else if !slices.Contains(repo.SnoozePresets, req.Snooze) {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "snooze must be tomorrow or next_week")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	var result repo.Reschedule
+	var err error
+	if req.Snooze != "" {
+		result, err = a.Repo.SnoozeTask(r.Context(), id, req.WorkspaceID, userID, req.Snooze, time.Now())
+	} else {
+		result, err = a.Repo.RescheduleTask(r.Context(), id, req.WorkspaceID, userID, dueDate)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Task not found")
+		case errors.Is(err, repo.ErrRecurringTask):
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Recurring tasks have no due date to reschedule")
+		case errors.Is(err, repo.ErrTaskDone):
+			writeError(w, http.StatusConflict, "TASK_DONE", "Done tasks cannot be rescheduled")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to reschedule task")
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id": id, "due_date": result.DueDate.Format("2006-01-02"), "value": result.Value, "postponed_count": result.PostponedCount,
+	})
+}
+
+func (a *API) handleListTaskReschedules(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	reschedules, err := a.Repo.ListTaskReschedules(r.Context(), id, workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list reschedules")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reschedules": reschedules})
+}
@@ -7,6 +7,7 @@ import (
 	"firegoals/internal/auth"
 	"firegoals/internal/repo"
 	"firegoals/internal/service"
+	"firegoals/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -17,6 +18,16 @@ type API struct {
 	Service *service.Service
 	Auth    *auth.Manager
 	Origins []string
+	// VAPIDPublicKey is handed to browsers subscribing to Web Push; empty
+	// when push is disabled.
+	VAPIDPublicKey string
+	// Storage holds uploaded files; uploads are disabled when nil.
+	Storage storage.Store
+	// MaxUploadBytes bounds the size of an uploaded file.
+	MaxUploadBytes int64
+	// PublicURL is the base of calendar feed links; the request's scheme and
+	// host are used when empty.
+	PublicURL string
 }
 
 func (a *API) Router() http.Handler {
@@ -29,6 +40,7 @@ func (a *API) Router() http.Handler {
 	r.Use(a.corsMiddleware)
 
 	r.Get("/health", a.handleHealth)
+	r.Get("/feeds/{token}.ics", a.handleCalendarFeed)
 
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/register", a.handleRegister)
@@ -40,25 +52,76 @@ func (a *API) Router() http.Handler {
 		r.Get("/me", a.handleMe)
 		r.Get("/settings", a.handleGetSettings)
 		r.Put("/settings", a.handleUpdateSettings)
+		r.Get("/me/notification-settings", a.handleGetNotificationSettings)
+		r.Put("/me/notification-settings", a.handleUpdateNotificationSettings)
+		r.Post("/me/push-subscriptions", a.handleCreatePushSubscription)
+		r.Delete("/me/push-subscriptions", a.handleDeletePushSubscription)
+		r.Get("/push/vapid-public-key", a.handleVAPIDPublicKey)
 		r.Get("/workspaces", a.handleListWorkspaces)
 		r.Post("/workspaces", a.handleCreateWorkspace)
 		r.Get("/workspaces/{id}/balance", a.handleWorkspaceBalance)
 		r.Get("/workspaces/{id}/members", a.handleListWorkspaceMembers)
 		r.Post("/workspaces/{id}/invite", a.handleCreateInvite)
+		r.Get("/workspaces/{id}/vacations", a.handleListVacations)
+		r.Post("/workspaces/{id}/vacations", a.handleCreateVacation)
+		r.Delete("/workspaces/{id}/vacations/{vacationID}", a.handleDeleteVacation)
+		r.Get("/workspaces/{id}/calendar-feed", a.handleGetCalendarFeed)
+		r.Post("/workspaces/{id}/calendar-feed", a.handleCreateCalendarFeed)
+		r.Delete("/workspaces/{id}/calendar-feed", a.handleDeleteCalendarFeed)
 		r.Post("/invites/accept", a.handleAcceptInvite)
 
 		r.Route("/goals", func(r chi.Router) {
 			r.Get("/", a.handleListGoals)
 			r.Post("/", a.handleCreateGoal)
 			r.Put("/{id}", a.handleUpdateGoal)
+			r.Get("/{id}/task-order", a.handleGoalTaskOrder)
+			r.Get("/{id}/progress", a.handleGoalProgress)
+			r.Get("/{id}/comments", a.handleListComments("goal"))
+			r.Post("/{id}/comments", a.handleCreateComment("goal"))
+			r.Put("/{id}/comments/{commentID}", a.handleUpdateComment("goal"))
+			r.Delete("/{id}/comments/{commentID}", a.handleDeleteComment("goal"))
+			r.Get("/{id}/comments/{commentID}/history", a.handleListCommentEdits("goal"))
 			r.Delete("/{id}", a.handleDeleteGoal)
+			r.Post("/{id}/restore", a.handleRestore("goal"))
 		})
 		r.Route("/tasks", func(r chi.Router) {
 			r.Get("/", a.handleListTasks)
 			r.Post("/", a.handleCreateTask)
+			r.Post("/bulk", a.handleBulkTasks)
 			r.Put("/{id}", a.handleUpdateTask)
 			r.Delete("/{id}", a.handleDeleteTask)
+			r.Post("/{id}/restore", a.handleRestore("task"))
 			r.Post("/{id}/complete", a.handleCompleteTask)
+			r.Post("/{id}/uncomplete", a.handleUncompleteTask)
+			r.Post("/{id}/reschedule", a.handleRescheduleTask)
+			r.Get("/{id}/reschedules", a.handleListTaskReschedules)
+			r.Post("/{id}/progress", a.handleLogTaskProgress)
+			r.Post("/{id}/occurrences/{date}/skip", a.handleSkipOccurrence)
+			r.Delete("/{id}/occurrences/{date}/skip", a.handleUnskipOccurrence)
+			r.Post("/{id}/timer/start", a.handleStartTimer)
+			r.Post("/{id}/timer/stop", a.handleStopTimer)
+			r.Get("/{id}/history", a.handleTaskHistory)
+			r.Get("/{id}/time-entries", a.handleListTimeEntries)
+			r.Post("/{id}/time-entries", a.handleCreateTimeEntry)
+			r.Delete("/{id}/time-entries/{entryID}", a.handleDeleteTimeEntry)
+			r.Get("/{id}/comments", a.handleListComments("task"))
+			r.Post("/{id}/comments", a.handleCreateComment("task"))
+			r.Put("/{id}/comments/{commentID}", a.handleUpdateComment("task"))
+			r.Delete("/{id}/comments/{commentID}", a.handleDeleteComment("task"))
+			r.Get("/{id}/comments/{commentID}/history", a.handleListCommentEdits("task"))
+			r.Get("/{id}/reminders", a.handleListReminders)
+			r.Put("/{id}/reminders", a.handleSetReminders)
+			r.Get("/{id}/checklist", a.handleListChecklist)
+			r.Post("/{id}/checklist", a.handleCreateChecklistItem)
+			r.Put("/{id}/checklist/order", a.handleReorderChecklist)
+			r.Post("/{id}/checklist/{itemID}/toggle", a.handleToggleChecklistItem)
+			r.Delete("/{id}/checklist/{itemID}", a.handleDeleteChecklistItem)
+		})
+		r.Route("/tags", func(r chi.Router) {
+			r.Get("/", a.handleListTags)
+			r.Post("/", a.handleCreateTag)
+			r.Put("/{id}", a.handleUpdateTag)
+			r.Delete("/{id}", a.handleDeleteTag)
 		})
 		r.Route("/rewards", func(r chi.Router) {
 			r.Get("/", a.handleListRewards)
@@ -66,6 +129,7 @@ func (a *API) Router() http.Handler {
 			r.Post("/", a.handleCreateReward)
 			r.Put("/{id}", a.handleUpdateReward)
 			r.Delete("/{id}", a.handleDeleteReward)
+			r.Post("/{id}/restore", a.handleRestore("reward"))
 			r.Post("/{id}/buy", a.handleBuyReward)
 		})
 		r.Route("/achievements", func(r chi.Router) {
@@ -73,7 +137,18 @@ func (a *API) Router() http.Handler {
 			r.Post("/", a.handleCreateAchievement)
 			r.Put("/{id}", a.handleUpdateAchievement)
 			r.Delete("/{id}", a.handleDeleteAchievement)
+			r.Post("/{id}/restore", a.handleRestore("achievement"))
+		})
+		r.Route("/uploads", func(r chi.Router) {
+			r.Get("/", a.handleListUploads)
+			r.Post("/", a.handleCreateUpload)
+			r.Get("/{id}", a.handleGetUpload)
+			r.Delete("/{id}", a.handleDeleteUpload)
 		})
+		r.Get("/search", a.handleSearch)
+		r.Get("/trash", a.handleListTrash)
+		r.Post("/import/ics", a.handleImportICS)
+		r.Get("/reports/time", a.handleTimeReport)
 		r.Get("/sync", a.handleSyncPull)
 		r.Post("/sync", a.handleSyncPush)
 	})
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"firegoals/internal/repo"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// maxSearchQuery bounds the query length in characters.
+	maxSearchQuery = 200
+)
+
+func (a *API) handleSearch(w http.ResponseWriter, r *http.Request) {
+	workspaceID := r.URL.Query().Get("workspace_id")
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "q required")
+		return
+	}
+	if utf8.RuneCountInString(query) > maxSearchQuery {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "q is too long")
+		return
+	}
+	types := repo.SearchEntities
+	if raw := r.URL.Query().Get("types"); raw != "" {
+		types = strings.Split(raw, ",")
+		for _, entityType := range types {
+			if !slices.Contains(repo.SearchEntities, entityType) {
+				writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "types must list goal, task, reward or achievement")
+				return
+			}
+		}
+	}
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "limit must be between 1 and 100")
+			return
+		}
+		limit = parsed
+	}
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	results, total, err := a.Repo.Search(r.Context(), workspaceID, query, types, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to search")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": results, "total": total})
+}
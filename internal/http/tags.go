@@ -0,0 +1,98 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type tagRequest struct {
+	WorkspaceID string  `json:"workspace_id"`
+	Name        string  `json:"name"`
+	Color       *string `json:"color"`
+}
+
+func (a *API) handleListTags(w http.ResponseWriter, r *http.Request) {
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	tags, err := a.Repo.ListTags(r.Context(), workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list tags")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+func (a *API) handleCreateTag(w http.ResponseWriter, r *http.Request) {
+	var req tagRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" || req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id and name required")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	id, err := a.Repo.CreateTag(r.Context(), req.WorkspaceID, req.Name, req.Color)
+	if err != nil {
+		if errors.Is(err, repo.ErrDuplicate) {
+			writeError(w, http.StatusConflict, "DUPLICATE", "Tag already exists")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create tag")
+		return
+	}
+	writeJSON(w, http.StatusCreated, entityResponse{ID: id})
+}
+
+func (a *API) handleUpdateTag(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req tagRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" || req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id and name required")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	if err := a.Repo.UpdateTag(r.Context(), id, req.WorkspaceID, req.Name, req.Color); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Tag not found")
+			return
+		}
+		if errors.Is(err, repo.ErrDuplicate) {
+			writeError(w, http.StatusConflict, "DUPLICATE", "Tag already exists")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update tag")
+		return
+	}
+	writeJSON(w, http.StatusOK, entityResponse{ID: id})
+}
+
+func (a *API) handleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	if err := a.Repo.DeleteTag(r.Context(), id, workspaceID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Tag not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to delete tag")
+		return
+	}
+	writeJSON(w, http.StatusOK, entityResponse{ID: id})
+}
@@ -0,0 +1,220 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type timerRequest struct {
+	WorkspaceID    string `json:"workspace_id"`
+	OccurrenceDate string `json:"occurrence_date"`
+}
+
+type timeEntryRequest struct {
+	WorkspaceID    string    `json:"workspace_id"`
+	OccurrenceDate string    `json:"occurrence_date"`
+	StartedAt      time.Time `json:"started_at"`
+	EndedAt        time.Time `json:"ended_at"`
+	Note           string    `json:"note"`
+}
+
+// maxTimeEntry bounds a single manual time entry.
+const maxTimeEntry = 24 * time.Hour
+
+func (a *API) handleStartTimer(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	var req timerRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	occurrenceDate, ok := parseOccurrenceDate(w, req.OccurrenceDate)
+	if !ok {
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	id, err := a.Repo.StartTimer(r.Context(), taskID, req.WorkspaceID, userID, occurrenceDate)
+	if err != nil {
+		if errors.Is(err, repo.ErrTimerRunning) {
+			writeError(w, http.StatusConflict, "TIMER_RUNNING", "A timer is already running on this task")
+			return
+		}
+		writeTimeEntryError(w, err, "Failed to start timer")
+		return
+	}
+	writeJSON(w, http.StatusCreated, entityResponse{ID: id})
+}
+
+func (a *API) handleStopTimer(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	var req timerRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	id, seconds, err := a.Repo.StopTimer(r.Context(), taskID, req.WorkspaceID, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNoTimer) {
+			writeError(w, http.StatusNotFound, "NO_TIMER", "No running timer on this task")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to stop timer")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "duration_seconds": seconds})
+}
+
+func (a *API) handleListTimeEntries(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	entries, err := a.Repo.ListTimeEntries(r.Context(), taskID, workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list time entries")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"time_entries": entries})
+}
+
+func (a *API) handleCreateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	var req timeEntryRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.WorkspaceID == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+		return
+	}
+	if req.StartedAt.IsZero() || !req.EndedAt.After(req.StartedAt) {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "started_at and a later ended_at are required")
+		return
+	}
+	if req.EndedAt.Sub(req.StartedAt) > maxTimeEntry {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "A time entry cannot exceed 24 hours")
+		return
+	}
+	if req.EndedAt.After(time.Now()) {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "ended_at cannot be in the future")
+		return
+	}
+	occurrenceDate, ok := parseOccurrenceDate(w, req.OccurrenceDate)
+	if !ok {
+		return
+	}
+	if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	id, err := a.Repo.AddTimeEntry(r.Context(), taskID, req.WorkspaceID, userID, occurrenceDate, req.StartedAt, req.EndedAt, req.Note)
+	if err != nil {
+		writeTimeEntryError(w, err, "Failed to add time entry")
+		return
+	}
+	writeJSON(w, http.StatusCreated, entityResponse{ID: id})
+}
+
+func (a *API) handleDeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	entryID := chi.URLParam(r, "entryID")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	if err := a.Repo.DeleteTimeEntry(r.Context(), entryID, taskID, workspaceID, userID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Time entry not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to delete time entry")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (a *API) handleTimeReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	workspaceID := query.Get("workspace_id")
+	groupBy := query.Get("group_by")
+	if groupBy == "" {
+		groupBy = "task"
+	}
+	if !repo.TimeReportGroups[groupBy] {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "group_by must be task, goal or member")
+		return
+	}
+	from, ok := parseDateParam(w, r, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseDateParam(w, r, "to")
+	if !ok {
+		return
+	}
+	if from == nil || to == nil || to.Before(*from) {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "from and to dates required")
+		return
+	}
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	report, err := a.Repo.TimeReport(r.Context(), workspaceID, groupBy, *from, to.AddDate(0, 0, 1))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to build time report")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"group_by": groupBy, "rows": report})
+}
+
+// parseOccurrenceDate parses an optional "YYYY-MM-DD" occurrence date.
+func parseOccurrenceDate(w http.ResponseWriter, value string) (*time.Time, bool) {
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid occurrence date")
+		return nil, false
+	}
+	return &parsed, true
+}
+
+// writeTimeEntryError maps the errors shared by timers and manual entries.
+func writeTimeEntryError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "Task not found")
+	case errors.Is(err, repo.ErrOccurrenceDate):
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Occurrence date required")
+	case errors.Is(err, repo.ErrNotAssignee):
+		writeError(w, http.StatusForbidden, "NOT_ASSIGNEE", "Only assignees or owners can track time on this task")
+	case errors.Is(err, repo.ErrTaskDone):
+		writeError(w, http.StatusConflict, "TASK_DONE", "The task or occurrence is already done")
+	case errors.Is(err, repo.ErrTimeOverlap):
+		writeError(w, http.StatusConflict, "TIME_OVERLAP", "The entry overlaps another of your entries or your running timer")
+	default:
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", message)
+	}
+}
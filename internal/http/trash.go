@@ -0,0 +1,54 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"firegoals/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *API) handleListTrash(w http.ResponseWriter, r *http.Request) {
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	items, err := a.Repo.ListTrash(r.Context(), workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list trash")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (a *API) handleRestore(entityType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		var req struct {
+			WorkspaceID string `json:"workspace_id"`
+		}
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if req.WorkspaceID == "" {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Workspace_id required")
+			return
+		}
+		if !a.authorizeWorkspace(w, r, req.WorkspaceID) {
+			return
+		}
+		if err := a.Repo.RestoreEntity(r.Context(), entityType, id, req.WorkspaceID); err != nil {
+			switch {
+			case errors.Is(err, repo.ErrNotFound):
+				writeError(w, http.StatusNotFound, "NOT_FOUND", "Entity not found in trash")
+			case errors.Is(err, repo.ErrDependencyCycle):
+				writeError(w, http.StatusConflict, "DEPENDENCY_CYCLE", "Restoring the task would form a dependency cycle")
+			default:
+				writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to restore entity")
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, entityResponse{ID: id})
+	}
+}
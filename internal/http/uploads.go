@@ -0,0 +1,231 @@
+package http
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"firegoals/internal/auth"
+	"firegoals/internal/media"
+	"firegoals/internal/repo"
+	"firegoals/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// defaultMaxUploadBytes applies when API.MaxUploadBytes is unset.
+	defaultMaxUploadBytes = 10 << 20
+	// multipartOverhead is allowed on top of the file for the form fields and
+	// part headers.
+	multipartOverhead = 64 << 10
+	// maxOriginalName bounds the stored client file name.
+	maxOriginalName = 255
+)
+
+func (a *API) maxUploadBytes() int64 {
+	if a.MaxUploadBytes > 0 {
+		return a.MaxUploadBytes
+	}
+	return defaultMaxUploadBytes
+}
+
+func (a *API) handleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	if a.Storage == nil {
+		writeError(w, http.StatusServiceUnavailable, "UPLOADS_DISABLED", "Uploads are not configured")
+		return
+	}
+	limit := a.maxUploadBytes()
+	r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+	if err := r.ParseMultipartForm(limit); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", "File is too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Multipart form with a file required")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	workspaceID := r.FormValue("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	part, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "File required")
+		return
+	}
+	defer part.Close()
+	if header.Size > limit {
+		writeError(w, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", "File is too large")
+		return
+	}
+	data, err := io.ReadAll(part)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Failed to read file")
+		return
+	}
+	file, err := media.Sanitize(data)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType):
+			writeError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_TYPE", "Only JPEG, PNG, GIF and PDF files are accepted")
+		case errors.Is(err, media.ErrTooManyPixels):
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Image dimensions are too large")
+		case errors.Is(err, media.ErrCorrupt):
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "File could not be decoded")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process file")
+		}
+		return
+	}
+	suffix, err := randomBytes(16)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to store file")
+		return
+	}
+	key := workspaceID + "/" + hex.EncodeToString(suffix) + media.Types[file.ContentType]
+	size := int64(len(file.Data))
+	if err := a.Storage.Put(r.Context(), key, file.ContentType, bytes.NewReader(file.Data), size); err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to store file")
+		return
+	}
+	var width, height *int
+	if file.Width > 0 {
+		width, height = &file.Width, &file.Height
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	id, err := a.Repo.CreateUpload(r.Context(), workspaceID, userID, key, file.ContentType, size, width, height, originalName(header.Filename))
+	if err != nil {
+		_ = a.Storage.Delete(r.Context(), key)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to store file")
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"id": id, "content_type": file.ContentType, "size_bytes": size, "width": width, "height": height})
+}
+
+// originalName keeps only the base name of a client-supplied file name.
+func originalName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if len(name) > maxOriginalName {
+		name = name[:maxOriginalName]
+	}
+	return strings.ToValidUTF8(name, "")
+}
+
+func (a *API) handleListUploads(w http.ResponseWriter, r *http.Request) {
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	uploads, err := a.Repo.ListUploads(r.Context(), workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list uploads")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"uploads": uploads})
+}
+
+func (a *API) handleGetUpload(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	if a.Storage == nil {
+		writeError(w, http.StatusServiceUnavailable, "UPLOADS_DISABLED", "Uploads are not configured")
+		return
+	}
+	upload, err := a.Repo.GetUpload(r.Context(), id, workspaceID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Upload not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to load upload")
+		return
+	}
+	body, err := a.Storage.Get(r.Context(), upload.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Upload not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to load upload")
+		return
+	}
+	defer body.Close()
+	disposition := "inline"
+	if !strings.HasPrefix(upload.ContentType, "image/") {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", upload.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(upload.Size, 10))
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, body)
+}
+
+func (a *API) handleDeleteUpload(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	workspaceID := r.URL.Query().Get("workspace_id")
+	if !a.authorizeWorkspace(w, r, workspaceID) {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	key, err := a.Repo.DeleteUpload(r.Context(), id, workspaceID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Upload not found")
+		case errors.Is(err, repo.ErrNotAuthor):
+			writeError(w, http.StatusForbidden, "FORBIDDEN", "Only the uploader or an owner can delete an upload")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to delete upload")
+		}
+		return
+	}
+	// The row is gone either way; a blob left behind is unreachable.
+	if a.Storage != nil {
+		_ = a.Storage.Delete(r.Context(), key)
+	}
+	writeJSON(w, http.StatusOK, entityResponse{ID: id})
+}
+
+// validateAttachments rejects attachment lists naming uploads outside the
+// workspace.
+func (a *API) validateAttachments(w http.ResponseWriter, r *http.Request, workspaceID string, uploadIDs []string) bool {
+	valid, err := a.Repo.AreWorkspaceUploads(r.Context(), workspaceID, uploadIDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to check attachments")
+		return false
+	}
+	if !valid {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Attachments must be uploads of the workspace")
+		return false
+	}
+	return true
+}
+
+func (a *API) setAttachments(w http.ResponseWriter, r *http.Request, entityType, entityID, workspaceID string, uploadIDs []string) bool {
+	if err := a.Repo.SetAttachments(r.Context(), entityType, entityID, workspaceID, uploadIDs); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "Entity not found")
+			return false
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to attach uploads")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxCountDays bounds the days walked to find the last of COUNT occurrences.
+const maxCountDays = 366 * 20
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Entry is a VEVENT or VTODO reduced to a day-based schedule: a single day,
+// or the same weekdays every week from Date to an optional End.
+type Entry struct {
+	Kind        string
+	UID         string
+	Summary     string
+	Description string
+	// Date is the day of a one-off entry (DUE, else DTSTART, for a VTODO) or
+	// the first day of a recurring one. It is nil for a VTODO without either.
+	Date *time.Time
+	// TimeZone is the IANA zone of the entry's start, when it has one.
+	TimeZone string
+	// Weekdays is set for recurring entries, in week order.
+	Weekdays []time.Weekday
+	End      *time.Time
+	// Exceptions are the EXDATE days that fall on the schedule.
+	Exceptions []time.Time
+}
+
+// Rejected is a VEVENT or VTODO that Entries could not express, and why.
+type Rejected struct {
+	Kind    string
+	UID     string
+	Summary string
+	Reason  string
+}
+
+// Entries converts the calendar's VEVENTs and VTODOs. Times without a zone of
+// their own are read in loc; nil means the calendar's X-WR-TIMEZONE, or UTC.
+// Cancelled and completed items, overrides of single occurrences and
+// recurrences other than daily or weekly on fixed weekdays are rejected.
+func (c Calendar) Entries(loc *time.Location) ([]Entry, []Rejected) {
+	if loc == nil {
+		loc = time.UTC
+		for _, p := range c.Props {
+			if p.Name != "X-WR-TIMEZONE" {
+				continue
+			}
+			if zone, ok := loadLocation(p.Text()); ok {
+				loc = zone
+			}
+		}
+	}
+	var entries []Entry
+	var rejected []Rejected
+	for _, component := range c.Components {
+		if component.Name != "VEVENT" && component.Name != "VTODO" {
+			continue
+		}
+		entry, err := c.entry(component, loc)
+		if err != nil {
+			rejected = append(rejected, Rejected{Kind: entry.Kind, UID: entry.UID, Summary: entry.Summary, Reason: err.Error()})
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rejected
+}
+
+// entry converts one component. The returned entry carries its kind, UID and
+// summary even when err is set.
+func (c Calendar) entry(component Component, loc *time.Location) (Entry, error) {
+	entry := Entry{Kind: component.Name}
+	if p, ok := component.Get("UID"); ok {
+		entry.UID = strings.TrimSpace(p.Text())
+	}
+	if p, ok := component.Get("SUMMARY"); ok {
+		entry.Summary = strings.TrimSpace(p.Text())
+	}
+	if p, ok := component.Get("DESCRIPTION"); ok {
+		entry.Description = p.Text()
+	}
+	status, _ := component.Get("STATUS")
+	switch {
+	case entry.Summary == "":
+		return entry, errors.New("no SUMMARY")
+	case strings.EqualFold(status.Value, "CANCELLED"):
+		return entry, errors.New("cancelled")
+	case strings.EqualFold(status.Value, "COMPLETED"):
+		return entry, errors.New("already completed")
+	}
+	if _, ok := component.Get("RECURRENCE-ID"); ok {
+		return entry, errors.New("changes a single occurrence of a recurring item")
+	}
+	if _, ok := component.Get("RDATE"); ok {
+		return entry, errors.New("RDATE is not supported")
+	}
+	rules := component.All("RRULE")
+	if len(rules) > 1 {
+		return entry, errors.New("more than one RRULE")
+	}
+
+	start, ok := component.Get("DTSTART")
+	if due, hasDue := component.Get("DUE"); hasDue && len(rules) == 0 {
+		start, ok = due, true
+	}
+	if !ok {
+		if component.Name == "VTODO" && len(rules) == 0 {
+			return entry, nil
+		}
+		return entry, errors.New("no DTSTART")
+	}
+	date, err := c.day(start, loc)
+	if err != nil {
+		return entry, err
+	}
+	entry.Date = &date
+	if tzid := start.Param("TZID"); tzid != "" && !strings.EqualFold(start.Param("VALUE"), "DATE") {
+		if zone, err := c.Location(tzid); err == nil {
+			if _, ok := loadLocation(zone.String()); ok {
+				entry.TimeZone = zone.String()
+			}
+		}
+	}
+	if len(rules) == 0 {
+		return entry, nil
+	}
+
+	rule, err := ParseRecur(rules[0].Value)
+	if err != nil {
+		return entry, err
+	}
+	if entry.Weekdays, err = weeklyDays(rule, date.Weekday()); err != nil {
+		return entry, err
+	}
+	switch {
+	case rule.Until != "":
+		until := Prop{Name: "UNTIL", Value: rule.Until}
+		if tzid := start.Param("TZID"); tzid != "" {
+			until.Params = []string{`TZID="` + tzid + `"`}
+		}
+		end, err := c.day(until, loc)
+		if err != nil {
+			return entry, err
+		}
+		entry.End = &end
+	case rule.Count > 0:
+		end, err := countEnd(date, entry.Weekdays, rule.Count)
+		if err != nil {
+			return entry, err
+		}
+		entry.End = &end
+	}
+
+	for _, p := range component.All("EXDATE") {
+		if tzid := start.Param("TZID"); tzid != "" && p.Param("TZID") == "" {
+			p.Params = append(slices.Clip(p.Params), `TZID="`+tzid+`"`)
+		}
+		times, _, err := c.Times(p, loc)
+		if err != nil {
+			return entry, err
+		}
+		for _, t := range times {
+			day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			if day.Before(date) || (entry.End != nil && day.After(*entry.End)) || !slices.Contains(entry.Weekdays, day.Weekday()) || slices.ContainsFunc(entry.Exceptions, day.Equal) {
+				continue
+			}
+			entry.Exceptions = append(entry.Exceptions, day)
+		}
+	}
+	sort.Slice(entry.Exceptions, func(i, j int) bool { return entry.Exceptions[i].Before(entry.Exceptions[j]) })
+	return entry, nil
+}
+
+// day is the calendar day, as a UTC midnight, of a single DATE or DATE-TIME
+// value.
+func (c Calendar) day(p Prop, loc *time.Location) (time.Time, error) {
+	times, _, err := c.Times(p, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(times) != 1 {
+		return time.Time{}, fmt.Errorf("invalid %s value %q", p.Name, p.Value)
+	}
+	t := times[0]
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// weeklyDays maps a daily or weekly rule to the weekdays it repeats on; a
+// weekly rule without BYDAY repeats on the weekday of its start.
+func weeklyDays(rule Recur, start time.Weekday) ([]time.Weekday, error) {
+	if rule.Freq != "DAILY" && rule.Freq != "WEEKLY" {
+		return nil, fmt.Errorf("FREQ=%s is not supported", rule.Freq)
+	}
+	if rule.Interval != 1 {
+		return nil, fmt.Errorf("INTERVAL=%d is not supported", rule.Interval)
+	}
+	others := make([]string, 0, len(rule.Other))
+	for name := range rule.Other {
+		if name != "WKST" {
+			others = append(others, name)
+		}
+	}
+	if len(others) > 0 {
+		sort.Strings(others)
+		return nil, fmt.Errorf("%s is not supported", others[0])
+	}
+	var days []time.Weekday
+	for _, code := range rule.ByDay {
+		day, ok := weekdayCodes[code]
+		if !ok {
+			return nil, fmt.Errorf("BYDAY=%s is not supported", code)
+		}
+		if !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+	if len(days) == 0 {
+		if rule.Freq == "WEEKLY" {
+			return []time.Weekday{start}, nil
+		}
+		return []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}, nil
+	}
+	slices.Sort(days)
+	return days, nil
+}
+
+// countEnd is the day of the last of count occurrences on weekdays from start.
+func countEnd(start time.Time, weekdays []time.Weekday, count int) (time.Time, error) {
+	day := start
+	for i := 0; i < maxCountDays; i, day = i+1, day.AddDate(0, 0, 1) {
+		if slices.Contains(weekdays, day.Weekday()) {
+			if count--; count == 0 {
+				return day, nil
+			}
+		}
+	}
+	return time.Time{}, errors.New("COUNT is too large")
+}
@@ -0,0 +1,142 @@
+// Package ical reads and writes iCalendar (RFC 5545) data. Written content
+// lines end in CRLF and are folded at 75 octets without splitting UTF-8
+// sequences; TEXT values are escaped.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	// maxLineOctets is the longest content line before folding, CRLF excluded.
+	maxLineOctets = 75
+)
+
+// Calendar is a VCALENDAR object.
+type Calendar struct {
+	ProdID string
+	// Name is shown by clients as the calendar title (X-WR-CALNAME).
+	Name string
+	// Props are further calendar properties, such as X-WR-TIMEZONE.
+	Props      []Prop
+	Components []Component
+}
+
+// Component is a VEVENT, VTODO or other component with its properties in
+// order.
+type Component struct {
+	Name  string
+	Props []Prop
+	// Children are nested components, such as the STANDARD and DAYLIGHT
+	// rules of a VTIMEZONE.
+	Children []Component
+}
+
+// Prop is one content line. Value is written as is; build props with Text,
+// Date, DateTime or Raw so that it is encoded for its type.
+type Prop struct {
+	Name   string
+	Params []string
+	Value  string
+}
+
+// Text is a TEXT property, escaped.
+func Text(name, value string) Prop {
+	return Prop{Name: name, Value: escapeText(value)}
+}
+
+// Date is a DATE property, e.g. an all-day DTSTART.
+func Date(name string, date time.Time) Prop {
+	return Prop{Name: name, Params: []string{"VALUE=DATE"}, Value: date.Format(dateFormat)}
+}
+
+// DateTime is a DATE-TIME property in UTC.
+func DateTime(name string, t time.Time) Prop {
+	return Prop{Name: name, Value: t.UTC().Format(dateTimeFormat)}
+}
+
+// Raw is a property whose value is already encoded, such as an RRULE.
+func Raw(name, value string, params ...string) Prop {
+	return Prop{Name: name, Params: params, Value: value}
+}
+
+// FormatDate formats a date as used in DATE values and RRULE UNTIL parts.
+func FormatDate(date time.Time) string {
+	return date.Format(dateFormat)
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func escapeText(value string) string {
+	return textEscaper.Replace(value)
+}
+
+// Encode writes the calendar to w.
+func (c Calendar) Encode(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	line := func(p Prop) {
+		writeFolded(out, p)
+	}
+	line(Raw("BEGIN", "VCALENDAR"))
+	line(Raw("VERSION", "2.0"))
+	line(Text("PRODID", c.ProdID))
+	line(Raw("CALSCALE", "GREGORIAN"))
+	line(Raw("METHOD", "PUBLISH"))
+	if c.Name != "" {
+		line(Text("X-WR-CALNAME", c.Name))
+	}
+	for _, p := range c.Props {
+		line(p)
+	}
+	var write func(Component)
+	write = func(component Component) {
+		line(Raw("BEGIN", component.Name))
+		for _, p := range component.Props {
+			line(p)
+		}
+		for _, child := range component.Children {
+			write(child)
+		}
+		line(Raw("END", component.Name))
+	}
+	for _, component := range c.Components {
+		write(component)
+	}
+	line(Raw("END", "VCALENDAR"))
+	return out.Flush()
+}
+
+// writeFolded writes one content line, folding it with CRLF and a space so
+// that no physical line exceeds maxLineOctets.
+func writeFolded(out *bufio.Writer, p Prop) {
+	var b strings.Builder
+	b.WriteString(p.Name)
+	for _, param := range p.Params {
+		b.WriteByte(';')
+		b.WriteString(param)
+	}
+	b.WriteByte(':')
+	b.WriteString(p.Value)
+	content := b.String()
+
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		out.WriteString(content[:cut])
+		out.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines start with the folding space.
+		limit = maxLineOctets - 1
+	}
+	out.WriteString(content)
+	out.WriteString("\r\n")
+}
@@ -0,0 +1,224 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEncodeFoldsAndEscapes(t *testing.T) {
+	summary := strings.Repeat("Пробежка, 5 км; ", 8)
+	cal := Calendar{ProdID: "-//Test//EN", Name: "Tasks", Components: []Component{{
+		Name: "VEVENT",
+		Props: []Prop{
+			Raw("UID", "task-1@test"),
+			Date("DTSTART", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+			DateTime("DTSTAMP", time.Date(2024, 1, 1, 12, 30, 0, 0, time.FixedZone("X", 3600))),
+			Text("SUMMARY", summary),
+			Text("DESCRIPTION", "line one\nline two\\"),
+		},
+	}}}
+	var buf bytes.Buffer
+	if err := cal.Encode(&buf); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	raw := buf.String()
+	if !strings.HasPrefix(raw, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(raw, "END:VCALENDAR\r\n") {
+		t.Fatalf("unexpected envelope:\n%s", raw)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(raw, "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Fatalf("line longer than %d octets: %q", maxLineOctets, line)
+		}
+		if !utf8.ValidString(line) {
+			t.Fatalf("fold split a UTF-8 sequence: %q", line)
+		}
+	}
+	for _, want := range []string{"DTSTART;VALUE=DATE:20240102\r\n", "DTSTAMP:20240101T113000Z\r\n", `DESCRIPTION:line one\nline two\\` + "\r\n"} {
+		if !strings.Contains(raw, want) {
+			t.Fatalf("expected %q in:\n%s", want, raw)
+		}
+	}
+	unfolded := strings.ReplaceAll(raw, "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:"+strings.Repeat(`Пробежка\, 5 км\; `, 8)+"\r\n") {
+		t.Fatalf("summary did not survive folding:\n%s", unfolded)
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	raw := "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:-//Other//EN\nX-WR-TIMEZONE:UTC\n" +
+		"BEGIN:VEVENT\nUID:a@x\nSUMMARY;LANGUAGE=\"en:US\":Call\\, then\n  write\nDTSTART;TZID=\"Europe/Paris\":20240105T090000\n" +
+		"BEGIN:VALARM\nACTION:DISPLAY\nEND:VALARM\nEND:VEVENT\nEND:VCALENDAR\n"
+	cal, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cal.ProdID != "-//Other//EN" || len(cal.Props) != 1 || len(cal.Components) != 1 {
+		t.Fatalf("unexpected calendar %+v", cal)
+	}
+	event := cal.Components[0]
+	summary, _ := event.Get("SUMMARY")
+	if summary.Text() != "Call, then write" || summary.Param("language") != "en:US" {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if len(event.Children) != 1 || event.Children[0].Name != "VALARM" {
+		t.Fatalf("expected nested VALARM, got %+v", event.Children)
+	}
+
+	var buf bytes.Buffer
+	if err := cal.Encode(&buf); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	again, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("parse encoded: %v", err)
+	}
+	if summary, _ := again.Components[0].Get("SUMMARY"); summary.Text() != "Call, then write" {
+		t.Fatalf("summary changed in round trip: %q", summary.Text())
+	}
+
+	for _, bad := range []string{"", "BEGIN:VCALENDAR\nEND:VEVENT\n", "BEGIN:VCALENDAR\nno colon\nEND:VCALENDAR\n", "BEGIN:VCALENDAR\n"} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestEntries(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skip("tzdata not available")
+	}
+	raw := `BEGIN:VCALENDAR
+BEGIN:VTIMEZONE
+TZID:Eastern Standard Time
+BEGIN:STANDARD
+DTSTART:16011104T020000
+TZOFFSETFROM:-0400
+TZOFFSETTO:-0500
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:gym
+SUMMARY:Gym
+DTSTART;TZID=America/New_York:20240101T203000
+RRULE:FREQ=WEEKLY;BYDAY=WE,MO;COUNT=4
+EXDATE;TZID=America/New_York:20240103T203000,20240105T203000
+END:VEVENT
+BEGIN:VEVENT
+UID:late
+SUMMARY:Late call
+DTSTART:20240109T200000Z
+END:VEVENT
+BEGIN:VTODO
+UID:report
+SUMMARY:Report
+DTSTART;VALUE=DATE:20240201
+DUE;TZID=Eastern Standard Time:20240203T230000
+END:VTODO
+BEGIN:VTODO
+UID:someday
+SUMMARY:Someday
+END:VTODO
+BEGIN:VEVENT
+UID:daily
+SUMMARY:Stretch
+DTSTART;VALUE=DATE:20240301
+RRULE:FREQ=DAILY;UNTIL=20240310;WKST=MO
+END:VEVENT
+BEGIN:VEVENT
+UID:monthly
+SUMMARY:Rent
+DTSTART;VALUE=DATE:20240301
+RRULE:FREQ=MONTHLY
+END:VEVENT
+BEGIN:VEVENT
+UID:biweekly
+SUMMARY:Sync
+DTSTART;VALUE=DATE:20240301
+RRULE:FREQ=WEEKLY;INTERVAL=2
+END:VEVENT
+BEGIN:VTODO
+UID:done
+SUMMARY:Done
+STATUS:COMPLETED
+END:VTODO
+BEGIN:VEVENT
+UID:gym
+SUMMARY:Gym moved
+RECURRENCE-ID;TZID=America/New_York:20240108T203000
+DTSTART;TZID=America/New_York:20240109T203000
+END:VEVENT
+BEGIN:VEVENT
+UID:mars
+SUMMARY:Mars
+DTSTART;TZID=Mars/Olympus:20240101T100000
+END:VEVENT
+END:VCALENDAR
+`
+	cal, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	tokyo := time.FixedZone("Tokyo", 9*3600)
+	entries, rejected := cal.Entries(tokyo)
+	day := func(value string) time.Time {
+		d, _ := time.Parse("2006-01-02", value)
+		return d
+	}
+	byUID := map[string]Entry{}
+	for _, entry := range entries {
+		byUID[entry.UID] = entry
+	}
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %+v", entries)
+	}
+
+	gym := byUID["gym"]
+	// 20:30 in New York stays on Monday the 1st; COUNT=4 ends on Wednesday the 10th.
+	if !gym.Date.Equal(day("2024-01-01")) || gym.TimeZone != "America/New_York" || !gym.End.Equal(day("2024-01-10")) {
+		t.Fatalf("unexpected gym entry %+v", gym)
+	}
+	if len(gym.Weekdays) != 2 || gym.Weekdays[0] != time.Monday || gym.Weekdays[1] != time.Wednesday {
+		t.Fatalf("unexpected gym weekdays %v", gym.Weekdays)
+	}
+	// Friday is not on the schedule.
+	if len(gym.Exceptions) != 1 || !gym.Exceptions[0].Equal(day("2024-01-03")) {
+		t.Fatalf("unexpected gym exceptions %v", gym.Exceptions)
+	}
+	// UTC times fall on the day seen in the default zone.
+	if late := byUID["late"]; !late.Date.Equal(day("2024-01-10")) || late.TimeZone != "" || late.Weekdays != nil {
+		t.Fatalf("unexpected late entry %+v", late)
+	}
+	// A todo is due on DUE, read in the VTIMEZONE's standard offset.
+	if report := byUID["report"]; !report.Date.Equal(day("2024-02-03")) || report.TimeZone != "" {
+		t.Fatalf("unexpected report entry %+v", report)
+	}
+	if someday := byUID["someday"]; someday.Date != nil {
+		t.Fatalf("expected undated todo, got %+v", someday)
+	}
+	if daily := byUID["daily"]; len(daily.Weekdays) != 7 || !daily.End.Equal(day("2024-03-10")) {
+		t.Fatalf("unexpected daily entry %+v", daily)
+	}
+
+	reasons := map[string]string{}
+	for _, r := range rejected {
+		reasons[r.UID+"/"+r.Summary] = r.Reason
+	}
+	want := map[string]string{
+		"monthly/Rent":  "FREQ=MONTHLY is not supported",
+		"biweekly/Sync": "INTERVAL=2 is not supported",
+		"done/Done":     "already completed",
+		"gym/Gym moved": "changes a single occurrence of a recurring item",
+		"mars/Mars":     `unknown time zone "Mars/Olympus"`,
+	}
+	if len(reasons) != len(want) {
+		t.Fatalf("unexpected rejections %v", reasons)
+	}
+	for key, reason := range want {
+		if reasons[key] != reason {
+			t.Fatalf("expected %s rejected with %q, got %q", key, reason, reasons[key])
+		}
+	}
+}
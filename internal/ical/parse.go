@@ -0,0 +1,319 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLineBytes bounds one unfolded content line while parsing.
+const maxLineBytes = 1 << 20
+
+// Parse reads the first VCALENDAR object from r. Lines may end in CRLF or LF;
+// folded lines are unfolded. Property values are kept as written: use
+// Prop.Text, Calendar.Times and ParseRecur to decode them.
+func Parse(r io.Reader) (Calendar, error) {
+	type contentLine struct {
+		number int
+		text   string
+	}
+	var lines []contentLine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		lines = append(lines, contentLine{number: number, text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return Calendar{}, fmt.Errorf("ical: %w", err)
+	}
+
+	var stack []Component
+	for _, line := range lines {
+		p, err := parseLine(line.text)
+		if err != nil {
+			return Calendar{}, fmt.Errorf("ical: line %d: %w", line.number, err)
+		}
+		switch p.Name {
+		case "BEGIN":
+			stack = append(stack, Component{Name: strings.ToUpper(p.Value)})
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return Calendar{}, fmt.Errorf("ical: line %d: unexpected END:%s", line.number, p.Value)
+			}
+			done := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				if done.Name != "VCALENDAR" {
+					return Calendar{}, fmt.Errorf("ical: line %d: %s outside VCALENDAR", line.number, done.Name)
+				}
+				return calendarOf(done), nil
+			}
+			parent := &stack[len(stack)-1]
+			parent.Children = append(parent.Children, done)
+		default:
+			if len(stack) == 0 {
+				return Calendar{}, fmt.Errorf("ical: line %d: property outside VCALENDAR", line.number)
+			}
+			top := &stack[len(stack)-1]
+			top.Props = append(top.Props, p)
+		}
+	}
+	if len(stack) > 0 {
+		return Calendar{}, fmt.Errorf("ical: missing END:%s", stack[len(stack)-1].Name)
+	}
+	return Calendar{}, errors.New("ical: no VCALENDAR")
+}
+
+// calendarOf turns a parsed VCALENDAR component into a Calendar.
+func calendarOf(root Component) Calendar {
+	cal := Calendar{Components: root.Children}
+	for _, p := range root.Props {
+		switch p.Name {
+		case "PRODID":
+			cal.ProdID = p.Text()
+		case "X-WR-CALNAME":
+			cal.Name = p.Text()
+		case "VERSION", "CALSCALE", "METHOD":
+			// Encode writes its own.
+		default:
+			cal.Props = append(cal.Props, p)
+		}
+	}
+	return cal
+}
+
+// parseLine splits a content line into name, parameters and value. Quoted
+// parameter values may contain ';', ':' and ','.
+func parseLine(line string) (Prop, error) {
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return Prop{}, errors.New("malformed content line")
+	}
+	p := Prop{Name: strings.ToUpper(line[:i])}
+	for line[i] == ';' {
+		start, quoted := i+1, false
+		j := start
+		for ; j < len(line); j++ {
+			c := line[j]
+			if c == '"' {
+				quoted = !quoted
+			} else if !quoted && (c == ';' || c == ':') {
+				break
+			}
+		}
+		if j == len(line) {
+			return Prop{}, errors.New("malformed content line")
+		}
+		p.Params = append(p.Params, line[start:j])
+		i = j
+	}
+	p.Value = line[i+1:]
+	return p, nil
+}
+
+// Param returns the unquoted value of a parameter such as TZID, or "".
+func (p Prop) Param(name string) string {
+	for _, param := range p.Params {
+		key, value, _ := strings.Cut(param, "=")
+		if strings.EqualFold(key, name) {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// Text decodes a TEXT value.
+func (p Prop) Text() string {
+	var b strings.Builder
+	for i := 0; i < len(p.Value); i++ {
+		c := p.Value[i]
+		if c == '\\' && i+1 < len(p.Value) {
+			i++
+			c = p.Value[i]
+			if c == 'n' || c == 'N' {
+				c = '\n'
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Get returns the component's first property with the given name.
+func (c Component) Get(name string) (Prop, bool) {
+	for _, p := range c.Props {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Prop{}, false
+}
+
+// All returns every property of the component with the given name.
+func (c Component) All(name string) []Prop {
+	var res []Prop
+	for _, p := range c.Props {
+		if p.Name == name {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Location resolves a TZID. IANA names load directly, also behind a
+// producer prefix such as /mozilla.org/20050126_1/Europe/Berlin; other names
+// go through the calendar's VTIMEZONE, by its X-LIC-LOCATION or, failing
+// that, as a fixed zone at its standard offset.
+func (c Calendar) Location(tzid string) (*time.Location, error) {
+	if loc, ok := loadLocation(tzid); ok {
+		return loc, nil
+	}
+	if parts := strings.Split(strings.Trim(tzid, "/"), "/"); len(parts) > 2 {
+		if loc, ok := loadLocation(strings.Join(parts[len(parts)-2:], "/")); ok {
+			return loc, nil
+		}
+	}
+	for _, zone := range c.Components {
+		if id, _ := zone.Get("TZID"); zone.Name != "VTIMEZONE" || id.Value != tzid {
+			continue
+		}
+		if name, ok := zone.Get("X-LIC-LOCATION"); ok {
+			if loc, ok := loadLocation(name.Value); ok {
+				return loc, nil
+			}
+		}
+		for _, rule := range zone.Children {
+			if offset, ok := rule.Get("TZOFFSETTO"); ok && rule.Name == "STANDARD" {
+				if seconds, err := parseOffset(offset.Value); err == nil {
+					return time.FixedZone(tzid, seconds), nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown time zone %q", tzid)
+}
+
+// loadLocation loads an IANA zone; the empty and "Local" names, which
+// time.LoadLocation accepts, are not zone names in a calendar.
+func loadLocation(name string) (*time.Location, bool) {
+	if name == "" || name == "Local" {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(name)
+	return loc, err == nil
+}
+
+// parseOffset parses a UTC offset such as +0300 or -043000 into seconds.
+func parseOffset(value string) (int, error) {
+	if (len(value) != 5 && len(value) != 7) || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+2*i >= len(value) {
+			break
+		}
+		n, err := strconv.Atoi(value[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid UTC offset %q", value)
+		}
+		seconds += n * unit
+	}
+	if value[0] == '-' {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
+
+// Times parses the DATE or DATE-TIME values of a property such as DTSTART
+// or EXDATE. Times with a TZID parameter are read in that zone; floating
+// times and dates in loc. UTC times are converted to the zone so that their
+// calendar day is the one seen there. allDay reports DATE values.
+func (c Calendar) Times(p Prop, loc *time.Location) (times []time.Time, allDay bool, err error) {
+	allDay = strings.EqualFold(p.Param("VALUE"), "DATE")
+	if tzid := p.Param("TZID"); tzid != "" && !allDay {
+		if loc, err = c.Location(tzid); err != nil {
+			return nil, false, err
+		}
+	}
+	for _, value := range strings.Split(p.Value, ",") {
+		var t time.Time
+		switch {
+		case allDay || len(value) == len(dateFormat):
+			allDay = true
+			t, err = time.ParseInLocation(dateFormat, value, loc)
+		case strings.HasSuffix(value, "Z"):
+			t, err = time.Parse(dateTimeFormat, value)
+			t = t.In(loc)
+		default:
+			t, err = time.ParseInLocation("20060102T150405", value, loc)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid %s value %q", p.Name, value)
+		}
+		times = append(times, t)
+	}
+	return times, allDay, nil
+}
+
+// Recur is a parsed RRULE value.
+type Recur struct {
+	Freq     string
+	Interval int
+	Count    int
+	// Until is the raw DATE or DATE-TIME value.
+	Until string
+	ByDay []string
+	// Other holds the remaining parts, such as BYMONTHDAY, by name.
+	Other map[string]string
+}
+
+// ParseRecur parses an RRULE value. Interval defaults to 1.
+func ParseRecur(value string) (Recur, error) {
+	rule := Recur{Interval: 1, Other: map[string]string{}}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return Recur{}, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		switch key = strings.ToUpper(key); key {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+		case "INTERVAL", "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return Recur{}, fmt.Errorf("invalid RRULE %s %q", key, val)
+			}
+			if key == "INTERVAL" {
+				rule.Interval = n
+			} else {
+				rule.Count = n
+			}
+		case "UNTIL":
+			rule.Until = val
+		case "BYDAY":
+			rule.ByDay = strings.Split(strings.ToUpper(val), ",")
+		default:
+			rule.Other[key] = val
+		}
+	}
+	if rule.Freq == "" {
+		return Recur{}, errors.New("RRULE without FREQ")
+	}
+	return rule, nil
+}
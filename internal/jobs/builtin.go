@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"firegoals/internal/notify"
+	"firegoals/internal/repo"
+)
+
+// Settings configures the built-in jobs.
+type Settings struct {
+	// PenaltyInterval is how often missed occurrences are charged.
+	PenaltyInterval time.Duration
+	// PurgeRetention is how long soft-deleted rows are kept before purging.
+	PurgeRetention time.Duration
+	// Dispatcher delivers the notification outbox.
+	Dispatcher *notify.Dispatcher
+}
+
+const (
+	cleanupSchedule      = "@hourly"
+	purgeSchedule        = "30 3 * * *"
+	notificationSchedule = "@every 1m"
+)
+
+// Builtin returns the jobs every server instance runs.
+func Builtin(repository *repo.Repo, settings Settings) ([]Job, error) {
+	penaltySchedule, err := ParseSchedule(fmt.Sprintf("@every %s", settings.PenaltyInterval))
+	if err != nil {
+		return nil, err
+	}
+	cleanup, err := ParseSchedule(cleanupSchedule)
+	if err != nil {
+		return nil, err
+	}
+	purge, err := ParseSchedule(purgeSchedule)
+	if err != nil {
+		return nil, err
+	}
+	notifications, err := ParseSchedule(notificationSchedule)
+	if err != nil {
+		return nil, err
+	}
+	return []Job{
+		{
+			Name:     "missed-penalties",
+			Schedule: penaltySchedule,
+			Run: func(ctx context.Context, scheduledAt time.Time) (string, error) {
+				lastRun, err := repository.LastJobSuccess(ctx, "missed-penalties")
+				if err != nil {
+					return "", err
+				}
+				charged, err := repository.ProcessMissedOccurrences(ctx, scheduledAt, lastRun)
+				return fmt.Sprintf("charged=%d", charged), err
+			},
+		},
+		{
+			Name:     "session-invite-cleanup",
+			Schedule: cleanup,
+			Run: func(ctx context.Context, scheduledAt time.Time) (string, error) {
+				sessions, err := repository.DeleteExpiredSessions(ctx, scheduledAt)
+				if err != nil {
+					return "", err
+				}
+				invites, err := repository.DeleteExpiredInvites(ctx, scheduledAt)
+				return fmt.Sprintf("sessions=%d invites=%d", sessions, invites), err
+			},
+		},
+		{
+			Name:     "purge-deleted",
+			Schedule: purge,
+			Run: func(ctx context.Context, scheduledAt time.Time) (string, error) {
+				purged, err := repository.PurgeDeleted(ctx, scheduledAt.Add(-settings.PurgeRetention))
+				return formatCounts(purged), err
+			},
+		},
+		{
+			Name:     "enqueue-reminders",
+			Schedule: notifications,
+			Run: func(ctx context.Context, scheduledAt time.Time) (string, error) {
+				enqueued, err := repository.EnqueueDueReminders(ctx, scheduledAt)
+				return fmt.Sprintf("enqueued=%d", enqueued), err
+			},
+		},
+		{
+			Name:     "deliver-notifications",
+			Schedule: notifications,
+			Run:      settings.Dispatcher.Deliver,
+		},
+	}, nil
+}
+
+// formatCounts renders per-table counts as sorted "table=n" pairs.
+func formatCounts(counts map[string]int64) string {
+	var parts []string
+	for table, count := range counts {
+		if count > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", table, count))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"firegoals/internal/repo"
+)
+
+// Job is a named unit of background work run on a schedule.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Run does the work for the slot scheduled at the given time and returns a
+	// short summary stored in the run history.
+	Run func(ctx context.Context, scheduledAt time.Time) (string, error)
+}
+
+// Runner executes jobs on their schedules. Several server instances may run the
+// same jobs: a Postgres advisory lock elects one instance per job at a time and
+// the job_runs table lets each scheduled slot be claimed only once.
+type Runner struct {
+	Repo *repo.Repo
+	Jobs []Job
+}
+
+// Run blocks until ctx is cancelled. Jobs run one after another; a slot missed
+// while the process was busy or down is not caught up.
+func (r *Runner) Run(ctx context.Context) {
+	if len(r.Jobs) == 0 {
+		return
+	}
+	now := time.Now()
+	next := make([]time.Time, len(r.Jobs))
+	for i, job := range r.Jobs {
+		next[i] = job.Schedule.Next(now)
+	}
+	for {
+		earliest := 0
+		for i := range next {
+			if next[i].Before(next[earliest]) {
+				earliest = i
+			}
+		}
+		timer := time.NewTimer(time.Until(next[earliest]))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		now := time.Now()
+		for i, job := range r.Jobs {
+			if next[i].After(now) {
+				continue
+			}
+			r.runJob(ctx, job, next[i])
+			next[i] = job.Schedule.Next(time.Now())
+		}
+	}
+}
+
+func (r *Runner) runJob(ctx context.Context, job Job, scheduledAt time.Time) {
+	_, err := r.Repo.WithAdvisoryLock(ctx, "jobs:"+job.Name, func(ctx context.Context) error {
+		runID, claimed, err := r.Repo.ClaimJobRun(ctx, job.Name, scheduledAt)
+		if err != nil || !claimed {
+			return err
+		}
+		detail, runErr := job.Run(ctx, scheduledAt)
+		if runErr != nil {
+			log.Printf("job %s: %v", job.Name, runErr)
+		}
+		return r.Repo.FinishJobRun(context.WithoutCancel(ctx), runID, runErr, detail)
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Printf("job %s: %v", job.Name, err)
+	}
+}
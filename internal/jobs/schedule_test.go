@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleNext(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, _ := time.Parse(time.RFC3339, value)
+		return parsed
+	}
+	cases := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"30 3 * * *", "2024-01-01T03:30:00Z", "2024-01-02T03:30:00Z"},
+		{"*/15 * * * *", "2024-01-01T10:07:10Z", "2024-01-01T10:15:00Z"},
+		{"0 9 * * 1-5", "2024-01-05T09:00:00Z", "2024-01-08T09:00:00Z"},
+		{"0 0 1,15 * *", "2024-01-02T00:00:00Z", "2024-01-15T00:00:00Z"},
+		{"0 0 * * 7", "2024-01-01T00:00:00Z", "2024-01-07T00:00:00Z"},
+		{"@hourly", "2024-01-01T10:59:59Z", "2024-01-01T11:00:00Z"},
+		{"@every 15m", "2024-01-01T10:07:00Z", "2024-01-01T10:15:00Z"},
+	}
+	for _, tc := range cases {
+		schedule, err := ParseSchedule(tc.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.spec, err)
+		}
+		if got := schedule.Next(at(tc.from)); !got.Equal(at(tc.want)) {
+			t.Fatalf("%s after %s: expected %s, got %s", tc.spec, tc.from, tc.want, got.Format(time.RFC3339))
+		}
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "0 0 0 * *", "*/0 * * * *", "@every 1ms"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Fatalf("expected %q to be rejected", spec)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// Package media validates uploaded files. Images are decoded and re-encoded,
+// which drops EXIF and every other metadata block; the EXIF orientation of a
+// JPEG is applied to the pixels first so photos keep their rotation.
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported file type")
+	ErrTooManyPixels   = errors.New("image dimensions too large")
+	ErrCorrupt         = errors.New("file could not be decoded")
+)
+
+// MaxPixels bounds width × height of accepted images, so a small compressed
+// file cannot expand into gigabytes when decoded.
+const MaxPixels = 40_000_000
+
+// jpegQuality is used when re-encoding JPEGs.
+const jpegQuality = 90
+
+// Types maps the accepted content types to file extensions.
+var Types = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"application/pdf": ".pdf",
+}
+
+// File is a sanitized upload.
+type File struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Sanitize sniffs the content type from the data itself, ignoring whatever
+// the client claimed, and re-encodes images.
+func Sanitize(data []byte) (File, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := Types[contentType]; !ok {
+		return File{}, ErrUnsupportedType
+	}
+	if contentType == "application/pdf" {
+		return File{Data: data, ContentType: contentType}, nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return File{}, ErrCorrupt
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return File{}, ErrTooManyPixels
+	}
+	var out bytes.Buffer
+	var bounds image.Rectangle
+	switch contentType {
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return File{}, ErrCorrupt
+		}
+		img = orient(img, jpegOrientation(data))
+		bounds = img.Bounds()
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return File{}, err
+		}
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return File{}, ErrCorrupt
+		}
+		bounds = img.Bounds()
+		if err := png.Encode(&out, img); err != nil {
+			return File{}, err
+		}
+	case "image/gif":
+		// Every frame is decoded at once, so the frames are counted first.
+		frames, err := gifFrames(data, MaxPixels/(config.Width*config.Height))
+		if err != nil {
+			return File{}, ErrCorrupt
+		}
+		if config.Width*config.Height*frames > MaxPixels {
+			return File{}, ErrTooManyPixels
+		}
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(anim.Image) == 0 {
+			return File{}, ErrCorrupt
+		}
+		bounds = image.Rect(0, 0, anim.Config.Width, anim.Config.Height)
+		clean := &gif.GIF{Image: anim.Image, Delay: anim.Delay, LoopCount: anim.LoopCount, Disposal: anim.Disposal, Config: anim.Config, BackgroundIndex: anim.BackgroundIndex}
+		if err := gif.EncodeAll(&out, clean); err != nil {
+			return File{}, err
+		}
+	}
+	return File{Data: out.Bytes(), ContentType: contentType, Width: bounds.Dx(), Height: bounds.Dy()}, nil
+}
+
+// gifFrames counts the image descriptors of a GIF by walking its blocks,
+// without decompressing any frame. Counting stops once it passes limit.
+func gifFrames(data []byte, limit int) (int, error) {
+	if len(data) < 13 {
+		return 0, ErrCorrupt
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&7 + 1) // global color table
+	}
+	frames := 0
+	for pos < len(data) && frames <= limit {
+		switch data[pos] {
+		case 0x3B: // trailer
+			return frames, nil
+		case 0x21: // extension: introducer and label
+			pos += 2
+		case 0x2C: // image descriptor
+			if pos+10 > len(data) {
+				return 0, ErrCorrupt
+			}
+			packed := data[pos+9]
+			pos += 10
+			if packed&0x80 != 0 {
+				pos += 3 << (packed&7 + 1) // local color table
+			}
+			pos++ // LZW minimum code size
+			frames++
+		default:
+			return 0, ErrCorrupt
+		}
+		// Data sub-blocks, up to the zero-length terminator.
+		for {
+			if pos >= len(data) {
+				return 0, ErrCorrupt
+			}
+			size := int(data[pos])
+			pos += 1 + size
+			if size == 0 {
+				break
+			}
+		}
+	}
+	return frames, nil
+}
+
+// jpegOrientation returns the EXIF orientation (1-8) of a JPEG, or 1 when it
+// has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// Start of scan or end of image: no more metadata segments.
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads tag 0x0112 from the first IFD of a TIFF header.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient applies an EXIF orientation so that the image displays upright
+// without the tag.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	src := image.NewRGBA(img.Bounds().Sub(img.Bounds().Min))
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.SetRGBA(dx, dy, src.RGBAAt(x, y))
+		}
+	}
+	return dst
+}
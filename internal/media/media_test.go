@@ -0,0 +1,91 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+)
+
+// exifSegment builds an APP1 segment holding only an orientation tag.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2A\x00\x00\x00\x08")
+	ifd := make([]byte, 2+12+4)
+	binary.BigEndian.PutUint16(ifd[0:], 1)
+	binary.BigEndian.PutUint16(ifd[2:], 0x0112)
+	binary.BigEndian.PutUint16(ifd[4:], 3) // SHORT
+	binary.BigEndian.PutUint32(ifd[6:], 1)
+	binary.BigEndian.PutUint16(ifd[10:], orientation)
+	payload := append([]byte("Exif\x00\x00"), append(tiff, ifd...)...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestSanitizeStripsExifAndAppliesOrientation(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 2; y++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	raw := encoded.Bytes()
+	withExif := append(append(append([]byte{}, raw[:2]...), exifSegment(6)...), raw[2:]...)
+	if jpegOrientation(withExif) != 6 {
+		t.Fatalf("expected orientation 6, got %d", jpegOrientation(withExif))
+	}
+
+	file, err := Sanitize(withExif)
+	if err != nil {
+		t.Fatalf("sanitize: %v", err)
+	}
+	if file.ContentType != "image/jpeg" || file.Width != 2 || file.Height != 4 {
+		t.Fatalf("expected a rotated 2x4 jpeg, got %s %dx%d", file.ContentType, file.Width, file.Height)
+	}
+	if bytes.Contains(file.Data, []byte("Exif")) {
+		t.Fatal("EXIF survived re-encoding")
+	}
+}
+
+func TestSanitizeRejectsUnsupported(t *testing.T) {
+	if _, err := Sanitize([]byte("<html><script>alert(1)</script></html>")); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("expected ErrUnsupportedType, got %v", err)
+	}
+	if _, err := Sanitize([]byte("\xFF\xD8\xFFnot really a jpeg")); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestSanitizeCountsGIFFramesBeforeDecoding(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 4, 4), palette))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var encoded bytes.Buffer
+	if err := gif.EncodeAll(&encoded, anim); err != nil {
+		t.Fatal(err)
+	}
+	if frames, err := gifFrames(encoded.Bytes(), 100); err != nil || frames != 3 {
+		t.Fatalf("expected 3 frames, got %d err=%v", frames, err)
+	}
+	if frames, err := gifFrames(encoded.Bytes(), 1); err != nil || frames != 2 {
+		t.Fatalf("counting should stop past the limit, got %d err=%v", frames, err)
+	}
+	if _, err := gifFrames(encoded.Bytes()[:encoded.Len()-6], 100); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for a truncated gif, got %v", err)
+	}
+	file, err := Sanitize(encoded.Bytes())
+	if err != nil || file.ContentType != "image/gif" || file.Width != 4 || file.Height != 4 {
+		t.Fatalf("sanitize: %+v err=%v", file, err)
+	}
+}
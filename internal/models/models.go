@@ -39,6 +39,18 @@ type Goal struct {
 	UpdatedAt   time.Time  `json:"updated_at"`
 	DeletedAt   *time.Time `json:"deleted_at"`
 	Version     int        `json:"version"`
+	// Progress is returned by the goal list, not by sync.
+	Progress *GoalProgress `json:"progress,omitempty"`
+}
+
+// GoalProgress aggregates the tasks linked to a goal.
+type GoalProgress struct {
+	TasksTotal           int      `json:"tasks_total"`
+	TasksDone            int      `json:"tasks_done"`
+	OccurrencesScheduled int      `json:"occurrences_scheduled"`
+	OccurrencesDone      int      `json:"occurrences_done"`
+	Earned               float64  `json:"earned"`
+	Percent              *float64 `json:"percent"`
 }
 
 type Task struct {
@@ -61,6 +73,127 @@ type Task struct {
 	UpdatedAt   time.Time  `json:"updated_at"`
 	DeletedAt   *time.Time `json:"deleted_at"`
 	Version     int        `json:"version"`
+
+	AssigneeOnly     bool     `json:"assignee_only"`
+	AssigneeIDs      []string `json:"assignee_ids"`
+	RequireChecklist bool     `json:"require_checklist"`
+	Priority         int      `json:"priority"`
+	TagIDs           []string `json:"tag_ids"`
+	BlockedBy        []string `json:"blocked_by"`
+	Blocks           []string `json:"blocks"`
+	AttachmentIDs    []string `json:"attachment_ids"`
+
+	StreakBonusPercent float64 `json:"streak_bonus_percent"`
+	CurrentStreak      int     `json:"current_streak"`
+	LongestStreak      int     `json:"longest_streak"`
+
+	Target           *float64 `json:"target"`
+	Unit             *string  `json:"unit"`
+	MaxPayoutPercent float64  `json:"max_payout_percent"`
+	Progress         float64  `json:"progress"`
+
+	Penalty    float64 `json:"penalty"`
+	PayPerHour bool    `json:"pay_per_hour"`
+
+	PostponeDecayPercent float64 `json:"postpone_decay_percent"`
+	PostponedCount       int     `json:"postponed_count"`
+}
+
+type Tag struct {
+	ID          string     `json:"id"`
+	WorkspaceID string     `json:"workspace_id"`
+	Name        string     `json:"name"`
+	Color       *string    `json:"color"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	Version     int        `json:"version"`
+}
+
+type TimeEntry struct {
+	ID              string     `json:"id"`
+	TaskID          string     `json:"task_id"`
+	UserID          string     `json:"user_id"`
+	OccurrenceDate  *string    `json:"occurrence_date"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+	Running         bool       `json:"running"`
+	DurationSeconds int64      `json:"duration_seconds"`
+	Note            string     `json:"note"`
+	Source          string     `json:"source"`
+}
+
+type Comment struct {
+	ID          string     `json:"id"`
+	WorkspaceID string     `json:"workspace_id"`
+	EntityType  string     `json:"entity_type"`
+	EntityID    string     `json:"entity_id"`
+	AuthorID    *string    `json:"author_id"`
+	AuthorEmail *string    `json:"author_email"`
+	Body        string     `json:"body"`
+	MentionIDs  []string   `json:"mention_ids"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	EditedAt    *time.Time `json:"edited_at"`
+	Version     int        `json:"version"`
+}
+
+type Upload struct {
+	ID           string    `json:"id"`
+	WorkspaceID  string    `json:"workspace_id"`
+	UploadedBy   *string   `json:"uploaded_by"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	Width        *int      `json:"width"`
+	Height       *int      `json:"height"`
+	OriginalName string    `json:"original_name"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Vacation struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspace_id"`
+	StartDate   string    `json:"start_date"`
+	EndDate     string    `json:"end_date"`
+	Reason      string    `json:"reason"`
+	CreatedBy   *string   `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type TaskReminder struct {
+	ID            string  `json:"id"`
+	TaskID        string  `json:"task_id"`
+	AtTime        *string `json:"at_time"`
+	MinutesBefore *int    `json:"minutes_before"`
+}
+
+type NotificationSettings struct {
+	UserID          string  `json:"user_id"`
+	Timezone        *string `json:"timezone"`
+	QuietHoursStart *string `json:"quiet_hours_start"`
+	QuietHoursEnd   *string `json:"quiet_hours_end"`
+	EmailEnabled    bool    `json:"email_enabled"`
+	WebhookURL      *string `json:"webhook_url"`
+}
+
+type ChecklistItem struct {
+	ID        string     `json:"id"`
+	TaskID    string     `json:"task_id"`
+	Title     string     `json:"title"`
+	Position  int        `json:"position"`
+	Done      bool       `json:"done"`
+	DoneAt    *time.Time `json:"done_at"`
+	Value     float64    `json:"value"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	Version   int        `json:"version"`
+}
+
+type TaskAssignee struct {
+	TaskID    string    `json:"task_id"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Reward struct {
@@ -72,6 +205,7 @@ type Reward struct {
 	IsShared      bool       `json:"is_shared"`
 	CooldownHours *int       `json:"cooldown_hours"`
 	OneTime       bool       `json:"one_time"`
+	AttachmentIDs []string   `json:"attachment_ids"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
 	DeletedAt     *time.Time `json:"deleted_at"`
@@ -89,28 +223,31 @@ type RewardPurchase struct {
 }
 
 type Achievement struct {
-	ID          string     `json:"id"`
-	WorkspaceID string     `json:"workspace_id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	ImageURL    *string    `json:"image_url"`
-	AchievedAt  *time.Time `json:"achieved_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	DeletedAt   *time.Time `json:"deleted_at"`
-	Version     int        `json:"version"`
+	ID            string     `json:"id"`
+	WorkspaceID   string     `json:"workspace_id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	ImageURL      *string    `json:"image_url"`
+	AttachmentIDs []string   `json:"attachment_ids"`
+	AchievedAt    *time.Time `json:"achieved_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at"`
+	Version       int        `json:"version"`
 }
 
 type Transaction struct {
-	ID          string    `json:"id"`
-	WorkspaceID string    `json:"workspace_id"`
-	UserID      *string   `json:"user_id"`
-	Type        string    `json:"type"`
-	Amount      float64   `json:"amount"`
-	Reason      string    `json:"reason"`
-	EntityType  *string   `json:"entity_type"`
-	EntityID    *string   `json:"entity_id"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID                    string     `json:"id"`
+	WorkspaceID           string     `json:"workspace_id"`
+	UserID                *string    `json:"user_id"`
+	Type                  string     `json:"type"`
+	Amount                float64    `json:"amount"`
+	Reason                string     `json:"reason"`
+	EntityType            *string    `json:"entity_type"`
+	EntityID              *string    `json:"entity_id"`
+	OccurrenceDate        *time.Time `json:"occurrence_date"`
+	ReversesTransactionID *string    `json:"reverses_transaction_id"`
+	CreatedAt             time.Time  `json:"created_at"`
 }
 
 type WorkspaceBalance struct {
@@ -0,0 +1,138 @@
+// Package netguard keeps requests to user-supplied URLs, such as webhooks and
+// push endpoints, away from the server's own network. URLs must be https, and
+// connections are refused at dial time to loopback, private, link-local and
+// other non-public addresses, so DNS rebinding and redirects cannot reach
+// them either.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxRedirects bounds the redirects Client follows.
+const maxRedirects = 5
+
+var (
+	// ErrForbiddenAddress is returned when a URL resolves to, or names, an
+	// address that is not public.
+	ErrForbiddenAddress = errors.New("address is not public")
+	// ErrInsecureURL is returned for URLs that are not https.
+	ErrInsecureURL = errors.New("url must be https")
+)
+
+// nonPublic lists the special-purpose ranges that IsPublic rejects besides
+// the loopback, private, link-local, multicast and unspecified ones.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// IsPublic reports whether addr is a globally routable unicast address.
+// IPv4-mapped IPv6 addresses are judged as IPv4.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range nonPublic {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL validates a user-supplied URL before it is stored: https, with a
+// host that is neither localhost nor a non-public IP literal. Hostnames are
+// checked again on every connection by Client.
+func CheckURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "https" {
+		return ErrInsecureURL
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return errors.New("url has no host")
+	}
+	if lower := strings.ToLower(strings.TrimSuffix(host, ".")); lower == "localhost" || strings.HasSuffix(lower, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublic(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// control is a net.Dialer Control hook refusing non-public addresses. It
+// runs after name resolution, for every address tried.
+func control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !IsPublic(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
+
+// httpsOnly rejects plain http requests, including redirected ones.
+type httpsOnly struct {
+	next http.RoundTripper
+}
+
+func (t httpsOnly) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return nil, ErrInsecureURL
+	}
+	return t.next.RoundTrip(req)
+}
+
+// Client returns an HTTP client for user-supplied URLs: https only, no proxy,
+// connections only to public addresses and at most a few redirects, each
+// checked like the original URL.
+func Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second, Control: control}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        20,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: httpsOnly{next: transport},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return CheckURL(req.URL.String())
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package netguard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+		"::ffff:8.8.8.8":   true,
+	} {
+		if got := IsPublic(netip.MustParseAddr(addr)); got != want {
+			t.Fatalf("IsPublic(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for raw, want := range map[string]error{
+		"https://hooks.example.com/x":    nil,
+		"http://hooks.example.com/x":     ErrInsecureURL,
+		"https://localhost:8080/":        ErrForbiddenAddress,
+		"https://169.254.169.254/latest": ErrForbiddenAddress,
+		"https://[::1]/":                 ErrForbiddenAddress,
+		"https://93.184.215.14/hook":     nil,
+	} {
+		if err := CheckURL(raw); !errors.Is(err, want) {
+			t.Fatalf("CheckURL(%s) = %v, want %v", raw, err, want)
+		}
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	if _, err := Client(time.Second).Get(server.URL); !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("expected loopback to be refused, got %v", err)
+	}
+	if _, err := Client(time.Second).Get("http://example.com/"); !errors.Is(err, ErrInsecureURL) {
+		t.Fatalf("expected http to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"firegoals/internal/netguard"
+	"firegoals/internal/repo"
+	"firegoals/internal/webpush"
+)
+
+// Mailer sends a plain-text email.
+type Mailer interface {
+	SendMail(ctx context.Context, to, subject, body string) error
+}
+
+// EmailChannel delivers notifications by email through a Mailer.
+type EmailChannel struct {
+	Mailer Mailer
+}
+
+func (c EmailChannel) Send(ctx context.Context, n repo.Notification) error {
+	if n.Email == "" {
+		return fmt.Errorf("%w: no email address", ErrPermanent)
+	}
+	title, body := payloadText(n)
+	return c.Mailer.SendMail(ctx, n.Email, title, body)
+}
+
+// SMTPMailer sends mail through an SMTP server with PLAIN auth when a username
+// is set.
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (m SMTPMailer) SendMail(ctx context.Context, to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host := m.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	msg := "From: " + m.From + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n" + body
+	return smtp.SendMail(m.Addr, auth, m.From, []string{to}, []byte(msg))
+}
+
+// WebhookChannel POSTs notifications as JSON to the recipient's webhook URL.
+// Without a Client it uses a netguard client, which only reaches public https
+// hosts.
+type WebhookChannel struct {
+	Client *http.Client
+}
+
+func (c WebhookChannel) Send(ctx context.Context, n repo.Notification) error {
+	if n.WebhookURL == nil || *n.WebhookURL == "" {
+		return fmt.Errorf("%w: no webhook url", ErrPermanent)
+	}
+	client := c.Client
+	if client == nil {
+		if err := netguard.CheckURL(*n.WebhookURL); err != nil {
+			return fmt.Errorf("%w: webhook url: %v", ErrPermanent, err)
+		}
+		client = netguard.Client(10 * time.Second)
+	}
+	body, err := json.Marshal(map[string]any{"id": n.ID, "kind": n.Kind, "user_id": n.UserID, "payload": n.Payload})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *n.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// PushStore is the subscription storage WebPushChannel needs.
+type PushStore interface {
+	ListPushSubscriptions(ctx context.Context, userID string) ([]repo.PushSubscription, error)
+	RemovePushSubscription(ctx context.Context, id string) error
+	TouchPushSubscription(ctx context.Context, id string, at time.Time) error
+}
+
+// pushTTL is how long push services keep a message for an offline device.
+const pushTTL = 24 * time.Hour
+
+// WebPushChannel delivers notifications to every browser the recipient has
+// subscribed. Subscriptions the push service reports gone are deleted; a
+// transient failure on any of them retries the whole notification.
+type WebPushChannel struct {
+	Store  PushStore
+	Sender *webpush.Sender
+}
+
+func (c WebPushChannel) Send(ctx context.Context, n repo.Notification) error {
+	subs, err := c.Store.ListPushSubscriptions(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+	title, body := payloadText(n)
+	payload, err := json.Marshal(map[string]any{"id": n.ID, "kind": n.Kind, "title": title, "body": body, "data": n.Payload})
+	if err != nil {
+		return err
+	}
+	var delivered int
+	var lastErr error
+	for _, sub := range subs {
+		err := c.Sender.Send(ctx, webpush.Subscription{Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth}, payload, pushTTL)
+		switch {
+		case err == nil:
+			delivered++
+			if err := c.Store.TouchPushSubscription(ctx, sub.ID, time.Now()); err != nil {
+				return err
+			}
+		case errors.Is(err, webpush.ErrGone):
+			if err := c.Store.RemovePushSubscription(ctx, sub.ID); err != nil {
+				return err
+			}
+		default:
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+	if delivered == 0 {
+		return fmt.Errorf("%w: no push subscriptions", ErrPermanent)
+	}
+	return nil
+}
+
+// FakeChannel records notifications instead of delivering them; set Err to
+// make every send fail.
+type FakeChannel struct {
+	mu   sync.Mutex
+	Sent []repo.Notification
+	Err  error
+}
+
+func (c *FakeChannel) Send(ctx context.Context, n repo.Notification) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return c.Err
+	}
+	c.Sent = append(c.Sent, n)
+	return nil
+}
+
+// FakeMailer records mail instead of sending it.
+type FakeMailer struct {
+	mu   sync.Mutex
+	Sent []string
+}
+
+func (m *FakeMailer) SendMail(ctx context.Context, to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Sent = append(m.Sent, to+": "+subject)
+	return nil
+}
+
+// payloadText extracts the title and body strings of a notification payload.
+func payloadText(n repo.Notification) (string, string) {
+	title, _ := n.Payload["title"].(string)
+	body, _ := n.Payload["body"].(string)
+	if title == "" {
+		title = n.Kind
+	}
+	return title, body
+}
@@ -0,0 +1,141 @@
+// Package notify delivers queued notifications from the outbox through
+// pluggable channels.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"firegoals/internal/repo"
+)
+
+const (
+	// maxAttempts is how many failed deliveries a notification gets before it
+	// is marked failed.
+	maxAttempts = 8
+	batchSize   = 100
+)
+
+// ErrPermanent wraps delivery errors that retrying cannot fix, such as a
+// recipient without an address.
+var ErrPermanent = errors.New("permanent delivery failure")
+
+// Channel delivers a notification to its recipient.
+type Channel interface {
+	Send(ctx context.Context, n repo.Notification) error
+}
+
+// Outbox is the storage the dispatcher reads from and reports outcomes to.
+type Outbox interface {
+	ListDueNotifications(ctx context.Context, now time.Time, limit int) ([]repo.Notification, error)
+	MarkNotificationSent(ctx context.Context, id string) error
+	RetryNotification(ctx context.Context, id string, retryAt *time.Time, lastError string) error
+	DeferNotification(ctx context.Context, id string, until time.Time) error
+}
+
+// Dispatcher sends due outbox rows through the channel named by each row.
+type Dispatcher struct {
+	Outbox   Outbox
+	Channels map[string]Channel
+}
+
+// Deliver sends every due notification once. Failures are rescheduled with
+// exponential backoff; notifications inside the recipient's quiet hours wait
+// until they end. It returns a short summary of the run.
+func (d *Dispatcher) Deliver(ctx context.Context, now time.Time) (string, error) {
+	items, err := d.Outbox.ListDueNotifications(ctx, now, batchSize)
+	if err != nil {
+		return "", err
+	}
+	var sent, retried, failed, deferred int
+	for _, item := range items {
+		if until, quiet := quietUntil(item.QuietStart, item.QuietEnd, item.Timezone, now); quiet {
+			if err := d.Outbox.DeferNotification(ctx, item.ID, until); err != nil {
+				return "", err
+			}
+			deferred++
+			continue
+		}
+		sendErr := fmt.Errorf("%w: channel %q not configured", ErrPermanent, item.Channel)
+		if channel, ok := d.Channels[item.Channel]; ok {
+			sendErr = channel.Send(ctx, item)
+		}
+		if sendErr == nil {
+			if err := d.Outbox.MarkNotificationSent(ctx, item.ID); err != nil {
+				return "", err
+			}
+			sent++
+			continue
+		}
+		var retryAt *time.Time
+		if item.Attempts+1 < maxAttempts && !errors.Is(sendErr, ErrPermanent) {
+			next := now.Add(retryDelay(item.Attempts + 1))
+			retryAt = &next
+			retried++
+		} else {
+			failed++
+		}
+		if err := d.Outbox.RetryNotification(ctx, item.ID, retryAt, sendErr.Error()); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("sent=%d retried=%d failed=%d deferred=%d", sent, retried, failed, deferred), nil
+}
+
+// retryDelay doubles from one minute per failed attempt, capped at six hours.
+func retryDelay(attempt int) time.Duration {
+	const maxDelay = 6 * time.Hour
+	if attempt > 10 {
+		return maxDelay
+	}
+	delay := time.Minute << (attempt - 1)
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// quietUntil reports whether now falls inside the quiet hours (local "HH:MM"
+// bounds, possibly wrapping past midnight) and when they end.
+func quietUntil(start, end, timezone *string, now time.Time) (time.Time, bool) {
+	if start == nil || end == nil || *start == *end {
+		return time.Time{}, false
+	}
+	from, err := time.Parse("15:04", *start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	to, err := time.Parse("15:04", *end)
+	if err != nil {
+		return time.Time{}, false
+	}
+	loc := time.UTC
+	if timezone != nil && *timezone != "" {
+		if parsed, err := time.LoadLocation(*timezone); err == nil {
+			loc = parsed
+		}
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	fromMinute := from.Hour()*60 + from.Minute()
+	toMinute := to.Hour()*60 + to.Minute()
+	endsAt := func(dayOffset int) time.Time {
+		return time.Date(local.Year(), local.Month(), local.Day()+dayOffset, to.Hour(), to.Minute(), 0, 0, loc)
+	}
+	if fromMinute < toMinute {
+		if minute >= fromMinute && minute < toMinute {
+			return endsAt(0), true
+		}
+		return time.Time{}, false
+	}
+	// Wrapping window, e.g. 22:00-07:00.
+	if minute >= fromMinute {
+		return endsAt(1), true
+	}
+	if minute < toMinute {
+		return endsAt(0), true
+	}
+	return time.Time{}, false
+}
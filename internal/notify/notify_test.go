@@ -0,0 +1,147 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"firegoals/internal/repo"
+	"firegoals/internal/webpush"
+)
+
+type fakeOutbox struct {
+	due      []repo.Notification
+	sent     []string
+	retries  map[string]*time.Time
+	deferred map[string]time.Time
+}
+
+func (o *fakeOutbox) ListDueNotifications(ctx context.Context, now time.Time, limit int) ([]repo.Notification, error) {
+	return o.due, nil
+}
+
+func (o *fakeOutbox) MarkNotificationSent(ctx context.Context, id string) error {
+	o.sent = append(o.sent, id)
+	return nil
+}
+
+func (o *fakeOutbox) RetryNotification(ctx context.Context, id string, retryAt *time.Time, lastError string) error {
+	o.retries[id] = retryAt
+	return nil
+}
+
+func (o *fakeOutbox) DeferNotification(ctx context.Context, id string, until time.Time) error {
+	o.deferred[id] = until
+	return nil
+}
+
+func TestDispatcherDeliver(t *testing.T) {
+	quietStart, quietEnd := "22:00", "07:00"
+	now := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	outbox := &fakeOutbox{
+		due: []repo.Notification{
+			{ID: "ok", Channel: "email", Email: "a@b.com", Payload: map[string]any{"title": "Read"}},
+			{ID: "quiet", Channel: "email", Email: "a@b.com", QuietStart: &quietStart, QuietEnd: &quietEnd},
+			{ID: "flaky", Channel: "webhook", Attempts: 2},
+			{ID: "exhausted", Channel: "webhook", Attempts: maxAttempts - 1},
+			{ID: "unknown", Channel: "sms"},
+		},
+		retries:  map[string]*time.Time{},
+		deferred: map[string]time.Time{},
+	}
+	mailer := &FakeMailer{}
+	webhook := &FakeChannel{Err: errors.New("503")}
+	dispatcher := &Dispatcher{Outbox: outbox, Channels: map[string]Channel{"email": EmailChannel{Mailer: mailer}, "webhook": webhook}}
+
+	summary, err := dispatcher.Deliver(context.Background(), now)
+	if err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if summary != "sent=1 retried=1 failed=2 deferred=1" {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+	if len(mailer.Sent) != 1 || mailer.Sent[0] != "a@b.com: Read" {
+		t.Fatalf("unexpected mail %v", mailer.Sent)
+	}
+	if until := outbox.deferred["quiet"]; !until.Equal(time.Date(2024, 1, 2, 7, 0, 0, 0, time.UTC)) {
+		t.Fatalf("quiet notification should wait until 07:00, got %v", until)
+	}
+	if retryAt := outbox.retries["flaky"]; retryAt == nil || !retryAt.Equal(now.Add(4*time.Minute)) {
+		t.Fatalf("third attempt should back off 4 minutes, got %v", retryAt)
+	}
+	if outbox.retries["exhausted"] != nil || outbox.retries["unknown"] != nil {
+		t.Fatalf("exhausted and unconfigured notifications should fail for good")
+	}
+}
+
+type fakePushStore struct {
+	subs    []repo.PushSubscription
+	removed []string
+}
+
+func (s *fakePushStore) ListPushSubscriptions(ctx context.Context, userID string) ([]repo.PushSubscription, error) {
+	return s.subs, nil
+}
+
+func (s *fakePushStore) RemovePushSubscription(ctx context.Context, id string) error {
+	s.removed = append(s.removed, id)
+	return nil
+}
+
+func (s *fakePushStore) TouchPushSubscription(ctx context.Context, id string, at time.Time) error {
+	return nil
+}
+
+func TestWebPushChannel(t *testing.T) {
+	service := webpush.NewFakeService()
+	defer service.Close()
+	public, private, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("keys: %v", err)
+	}
+	store := &fakePushStore{}
+	for _, id := range []string{"phone", "laptop"} {
+		sub, err := service.Subscribe()
+		if err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+		store.subs = append(store.subs, repo.PushSubscription{ID: id, Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth})
+	}
+	service.Expire(store.subs[1].Endpoint)
+	channel := WebPushChannel{Store: store, Sender: &webpush.Sender{Client: service.Server.Client(), VAPIDPublicKey: public, VAPIDPrivateKey: private, Subject: "mailto:ops@example.com"}}
+
+	err = channel.Send(context.Background(), repo.Notification{ID: "n1", Kind: "reward_purchased", Payload: map[string]any{"title": "Reward purchased", "body": "Cinema"}})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	received := service.Received()
+	if len(received) != 1 || received[0].Endpoint != store.subs[0].Endpoint {
+		t.Fatalf("expected one delivery to the phone, got %+v", received)
+	}
+	var message map[string]any
+	if err := json.Unmarshal(received[0].Payload, &message); err != nil || message["title"] != "Reward purchased" || message["body"] != "Cinema" {
+		t.Fatalf("unexpected payload %s", received[0].Payload)
+	}
+	if len(store.removed) != 1 || store.removed[0] != "laptop" {
+		t.Fatalf("gone subscription should be removed, got %v", store.removed)
+	}
+
+	store.subs = store.subs[1:]
+	if err := channel.Send(context.Background(), repo.Notification{ID: "n2"}); !errors.Is(err, ErrPermanent) {
+		t.Fatalf("no live subscriptions should fail permanently, got %v", err)
+	}
+}
+
+func TestQuietUntil(t *testing.T) {
+	start, end := "09:00", "12:00"
+	if _, quiet := quietUntil(&start, &end, nil, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)); quiet {
+		t.Fatalf("end of quiet hours is exclusive")
+	}
+	zone := "Europe/Moscow"
+	until, quiet := quietUntil(&start, &end, &zone, time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC))
+	if !quiet || !until.Equal(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("10:00 Moscow is quiet until 12:00 Moscow, got %v %v", until, quiet)
+	}
+}
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AreWorkspaceMembers reports whether every user in userIDs belongs to the workspace.
+func (r *Repo) AreWorkspaceMembers(ctx context.Context, workspaceID string, userIDs []string) (bool, error) {
+	if len(userIDs) == 0 {
+		return true, nil
+	}
+	var count int
+	err := r.Pool.QueryRow(ctx, `SELECT count(DISTINCT user_id) FROM workspace_members WHERE workspace_id=$1 AND user_id::text = ANY($2)`, workspaceID, userIDs).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count == len(uniqueStrings(userIDs)), nil
+}
+
+// SetTaskAssignees replaces the assignee list of a task and bumps its version so
+// sync clients pick up the change.
+func (r *Repo) SetTaskAssignees(ctx context.Context, taskID, workspaceID string, userIDs []string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setTaskAssignees(ctx, tx, taskID, workspaceID, userIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// setTaskAssignees is SetTaskAssignees within tx.
+func setTaskAssignees(ctx context.Context, tx pgx.Tx, taskID, workspaceID string, userIDs []string) error {
+	cmd, err := tx.Exec(ctx, `UPDATE tasks SET updated_at=now(), version=version+1 WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, taskID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM task_assignees WHERE task_id=$1`, taskID); err != nil {
+		return err
+	}
+	for _, userID := range uniqueStrings(userIDs) {
+		if _, err := tx.Exec(ctx, `INSERT INTO task_assignees (task_id, user_id) VALUES ($1, $2)`, taskID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireAssignee returns ErrNotAssignee unless the user is assigned to the task
+// or owns the workspace.
+func requireAssignee(ctx context.Context, tx pgx.Tx, taskID, workspaceID, userID string) error {
+	var allowed bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM task_assignees WHERE task_id=$1 AND user_id=$2)
+		OR EXISTS(SELECT 1 FROM workspace_members WHERE workspace_id=$3 AND user_id=$2 AND role='owner')`, taskID, userID, workspaceID).Scan(&allowed); err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrNotAssignee
+	}
+	return nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	res := make([]string, 0, len(values))
+	for _, value := range values {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		res = append(res, value)
+	}
+	return res
+}
@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Repo) ListChecklistItems(ctx context.Context, taskID, workspaceID string) ([]map[string]any, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT i.id, i.title, i.position, i.done, i.done_at, i.value, i.created_at, i.updated_at, i.version
+		FROM task_checklist_items i JOIN tasks ON tasks.id = i.task_id
+		WHERE i.task_id=$1 AND tasks.workspace_id=$2 AND i.deleted_at IS NULL
+		ORDER BY i.position, i.created_at`, taskID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []map[string]any
+	for rows.Next() {
+		var id, title string
+		var position, version int
+		var done bool
+		var doneAt *time.Time
+		var value float64
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&id, &title, &position, &done, &doneAt, &value, &createdAt, &updatedAt, &version); err != nil {
+			return nil, err
+		}
+		res = append(res, map[string]any{
+			"id": id, "task_id": taskID, "title": title, "position": position, "done": done, "done_at": doneAt, "value": value, "created_at": createdAt, "updated_at": updatedAt, "version": version,
+		})
+	}
+	return res, rows.Err()
+}
+
+// CreateChecklistItem appends an item to the end of the task's checklist.
+func (r *Repo) CreateChecklistItem(ctx context.Context, taskID, workspaceID, title string, value float64) (string, error) {
+	var id string
+	err := r.Pool.QueryRow(ctx, `INSERT INTO task_checklist_items (task_id, title, value, position)
+		SELECT tasks.id, $3, $4, COALESCE((SELECT max(position) + 1 FROM task_checklist_items WHERE task_id = tasks.id AND deleted_at IS NULL), 0)
+		FROM tasks WHERE tasks.id=$1 AND tasks.workspace_id=$2 AND tasks.deleted_at IS NULL
+		RETURNING id`, taskID, workspaceID, title, value).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return id, err
+}
+
+// ReorderChecklistItems sets item positions to their index in itemIDs.
+func (r *Repo) ReorderChecklistItems(ctx context.Context, taskID, workspaceID string, itemIDs []string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for position, itemID := range itemIDs {
+		cmd, err := tx.Exec(ctx, `UPDATE task_checklist_items SET position=$1, updated_at=now(), version=version+1
+			WHERE id=$2 AND task_id=$3 AND deleted_at IS NULL
+			AND task_id IN (SELECT id FROM tasks WHERE workspace_id=$4 AND deleted_at IS NULL)`, position, itemID, taskID, workspaceID)
+		if err != nil {
+			return err
+		}
+		if cmd.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// ToggleChecklistItem sets an item's done state. Checking an item with a value
+// credits it as an 'earn'; unchecking reverses that earn. Earns of a recurring
+// task's earlier occurrences are filed under their dates and stay. The returned amount is
+// positive for credits and negative for reversals; changed is false when the item
+// already had the requested state.
+func (r *Repo) ToggleChecklistItem(ctx context.Context, itemID, taskID, workspaceID, userID string, done bool) (float64, bool, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var value float64
+	err = tx.QueryRow(ctx, `UPDATE task_checklist_items SET done=$1, done_at=CASE WHEN $1 THEN now() ELSE NULL END, updated_at=now(), version=version+1
+		WHERE id=$2 AND task_id=$3 AND done!=$1 AND deleted_at IS NULL
+		AND task_id IN (SELECT id FROM tasks WHERE workspace_id=$4 AND deleted_at IS NULL)
+		RETURNING value`, done, itemID, taskID, workspaceID).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM task_checklist_items i JOIN tasks ON tasks.id = i.task_id
+			WHERE i.id=$1 AND i.task_id=$2 AND tasks.workspace_id=$3 AND i.deleted_at IS NULL AND tasks.deleted_at IS NULL)`, itemID, taskID, workspaceID).Scan(&exists); err != nil {
+			return 0, false, err
+		}
+		if !exists {
+			return 0, false, ErrNotFound
+		}
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	amount := 0.0
+	if value > 0 {
+		if done {
+			if err := creditEarn(ctx, tx, workspaceID, userID, value, "checklist item completed", "checklist_item", itemID, nil); err != nil {
+				return 0, false, err
+			}
+			amount = value
+		} else {
+			reversed, err := reverseEarns(ctx, tx, workspaceID, userID, "checklist_item", itemID, nil, value, "checklist item reopened", false)
+			if err != nil {
+				return 0, false, err
+			}
+			amount = -reversed
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, err
+	}
+	return amount, true, nil
+}
+
+func (r *Repo) DeleteChecklistItem(ctx context.Context, itemID, taskID, workspaceID string) error {
+	cmd, err := r.Pool.Exec(ctx, `UPDATE task_checklist_items SET deleted_at=now(), updated_at=now(), version=version+1
+		WHERE id=$1 AND task_id=$2 AND deleted_at IS NULL
+		AND task_id IN (SELECT id FROM tasks WHERE workspace_id=$3)`, itemID, taskID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CommentEntities maps the entity types that can be commented on to their
+// tables.
+var CommentEntities = map[string]string{"task": "tasks", "goal": "goals"}
+
+// mentionPattern matches @handle, where handle is a member's email or the part
+// of it before the @. The mention must start the text or follow a non-word
+// character so that email addresses in the body are not read as mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.+-]+(?:@[\w-]+(?:\.[\w-]+)+)?)`)
+
+// mentionHandles returns the distinct lower-cased handles mentioned in body.
+func mentionHandles(body string) []string {
+	var handles []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		handle := strings.ToLower(strings.TrimRight(match[1], ".-"))
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// resolveMentions returns the ids of workspace members, other than the author,
+// whose email or email local part is mentioned in body.
+func resolveMentions(ctx context.Context, tx pgx.Tx, workspaceID, authorID, body string) ([]string, error) {
+	handles := mentionHandles(body)
+	if len(handles) == 0 {
+		return []string{}, nil
+	}
+	rows, err := tx.Query(ctx, `SELECT u.id::text FROM workspace_members m JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id=$1 AND u.id <> $2 AND (lower(u.email) = ANY($3) OR lower(split_part(u.email, '@', 1)) = ANY($3))
+		ORDER BY u.id`, workspaceID, authorID, handles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// commentTarget loads the title of the commented task or goal.
+func commentTarget(ctx context.Context, tx pgx.Tx, entityType, entityID, workspaceID string) (string, error) {
+	table, ok := CommentEntities[entityType]
+	if !ok {
+		return "", ErrNotFound
+	}
+	var title string
+	err := tx.QueryRow(ctx, `SELECT title FROM `+table+` WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, entityID, workspaceID).Scan(&title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return title, err
+}
+
+// notifyMentions queues a mention notification for each user. The dedupe key
+// is per comment, so editing a comment only notifies newly mentioned members.
+func notifyMentions(ctx context.Context, tx pgx.Tx, commentID, entityType, entityID, workspaceID, authorID, title, body string, userIDs []string) error {
+	for _, userID := range userIDs {
+		if _, err := tx.Exec(ctx, `INSERT INTO notification_outbox (user_id, channel, kind, dedupe_key, payload)
+			SELECT $1, channel, 'mention', $2, $3 FROM (`+userChannelsQuery("$1")+`) channels
+			ON CONFLICT (user_id, channel, dedupe_key) DO NOTHING`, userID, "mention:"+commentID, map[string]any{
+			"title": "Mentioned on " + title, "body": body, "comment_id": commentID, entityType + "_id": entityID,
+			"workspace_id": workspaceID, "user_id": authorID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateComment adds a comment to a live task or goal and notifies the members
+// it mentions.
+func (r *Repo) CreateComment(ctx context.Context, workspaceID, entityType, entityID, authorID, body string) (string, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+	title, err := commentTarget(ctx, tx, entityType, entityID, workspaceID)
+	if err != nil {
+		return "", err
+	}
+	mentions, err := resolveMentions(ctx, tx, workspaceID, authorID, body)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	if err := tx.QueryRow(ctx, `INSERT INTO comments (workspace_id, entity_type, entity_id, author_id, body, mention_ids)
+		VALUES ($1,$2,$3,$4,$5,$6) RETURNING id`, workspaceID, entityType, entityID, authorID, body, mentions).Scan(&id); err != nil {
+		return "", err
+	}
+	if err := notifyMentions(ctx, tx, id, entityType, entityID, workspaceID, authorID, title, body, mentions); err != nil {
+		return "", err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// UpdateComment replaces the body of the user's own comment, keeping the
+// previous body in the edit history.
+func (r *Repo) UpdateComment(ctx context.Context, id, workspaceID, entityType, entityID, userID, body string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	title, err := commentTarget(ctx, tx, entityType, entityID, workspaceID)
+	if err != nil {
+		return err
+	}
+	var authorID *string
+	var previous string
+	err = tx.QueryRow(ctx, `SELECT author_id::text, body FROM comments
+		WHERE id=$1 AND workspace_id=$2 AND entity_type=$3 AND entity_id=$4 AND deleted_at IS NULL FOR UPDATE`, id, workspaceID, entityType, entityID).Scan(&authorID, &previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if authorID == nil || *authorID != userID {
+		return ErrNotAuthor
+	}
+	if previous == body {
+		return nil
+	}
+	mentions, err := resolveMentions(ctx, tx, workspaceID, userID, body)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO comment_edits (comment_id, body, edited_by) VALUES ($1,$2,$3)`, id, previous, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE comments SET body=$2, mention_ids=$3, edited_at=now(), updated_at=now(), version=version+1 WHERE id=$1`, id, body, mentions); err != nil {
+		return err
+	}
+	if err := notifyMentions(ctx, tx, id, entityType, entityID, workspaceID, userID, title, body, mentions); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteComment soft-deletes a comment. Authors delete their own comments,
+// workspace owners any comment.
+func (r *Repo) DeleteComment(ctx context.Context, id, workspaceID, entityType, entityID, userID string) error {
+	cmd, err := r.Pool.Exec(ctx, `UPDATE comments SET deleted_at=now(), updated_at=now(), version=version+1
+		WHERE id=$1 AND workspace_id=$2 AND entity_type=$3 AND entity_id=$4 AND deleted_at IS NULL
+		AND (author_id=$5 OR EXISTS(SELECT 1 FROM workspace_members WHERE workspace_id=$2 AND user_id=$5 AND role='owner'))`, id, workspaceID, entityType, entityID, userID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() > 0 {
+		return nil
+	}
+	var exists bool
+	if err := r.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id=$1 AND workspace_id=$2 AND entity_type=$3 AND entity_id=$4 AND deleted_at IS NULL)`, id, workspaceID, entityType, entityID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrNotAuthor
+	}
+	return ErrNotFound
+}
+
+// ListComments returns the live comments on a task or goal, oldest first.
+func (r *Repo) ListComments(ctx context.Context, workspaceID, entityType, entityID string) ([]map[string]any, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT c.id, c.author_id::text, u.email, c.body, c.mention_ids::text[], c.created_at, c.updated_at, c.edited_at, c.version
+		FROM comments c LEFT JOIN users u ON u.id = c.author_id
+		WHERE c.workspace_id=$1 AND c.entity_type=$2 AND c.entity_id=$3 AND c.deleted_at IS NULL
+		ORDER BY c.created_at, c.id`, workspaceID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []map[string]any
+	for rows.Next() {
+		var id, body string
+		var authorID, authorEmail *string
+		var mentionIDs []string
+		var createdAt, updatedAt time.Time
+		var editedAt *time.Time
+		var version int
+		if err := rows.Scan(&id, &authorID, &authorEmail, &body, &mentionIDs, &createdAt, &updatedAt, &editedAt, &version); err != nil {
+			return nil, err
+		}
+		res = append(res, map[string]any{
+			"id": id, "workspace_id": workspaceID, "entity_type": entityType, "entity_id": entityID, "author_id": authorID, "author_email": authorEmail,
+			"body": body, "mention_ids": mentionIDs, "created_at": createdAt, "updated_at": updatedAt, "edited_at": editedAt, "version": version,
+		})
+	}
+	return res, rows.Err()
+}
+
+// ListCommentEdits returns the previous bodies of a comment, oldest first.
+func (r *Repo) ListCommentEdits(ctx context.Context, id, workspaceID, entityType, entityID string) ([]map[string]any, error) {
+	var exists bool
+	if err := r.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id=$1 AND workspace_id=$2 AND entity_type=$3 AND entity_id=$4 AND deleted_at IS NULL)`, id, workspaceID, entityType, entityID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+	rows, err := r.Pool.Query(ctx, `SELECT id, body, edited_by::text, edited_at FROM comment_edits WHERE comment_id=$1 ORDER BY edited_at, id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []map[string]any
+	for rows.Next() {
+		var editID, body string
+		var editedBy *string
+		var editedAt time.Time
+		if err := rows.Scan(&editID, &body, &editedBy, &editedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, map[string]any{"id": editID, "comment_id": id, "body": body, "edited_by": editedBy, "edited_at": editedAt})
+	}
+	return res, rows.Err()
+}
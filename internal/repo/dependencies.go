@@ -0,0 +1,252 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// taskBlockedByColumn and taskBlocksColumn select a task's dependency edges as
+// text arrays, ignoring deleted tasks on the other side.
+const taskBlockedByColumn = `COALESCE((SELECT array_agg(td.blocked_by_id::text ORDER BY td.blocked_by_id) FROM task_dependencies td JOIN tasks b ON b.id = td.blocked_by_id AND b.deleted_at IS NULL WHERE td.task_id = tasks.id), '{}') AS blocked_by`
+
+const taskBlocksColumn = `COALESCE((SELECT array_agg(td.task_id::text ORDER BY td.task_id) FROM task_dependencies td JOIN tasks d ON d.id = td.task_id AND d.deleted_at IS NULL WHERE td.blocked_by_id = tasks.id), '{}') AS blocks`
+
+// AreWorkspaceTasks reports whether every id is a live task of the workspace.
+func (r *Repo) AreWorkspaceTasks(ctx context.Context, workspaceID string, taskIDs []string) (bool, error) {
+	if len(taskIDs) == 0 {
+		return true, nil
+	}
+	var count int
+	err := r.Pool.QueryRow(ctx, `SELECT count(*) FROM tasks WHERE workspace_id=$1 AND deleted_at IS NULL AND id::text = ANY($2)`, workspaceID, uniqueStrings(taskIDs)).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count == len(uniqueStrings(taskIDs)), nil
+}
+
+// SetTaskDependencies replaces the tasks that block taskID. Every blocker must
+// be a live task of the same workspace and the new edges must not close a
+// cycle. Tasks gaining or losing a dependent are bumped so that their blocks
+// list syncs.
+func (r *Repo) SetTaskDependencies(ctx context.Context, taskID, workspaceID string, blockedBy []string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setTaskDependencies(ctx, tx, taskID, workspaceID, blockedBy); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// setTaskDependencies is SetTaskDependencies within tx.
+func setTaskDependencies(ctx context.Context, tx pgx.Tx, taskID, workspaceID string, blockedBy []string) error {
+	blockedBy = uniqueStrings(blockedBy)
+	// Serialise dependency edits per workspace so two concurrent edits cannot
+	// each add half of a cycle.
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('task_dependencies:' || $1))`, workspaceID); err != nil {
+		return err
+	}
+	cmd, err := tx.Exec(ctx, `UPDATE tasks SET updated_at=now(), version=version+1 WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, taskID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if len(blockedBy) > 0 {
+		var count int
+		if err := tx.QueryRow(ctx, `SELECT count(*) FROM tasks WHERE workspace_id=$1 AND deleted_at IS NULL AND id::text = ANY($2)`, workspaceID, blockedBy).Scan(&count); err != nil {
+			return err
+		}
+		if count != len(blockedBy) {
+			return ErrInvalidDependency
+		}
+	}
+	edges, err := workspaceDependencies(ctx, tx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if dependencyCycle(edges, taskID, blockedBy) {
+		return ErrDependencyCycle
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET updated_at=now(), version=version+1
+		WHERE workspace_id=$1 AND id::text = ANY($2)`, workspaceID, append(append([]string{}, edges[taskID]...), blockedBy...)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM task_dependencies WHERE task_id=$1`, taskID); err != nil {
+		return err
+	}
+	for _, blockerID := range blockedBy {
+		if _, err := tx.Exec(ctx, `INSERT INTO task_dependencies (task_id, blocked_by_id) VALUES ($1, $2)`, taskID, blockerID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workspaceDependencies loads every blocked_by edge between live tasks of the
+// workspace, keyed by the blocked task.
+func workspaceDependencies(ctx context.Context, tx pgx.Tx, workspaceID string) (map[string][]string, error) {
+	rows, err := tx.Query(ctx, `SELECT td.task_id::text, td.blocked_by_id::text FROM task_dependencies td
+		JOIN tasks t ON t.id = td.task_id AND t.workspace_id=$1 AND t.deleted_at IS NULL
+		JOIN tasks b ON b.id = td.blocked_by_id AND b.deleted_at IS NULL`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	edges := map[string][]string{}
+	for rows.Next() {
+		var taskID, blockerID string
+		if err := rows.Scan(&taskID, &blockerID); err != nil {
+			return nil, err
+		}
+		edges[taskID] = append(edges[taskID], blockerID)
+	}
+	return edges, rows.Err()
+}
+
+// dependencyCycle reports whether making taskID blocked by blockedBy would
+// create a cycle, i.e. whether taskID is reachable from any new blocker by
+// following existing blocked_by edges.
+func dependencyCycle(edges map[string][]string, taskID string, blockedBy []string) bool {
+	seen := map[string]bool{}
+	stack := append([]string{}, blockedBy...)
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if current == taskID {
+			return true
+		}
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+		stack = append(stack, edges[current]...)
+	}
+	return false
+}
+
+// openBlockers reports whether the task has a blocker that is not done. A
+// recurring blocker counts as done when its occurrence on the same date is;
+// one-off tasks compare against their due date.
+func openBlockers(ctx context.Context, tx pgx.Tx, taskID string, occurrenceDate *time.Time) (bool, error) {
+	var blocked bool
+	err := tx.QueryRow(ctx, `SELECT EXISTS(
+		SELECT 1 FROM task_dependencies td
+		JOIN tasks b ON b.id = td.blocked_by_id AND b.deleted_at IS NULL
+		JOIN tasks t ON t.id = td.task_id
+		WHERE td.task_id=$1 AND NOT CASE
+			WHEN b.is_recurring THEN EXISTS(SELECT 1 FROM task_occurrences o WHERE o.task_id = b.id AND o.occurrence_date = COALESCE($2::date, t.due_date) AND o.status = 'done')
+			ELSE b.status = 'done' END)`, taskID, occurrenceDate).Scan(&blocked)
+	return blocked, err
+}
+
+// GoalTaskOrder returns the goal's tasks in dependency order: every task comes
+// after the tasks blocking it, ties keep priority and creation order. Each task
+// carries its level (length of the longest blocker chain within the goal).
+func (r *Repo) GoalTaskOrder(ctx context.Context, goalID, workspaceID string) ([]map[string]any, error) {
+	var exists bool
+	if err := r.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM goals WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL)`, goalID, workspaceID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+	rows, err := r.Pool.Query(ctx, `SELECT id, title, status, is_recurring, priority, `+taskBlockedByColumn+`, `+taskBlocksColumn+`
+		FROM tasks WHERE goal_id=$1 AND workspace_id=$2 AND deleted_at IS NULL
+		ORDER BY priority DESC, created_at, id`, goalID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	byID := map[string]map[string]any{}
+	edges := map[string][]string{}
+	for rows.Next() {
+		var id, title, status string
+		var isRecurring bool
+		var priority int
+		var blockedBy, blocks []string
+		if err := rows.Scan(&id, &title, &status, &isRecurring, &priority, &blockedBy, &blocks); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		edges[id] = blockedBy
+		byID[id] = map[string]any{
+			"id": id, "title": title, "status": status, "is_recurring": isRecurring, "priority": priority, "blocked_by": blockedBy, "blocks": blocks,
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	order, levels := topoOrder(ids, edges)
+	res := make([]map[string]any, 0, len(order))
+	for _, id := range order {
+		task := byID[id]
+		task["level"] = levels[id]
+		res = append(res, task)
+	}
+	return res, nil
+}
+
+// topoOrder sorts ids so that blockers precede the tasks they block, keeping
+// the given order among tasks that are ready at the same time (Kahn's
+// algorithm). Edges to tasks outside ids are ignored. It also returns each
+// task's level: 0 without blockers in ids, else one more than its deepest
+// blocker.
+func topoOrder(ids []string, edges map[string][]string) ([]string, map[string]int) {
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+	pending := make(map[string]int, len(ids))
+	dependents := map[string][]string{}
+	for _, id := range ids {
+		for _, blocker := range edges[id] {
+			if _, ok := index[blocker]; ok {
+				pending[id]++
+				dependents[blocker] = append(dependents[blocker], id)
+			}
+		}
+	}
+	levels := make(map[string]int, len(ids))
+	order := make([]string, 0, len(ids))
+	done := make([]bool, len(ids))
+	for len(order) < len(ids) {
+		// Take the first ready task in the original order so the result is
+		// stable; goals are small, so the quadratic scan is fine.
+		next := -1
+		for i, id := range ids {
+			if !done[i] && pending[id] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			// A cycle cannot be stored, but never loop forever on bad data.
+			for i, id := range ids {
+				if !done[i] {
+					done[i] = true
+					order = append(order, id)
+				}
+			}
+			break
+		}
+		id := ids[next]
+		done[next] = true
+		order = append(order, id)
+		for _, dependent := range dependents[id] {
+			pending[dependent]--
+			if levels[id]+1 > levels[dependent] {
+				levels[dependent] = levels[id] + 1
+			}
+		}
+	}
+	return order, levels
+}
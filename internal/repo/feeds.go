@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CalendarFeed is the owner of a calendar feed token.
+type CalendarFeed struct {
+	WorkspaceID   string
+	WorkspaceName string
+	UserID        string
+}
+
+// hashFeedToken is what calendar_feeds stores instead of the token.
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetCalendarFeed makes token the member's feed token for the workspace,
+// replacing (and so revoking) any previous one.
+func (r *Repo) SetCalendarFeed(ctx context.Context, workspaceID, userID, token string) (time.Time, error) {
+	var createdAt time.Time
+	err := r.Pool.QueryRow(ctx, `INSERT INTO calendar_feeds (workspace_id, user_id, token_hash) VALUES ($1,$2,$3)
+		ON CONFLICT (workspace_id, user_id) DO UPDATE SET token_hash=EXCLUDED.token_hash, created_at=now(), last_used_at=NULL
+		RETURNING created_at`, workspaceID, userID, hashFeedToken(token)).Scan(&createdAt)
+	return createdAt, err
+}
+
+// GetCalendarFeed describes the member's feed for the workspace. The token
+// itself cannot be read back.
+func (r *Repo) GetCalendarFeed(ctx context.Context, workspaceID, userID string) (map[string]any, error) {
+	var createdAt time.Time
+	var lastUsedAt *time.Time
+	err := r.Pool.QueryRow(ctx, `SELECT created_at, last_used_at FROM calendar_feeds WHERE workspace_id=$1 AND user_id=$2`, workspaceID, userID).Scan(&createdAt, &lastUsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"workspace_id": workspaceID, "created_at": createdAt, "last_used_at": lastUsedAt}, nil
+}
+
+func (r *Repo) DeleteCalendarFeed(ctx context.Context, workspaceID, userID string) error {
+	cmd, err := r.Pool.Exec(ctx, `DELETE FROM calendar_feeds WHERE workspace_id=$1 AND user_id=$2`, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CalendarFeedByToken resolves a feed token and records its use. Tokens of
+// members who have left the workspace resolve to ErrNotFound.
+func (r *Repo) CalendarFeedByToken(ctx context.Context, token string) (CalendarFeed, error) {
+	var feed CalendarFeed
+	err := r.Pool.QueryRow(ctx, `UPDATE calendar_feeds f SET last_used_at=now()
+		FROM workspaces w, workspace_members m
+		WHERE f.token_hash=$1 AND w.id=f.workspace_id AND m.workspace_id=f.workspace_id AND m.user_id=f.user_id
+		RETURNING f.workspace_id, w.name, f.user_id`, hashFeedToken(token)).Scan(&feed.WorkspaceID, &feed.WorkspaceName, &feed.UserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return CalendarFeed{}, ErrNotFound
+	}
+	return feed, err
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskFilter narrows task listings. Zero values mean "no filter".
+type TaskFilter struct {
+	AssigneeID *string
+	TagID      *string
+	Priority   *int
+	Status     string
+	GoalID     *string
+	DueBefore  *time.Time
+	DueAfter   *time.Time
+	Recurring  *bool
+	Text       string
+}
+
+// where compiles the filter into SQL conditions on the tasks table. Every value
+// is bound as a parameter numbered after the args the caller already has.
+func (f TaskFilter) where(args []any) (string, []any) {
+	var sb strings.Builder
+	add := func(cond string, value any) {
+		args = append(args, value)
+		sb.WriteString(" AND ")
+		sb.WriteString(strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if f.AssigneeID != nil {
+		add(`EXISTS(SELECT 1 FROM task_assignees WHERE task_id=tasks.id AND user_id=?)`, *f.AssigneeID)
+	}
+	if f.TagID != nil {
+		add(`EXISTS(SELECT 1 FROM task_tags WHERE task_id=tasks.id AND tag_id=?)`, *f.TagID)
+	}
+	if f.Priority != nil {
+		add(`tasks.priority=?`, *f.Priority)
+	}
+	if f.Status != "" {
+		add(`tasks.status=?`, f.Status)
+	}
+	if f.GoalID != nil {
+		add(`tasks.goal_id=?`, *f.GoalID)
+	}
+	if f.DueBefore != nil {
+		add(`tasks.due_date<=?`, *f.DueBefore)
+	}
+	if f.DueAfter != nil {
+		add(`tasks.due_date>=?`, *f.DueAfter)
+	}
+	if f.Recurring != nil {
+		add(`tasks.is_recurring=?`, *f.Recurring)
+	}
+	if f.Text != "" {
+		add(`(tasks.title ILIKE ? OR tasks.description ILIKE ?)`, "%"+escapeLike(f.Text)+"%")
+	}
+	return sb.String(), args
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
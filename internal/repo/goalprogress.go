@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// goalProgressQuery aggregates the live tasks of the goals in $1 that belong
+// to workspace $3. One-off tasks count as done or not; recurring tasks
+// contribute their occurrences from the later of the goal's and the task's
+// start (or creation) to the earlier of the goal's end ($2, today, when
+// open-ended) and the task's end. Skipped occurrences, including pending ones
+// on vacation days, are not scheduled. Earned fire is every earn on the
+// goal's tasks and their checklist items net of reversals.
+const goalProgressQuery = `SELECT g.id,
+		count(t.id) FILTER (WHERE NOT t.is_recurring)::int,
+		count(t.id) FILTER (WHERE NOT t.is_recurring AND t.status = 'done')::int,
+		coalesce(sum(occ.scheduled), 0)::int, coalesce(sum(occ.done), 0)::int, coalesce(sum(fire.earned), 0)
+	FROM goals g
+	LEFT JOIN tasks t ON t.workspace_id = g.workspace_id AND t.goal_id = g.id AND t.deleted_at IS NULL
+	LEFT JOIN LATERAL (
+		SELECT count(*) FILTER (WHERE s.status <> 'skipped') AS scheduled, count(*) FILTER (WHERE s.status = 'done') AS done
+		FROM (
+			SELECT CASE WHEN coalesce(o.status, 'pending') = 'pending' AND EXISTS (
+					SELECT 1 FROM workspace_vacations v WHERE v.workspace_id = t.workspace_id AND day::date BETWEEN v.start_date AND v.end_date
+				) THEN 'skipped' ELSE coalesce(o.status, 'pending') END AS status
+			FROM generate_series(greatest(g.start_date, coalesce(t.start_date, t.created_at::date))::timestamp,
+				least(coalesce(g.end_date, $2::date), t.end_date)::timestamp, interval '1 day') AS day
+			LEFT JOIN task_occurrences o ON o.task_id = t.id AND o.occurrence_date = day::date
+			WHERE t.is_recurring AND extract(dow FROM day)::smallint = ANY(t.recurrence_weekdays)
+		) s
+	) occ ON true
+	LEFT JOIN LATERAL (
+		SELECT sum(CASE tr.type WHEN 'earn' THEN tr.amount WHEN 'reversal' THEN -tr.amount ELSE 0 END) AS earned
+		FROM transactions tr
+		LEFT JOIN task_checklist_items ci ON tr.entity_type = 'checklist_item' AND ci.id = tr.entity_id
+		WHERE tr.workspace_id = g.workspace_id AND ((tr.entity_type = 'task' AND tr.entity_id = t.id) OR ci.task_id = t.id)
+	) fire ON true
+	WHERE g.id = ANY($1) AND g.workspace_id = $3 AND g.deleted_at IS NULL
+	GROUP BY g.id`
+
+// GoalProgress returns the progress of a goal as described for
+// goalProgressQuery. It returns ErrNotFound for a missing or deleted goal.
+func (r *Repo) GoalProgress(ctx context.Context, goalID, workspaceID string) (map[string]any, error) {
+	progress, err := r.goalProgress(ctx, workspaceID, []string{goalID}, truncateDate(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	if progress[goalID] == nil {
+		return nil, ErrNotFound
+	}
+	return progress[goalID], nil
+}
+
+// goalProgress computes the progress of several goals in one query, keyed
+// by goal id. Goals that are missing or deleted are left out.
+func (r *Repo) goalProgress(ctx context.Context, workspaceID string, goalIDs []string, today time.Time) (map[string]map[string]any, error) {
+	res := map[string]map[string]any{}
+	if len(goalIDs) == 0 {
+		return res, nil
+	}
+	rows, err := r.Pool.Query(ctx, goalProgressQuery, goalIDs, today, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var tasksTotal, tasksDone, scheduled, done int
+		var earned float64
+		if err := rows.Scan(&id, &tasksTotal, &tasksDone, &scheduled, &done, &earned); err != nil {
+			return nil, err
+		}
+		// percent combines one-off tasks and occurrences; nil without either.
+		var percent *float64
+		if total := tasksTotal + scheduled; total > 0 {
+			value := math.Round(float64(tasksDone+done)*1000/float64(total)) / 10
+			percent = &value
+		}
+		res[id] = map[string]any{
+			"tasks_total": tasksTotal, "tasks_done": tasksDone,
+			"occurrences_scheduled": scheduled, "occurrences_done": done,
+			"earned": earned, "percent": percent,
+		}
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// historyEntry collects what happened to one occurrence of a task, or to a
+// one-off task as a whole (date is empty).
+type historyEntry struct {
+	date         string
+	status       string
+	completedAt  *time.Time
+	skipReason   *string
+	skippedBy    *string
+	transactions []map[string]any
+	earned       float64
+	reversed     float64
+	penalty      float64
+	// completedBy is the user of the latest earn that was not reversed.
+	completedBy *string
+}
+
+// TaskHistory merges a task's occurrences, its completion and the ledger
+// transactions referring to it into one entry per occurrence date, newest
+// first; a one-off task has a single entry without a date. from and to limit
+// occurrences by date and undated activity by the day it happened.
+func (r *Repo) TaskHistory(ctx context.Context, taskID, workspaceID string, from, to *time.Time) ([]map[string]any, error) {
+	var status string
+	var doneAt *time.Time
+	var isRecurring bool
+	err := r.Pool.QueryRow(ctx, `SELECT status, done_at, is_recurring FROM tasks WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, taskID, workspaceID).Scan(&status, &doneAt, &isRecurring)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]*historyEntry{}
+	entry := func(date string) *historyEntry {
+		if entries[date] == nil {
+			entries[date] = &historyEntry{date: date}
+		}
+		return entries[date]
+	}
+	inRange := func(day time.Time) bool {
+		day = truncateDate(day)
+		return (from == nil || !day.Before(*from)) && (to == nil || !day.After(*to))
+	}
+
+	if !isRecurring && doneAt != nil && inRange(*doneAt) {
+		item := entry("")
+		item.status = status
+		item.completedAt = doneAt
+	}
+
+	rows, err := r.Pool.Query(ctx, `SELECT occurrence_date, status, completed_at, skip_reason, skipped_by FROM task_occurrences
+		WHERE task_id=$1 AND ($2::date IS NULL OR occurrence_date >= $2) AND ($3::date IS NULL OR occurrence_date <= $3)`, taskID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date time.Time
+		var occurrenceStatus string
+		var completedAt *time.Time
+		var skipReason, skippedBy *string
+		if err := rows.Scan(&date, &occurrenceStatus, &completedAt, &skipReason, &skippedBy); err != nil {
+			return nil, err
+		}
+		item := entry(date.Format("2006-01-02"))
+		item.status, item.completedAt, item.skipReason, item.skippedBy = occurrenceStatus, completedAt, skipReason, skippedBy
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	rows, err = r.Pool.Query(ctx, `SELECT t.id, t.user_id, t.type, t.amount, t.reason, t.occurrence_date, t.reverses_transaction_id, t.created_at,
+			EXISTS (SELECT 1 FROM transactions r WHERE r.reverses_transaction_id = t.id)
+		FROM transactions t
+		WHERE t.workspace_id=$1 AND t.entity_type='task' AND t.entity_id=$2
+		AND ($3::date IS NULL OR coalesce(t.occurrence_date, t.created_at::date) >= $3)
+		AND ($4::date IS NULL OR coalesce(t.occurrence_date, t.created_at::date) <= $4)
+		ORDER BY t.created_at, t.id`, workspaceID, taskID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, transactionType, reason string
+		var userID, reverses *string
+		var amount float64
+		var occurrenceDate *time.Time
+		var createdAt time.Time
+		var reversed bool
+		if err := rows.Scan(&id, &userID, &transactionType, &amount, &reason, &occurrenceDate, &reverses, &createdAt, &reversed); err != nil {
+			return nil, err
+		}
+		date := ""
+		if occurrenceDate != nil {
+			date = occurrenceDate.Format("2006-01-02")
+		}
+		item := entry(date)
+		switch transactionType {
+		case "earn":
+			item.earned += amount
+			if !reversed {
+				item.completedBy = userID
+			}
+		case "reversal":
+			item.reversed += amount
+		case "penalty":
+			item.penalty += amount
+		}
+		item.transactions = append(item.transactions, map[string]any{
+			"id": id, "type": transactionType, "amount": amount, "reason": reason, "user_id": userID,
+			"reverses_transaction_id": reverses, "reversed": reversed, "created_at": createdAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ordered := make([]*historyEntry, 0, len(entries))
+	for _, item := range entries {
+		ordered = append(ordered, item)
+	}
+	// Dates sort as strings; the undated entry goes last.
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].date == "" || ordered[j].date == "" {
+			return ordered[j].date == ""
+		}
+		return ordered[i].date > ordered[j].date
+	})
+	res := make([]map[string]any, 0, len(ordered))
+	for _, item := range ordered {
+		var date *string
+		if item.date != "" {
+			date = &item.date
+		}
+		if item.status == "" {
+			// Ledger rows without an occurrence row: a one-off task's state
+			// or an occurrence that was never recorded.
+			item.status = "pending"
+			if !isRecurring {
+				item.status, item.completedAt = status, doneAt
+			}
+		}
+		var completedBy *string
+		if item.status == "done" {
+			completedBy = item.completedBy
+		}
+		if item.transactions == nil {
+			item.transactions = []map[string]any{}
+		}
+		res = append(res, map[string]any{
+			"occurrence_date": date, "status": item.status, "completed_at": item.completedAt, "completed_by": completedBy,
+			"skip_reason": item.skipReason, "skipped_by": item.skippedBy,
+			"earned": item.earned, "reversed": item.reversed, "penalty": item.penalty, "net": item.earned - item.reversed - item.penalty,
+			"transactions": item.transactions,
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+// importSkipReason is recorded on occurrences excluded in an imported
+// calendar.
+const importSkipReason = "Excluded in imported calendar"
+
+// ImportTask is a task read from a calendar file. UID identifies the event or
+// todo it came from.
+type ImportTask struct {
+	UID          string
+	Title        string
+	Description  string
+	DueDate      *time.Time
+	IsRecurring  bool
+	Weekdays     []int
+	StartDate    *time.Time
+	EndDate      *time.Time
+	Timezone     *string
+	SkippedDates []time.Time
+}
+
+// ImportResult is the outcome for one ImportTask. TaskID is empty for
+// duplicates and in a dry run.
+type ImportResult struct {
+	TaskID string
+	// Duplicate is set when the UID was imported into the workspace before,
+	// or appears earlier in the same import.
+	Duplicate bool
+}
+
+// ImportTasks creates open tasks worth nothing, with skipped occurrences for
+// their SkippedDates, in one transaction. Tasks whose UID was already
+// imported are left out. With dryRun only duplicates are looked up.
+func (r *Repo) ImportTasks(ctx context.Context, workspaceID, userID string, tasks []ImportTask, dryRun bool) ([]ImportResult, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]ImportResult, len(tasks))
+	seen := map[string]bool{}
+	for i, task := range tasks {
+		if task.UID != "" {
+			if seen[task.UID] {
+				results[i].Duplicate = true
+				continue
+			}
+			seen[task.UID] = true
+			var imported bool
+			if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM ical_imports WHERE workspace_id=$1 AND uid=$2)`, workspaceID, task.UID).Scan(&imported); err != nil {
+				return nil, err
+			}
+			if imported {
+				results[i].Duplicate = true
+				continue
+			}
+		}
+		if dryRun {
+			continue
+		}
+		var id string
+		if err := tx.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, description, due_date, value, status, is_recurring, recurrence_weekdays, start_date, end_date, timezone)
+			VALUES ($1,$2,$3,$4,0,'open',$5,$6,$7,$8,$9) RETURNING id`, workspaceID, task.Title, task.Description, task.DueDate, task.IsRecurring, task.Weekdays, task.StartDate, task.EndDate, task.Timezone).Scan(&id); err != nil {
+			return nil, err
+		}
+		for _, date := range task.SkippedDates {
+			if _, err := tx.Exec(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, status, skip_reason, skipped_by) VALUES ($1,$2,'skipped',$3,$4)
+				ON CONFLICT (task_id, occurrence_date) DO NOTHING`, id, date, importSkipReason, userID); err != nil {
+				return nil, err
+			}
+		}
+		if task.UID != "" {
+			if _, err := tx.Exec(ctx, `INSERT INTO ical_imports (workspace_id, uid, task_id, imported_by) VALUES ($1,$2,$3,$4)`, workspaceID, task.UID, id, userID); err != nil {
+				return nil, err
+			}
+		}
+		results[i].TaskID = id
+	}
+	if dryRun {
+		return results, nil
+	}
+	return results, tx.Commit(ctx)
+}
@@ -0,0 +1,330 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listTaskInstancesLoop is the previous ListTaskInstances: it loads every
+// recurring task of the workspace and its occurrences, then walks the range
+// day by day in Go. It is kept to check and benchmark the set-based query
+// against.
+func (r *Repo) listTaskInstancesLoop(ctx context.Context, workspaceID string, from, to time.Time, filter TaskFilter) ([]map[string]any, error) {
+	conditions, args := filter.where([]any{workspaceID, from, to})
+	rows, err := r.Pool.Query(ctx, `SELECT id, goal_id, title, description, due_date, repeat_rule, value, status, done_at, is_recurring, recurrence_weekdays, start_date, end_date, timezone, assignee_only, require_checklist, priority, streak_bonus_percent, target, unit, max_payout_percent, progress, penalty, pay_per_hour, `+taskAssigneesColumn+`, `+taskTagsColumn+`, `+taskBlockedByColumn+`, `+taskBlocksColumn+`, `+taskAttachmentsColumn+`
+		FROM tasks
+		WHERE workspace_id=$1 AND deleted_at IS NULL
+		AND ((is_recurring = false AND due_date BETWEEN $2 AND $3) OR is_recurring = true)`+conditions, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type taskRow struct {
+		id                 string
+		goalID             *string
+		title              string
+		description        string
+		dueDate            *time.Time
+		repeatRule         *string
+		value              float64
+		status             string
+		doneAt             *time.Time
+		isRecurring        bool
+		recurrenceWeekdays []int
+		startDate          *time.Time
+		endDate            *time.Time
+		timezone           *string
+		assigneeOnly       bool
+		requireChecklist   bool
+		priority           int
+		streakBonusPercent float64
+		target             *float64
+		unit               *string
+		maxPayoutPercent   float64
+		progress           float64
+		penalty            float64
+		payPerHour         bool
+		assigneeIDs        []string
+		tagIDs             []string
+		blockedBy          []string
+		blocks             []string
+		attachmentIDs      []string
+	}
+
+	var tasks []taskRow
+	var recurringIDs []string
+	var recurring []streakTask
+	for rows.Next() {
+		var row taskRow
+		var recurrenceWeekdays []int16
+		if err := rows.Scan(&row.id, &row.goalID, &row.title, &row.description, &row.dueDate, &row.repeatRule, &row.value, &row.status, &row.doneAt, &row.isRecurring, &recurrenceWeekdays, &row.startDate, &row.endDate, &row.timezone, &row.assigneeOnly, &row.requireChecklist, &row.priority, &row.streakBonusPercent, &row.target, &row.unit, &row.maxPayoutPercent, &row.progress, &row.penalty, &row.payPerHour, &row.assigneeIDs, &row.tagIDs, &row.blockedBy, &row.blocks, &row.attachmentIDs); err != nil {
+			return nil, err
+		}
+		if recurrenceWeekdays != nil {
+			row.recurrenceWeekdays = make([]int, 0, len(recurrenceWeekdays))
+			for _, day := range recurrenceWeekdays {
+				row.recurrenceWeekdays = append(row.recurrenceWeekdays, int(day))
+			}
+		}
+		if row.isRecurring {
+			recurringIDs = append(recurringIDs, row.id)
+			recurring = append(recurring, streakTask{id: row.id, timezone: row.timezone})
+		}
+		tasks = append(tasks, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	streaks, err := r.loadStreaks(ctx, recurring, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	type occurrenceRow struct {
+		status     string
+		skipReason *string
+		progress   float64
+	}
+	occurrences := map[string]map[string]occurrenceRow{}
+	if len(recurringIDs) > 0 {
+		rows, err := r.Pool.Query(ctx, `SELECT task_id, occurrence_date, status, skip_reason, progress FROM task_occurrences
+			WHERE occurrence_date BETWEEN $1 AND $2 AND task_id = ANY($3)`, from, to, recurringIDs)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var taskID string
+			var occurrenceDate time.Time
+			var occurrence occurrenceRow
+			if err := rows.Scan(&taskID, &occurrenceDate, &occurrence.status, &occurrence.skipReason, &occurrence.progress); err != nil {
+				return nil, err
+			}
+			dateKey := occurrenceDate.Format("2006-01-02")
+			if occurrences[taskID] == nil {
+				occurrences[taskID] = map[string]occurrenceRow{}
+			}
+			occurrences[taskID][dateKey] = occurrence
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	vacations, err := r.vacationsBetween(ctx, workspaceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []map[string]any
+	fromDate := truncateDate(from)
+	toDate := truncateDate(to)
+	for _, task := range tasks {
+		if !task.isRecurring {
+			if task.dueDate == nil {
+				continue
+			}
+			res = append(res, map[string]any{
+				"id": task.id, "workspace_id": workspaceID, "goal_id": task.goalID, "title": task.title, "description": task.description, "due_date": task.dueDate, "repeat_rule": task.repeatRule, "value": task.value, "status": task.status, "done_at": task.doneAt, "is_recurring": task.isRecurring, "recurrence_weekdays": task.recurrenceWeekdays, "start_date": task.startDate, "end_date": task.endDate, "timezone": task.timezone, "assignee_only": task.assigneeOnly, "require_checklist": task.requireChecklist, "priority": task.priority, "streak_bonus_percent": task.streakBonusPercent, "target": task.target, "unit": task.unit, "max_payout_percent": task.maxPayoutPercent, "progress": task.progress, "penalty": task.penalty, "pay_per_hour": task.payPerHour, "current_streak": streaks[task.id].Current, "longest_streak": streaks[task.id].Longest, "assignee_ids": task.assigneeIDs, "tag_ids": task.tagIDs, "blocked_by": task.blockedBy, "blocks": task.blocks, "attachment_ids": task.attachmentIDs, "occurrence_date": task.dueDate.Format("2006-01-02"), "occurrence_status": occurrenceStatusOf(task.status), "skip_reason": nil, "done": task.status == "done", "missed": false,
+			})
+			continue
+		}
+		if len(task.recurrenceWeekdays) == 0 {
+			continue
+		}
+		start := fromDate
+		if task.startDate != nil && task.startDate.After(start) {
+			start = truncateDate(*task.startDate)
+		}
+		end := toDate
+		if task.endDate != nil && task.endDate.Before(end) {
+			end = truncateDate(*task.endDate)
+		}
+		for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+			if !containsWeekday(task.recurrenceWeekdays, int(date.Weekday())) {
+				continue
+			}
+			dateKey := date.Format("2006-01-02")
+			occurrence, ok := occurrences[task.id][dateKey]
+			if !ok {
+				occurrence.status = "pending"
+			}
+			// A workspace vacation excuses whatever is still pending.
+			if occurrence.status == "pending" {
+				if vacation := vacationOn(vacations, date); vacation != nil {
+					occurrence.status = "skipped"
+					occurrence.skipReason = &vacation.reason
+				}
+			}
+			res = append(res, map[string]any{
+				"id": task.id, "workspace_id": workspaceID, "goal_id": task.goalID, "title": task.title, "description": task.description, "due_date": task.dueDate, "repeat_rule": task.repeatRule, "value": task.value, "status": task.status, "done_at": task.doneAt, "is_recurring": task.isRecurring, "recurrence_weekdays": task.recurrenceWeekdays, "start_date": task.startDate, "end_date": task.endDate, "timezone": task.timezone, "assignee_only": task.assigneeOnly, "require_checklist": task.requireChecklist, "priority": task.priority, "streak_bonus_percent": task.streakBonusPercent, "target": task.target, "unit": task.unit, "max_payout_percent": task.maxPayoutPercent, "progress": occurrence.progress, "penalty": task.penalty, "pay_per_hour": task.payPerHour, "current_streak": streaks[task.id].Current, "longest_streak": streaks[task.id].Longest, "assignee_ids": task.assigneeIDs, "tag_ids": task.tagIDs, "blocked_by": task.blockedBy, "blocks": task.blocks, "attachment_ids": task.attachmentIDs, "occurrence_date": dateKey, "occurrence_status": occurrence.status, "skip_reason": occurrence.skipReason, "done": occurrence.status == "done", "missed": occurrence.status == "missed",
+			})
+		}
+	}
+	return res, nil
+}
+
+type vacation struct {
+	startDate time.Time
+	endDate   time.Time
+	reason    string
+}
+
+func (r *Repo) vacationsBetween(ctx context.Context, workspaceID string, from, to time.Time) ([]vacation, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT start_date, end_date, reason FROM workspace_vacations
+		WHERE workspace_id=$1 AND start_date <= $3 AND end_date >= $2 ORDER BY start_date`, workspaceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []vacation
+	for rows.Next() {
+		var item vacation
+		if err := rows.Scan(&item.startDate, &item.endDate, &item.reason); err != nil {
+			return nil, err
+		}
+		res = append(res, item)
+	}
+	return res, rows.Err()
+}
+
+func vacationOn(vacations []vacation, date time.Time) *vacation {
+	for i := range vacations {
+		if !date.Before(truncateDate(vacations[i].startDate)) && !date.After(truncateDate(vacations[i].endDate)) {
+			return &vacations[i]
+		}
+	}
+	return nil
+}
+
+// seedInstances fills a workspace with recurring tasks on various weekdays,
+// a year of occurrences in mixed states, one-off tasks and vacations. A second
+// workspace gets the same load so that queries must filter by workspace.
+func seedInstances(tb testing.TB, repo *Repo, tasks int) string {
+	tb.Helper()
+	ctx := context.Background()
+	var workspaceID string
+	for i, name := range []string{"Bench", "Noise"} {
+		var id string
+		if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ($1, 'personal') RETURNING id`, name).Scan(&id); err != nil {
+			tb.Fatalf("workspace: %v", err)
+		}
+		if i == 0 {
+			workspaceID = id
+		}
+		statements := []string{
+			`INSERT INTO tasks (workspace_id, title, value, status, is_recurring, recurrence_weekdays, start_date, end_date)
+				SELECT $1, 'Recurring ' || n, 5, 'open', true,
+					CASE n % 3 WHEN 0 THEN '{0,1,2,3,4,5,6}'::smallint[] WHEN 1 THEN '{1,3,5}'::smallint[] ELSE '{6}'::smallint[] END,
+					'2024-01-01'::date + (n % 60), CASE WHEN n % 5 = 0 THEN '2024-10-01'::date END
+				FROM generate_series(1, $2::int) n`,
+			`INSERT INTO tasks (workspace_id, title, value, status, due_date)
+				SELECT $1, 'Once ' || n, 3, CASE WHEN n % 2 = 0 THEN 'done' ELSE 'open' END, '2024-01-01'::date + (n % 366)
+				FROM generate_series(1, $2::int) n`,
+			`INSERT INTO task_occurrences (task_id, occurrence_date, status, skip_reason, progress)
+				SELECT tasks.id, day::date, CASE WHEN extract(day FROM day)::int % 4 = 0 THEN 'skipped' WHEN extract(day FROM day)::int % 7 = 0 THEN 'missed' ELSE 'done' END,
+					CASE WHEN extract(day FROM day)::int % 4 = 0 THEN 'busy' END, extract(day FROM day)::int
+				FROM tasks, generate_series('2024-01-01'::timestamp, '2024-12-31', interval '1 day') day
+				WHERE tasks.workspace_id = $1 AND tasks.is_recurring AND extract(dow FROM day)::smallint = ANY(tasks.recurrence_weekdays)
+					AND day::date >= tasks.start_date AND extract(day FROM day)::int % 3 <> 0`,
+			`INSERT INTO workspace_vacations (workspace_id, start_date, end_date, reason)
+				VALUES ($1, '2024-03-01', '2024-03-10', 'spring'), ($1, '2024-08-01', '2024-08-20', 'summer'), ($1, '2024-08-15', '2024-08-25', 'overlap')`,
+		}
+		for _, statement := range statements {
+			args := []any{id, tasks}
+			if strings.Count(statement, "$2") == 0 {
+				args = args[:1]
+			}
+			if _, err := repo.Pool.Exec(ctx, statement, args...); err != nil {
+				tb.Fatalf("seed: %v", err)
+			}
+		}
+	}
+	return workspaceID
+}
+
+// instanceKeys reduces instances to sortable "id date status reason progress"
+// strings so that implementations ordering them differently compare equal.
+func instanceKeys(instances []map[string]any) []string {
+	keys := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		reason := ""
+		if value, ok := instance["skip_reason"].(*string); ok && value != nil {
+			reason = *value
+		}
+		keys = append(keys, fmt.Sprintf("%v %v %v %s %v", instance["id"], instance["occurrence_date"], instance["occurrence_status"], reason, instance["progress"]))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestListTaskInstancesMatchesLoop(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	workspaceID := seedInstances(t, repo, 30)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	want, err := repo.listTaskInstancesLoop(ctx, workspaceID, from, to, TaskFilter{})
+	if err != nil {
+		t.Fatalf("loop: %v", err)
+	}
+	got, err := repo.ListTaskInstances(ctx, workspaceID, from, to, TaskFilter{})
+	if err != nil {
+		t.Fatalf("instances: %v", err)
+	}
+	wantKeys, gotKeys := instanceKeys(want), instanceKeys(got)
+	if len(gotKeys) != len(wantKeys) {
+		t.Fatalf("expected %d instances, got %d", len(wantKeys), len(gotKeys))
+	}
+	for i := range wantKeys {
+		if gotKeys[i] != wantKeys[i] {
+			t.Fatalf("instance %d: expected %q, got %q", i, wantKeys[i], gotKeys[i])
+		}
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i]["occurrence_date"].(string) < got[i-1]["occurrence_date"].(string) {
+			t.Fatalf("instances are not ordered by date at %d", i)
+		}
+	}
+}
+
+func BenchmarkListTaskInstances(b *testing.B) {
+	repo, cleanup := setupTestRepo(b)
+	defer cleanup()
+	ctx := context.Background()
+	workspaceID := seedInstances(b, repo, 500)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	if _, err := repo.Pool.Exec(ctx, `ANALYZE`); err != nil {
+		b.Fatalf("analyze: %v", err)
+	}
+
+	implementations := []struct {
+		name string
+		list func(context.Context, string, time.Time, time.Time, TaskFilter) ([]map[string]any, error)
+	}{
+		{"loop", repo.listTaskInstancesLoop},
+		{"set", repo.ListTaskInstances},
+	}
+	for _, implementation := range implementations {
+		for _, span := range []struct {
+			name string
+			to   time.Time
+		}{{"week", from.AddDate(0, 0, 6)}, {"year", to}} {
+			b.Run(implementation.name+"/"+span.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := implementation.list(ctx, workspaceID, from, span.to, TaskFilter{}); err != nil {
+						b.Fatalf("list: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// purgeTable is a table whose soft-deleted rows PurgeDeleted removes.
+type purgeTable struct {
+	name string
+	// entityType is how comments and attachments refer to the table's rows;
+	// they go with the purged row.
+	entityType string
+	// keep is a condition on row t that spares it: rows other tables still
+	// refer to as history stay soft-deleted rather than take it with them.
+	keep string
+	// unlink is a statement run with the purged ids as purged, clearing
+	// references that would otherwise point at the gone rows.
+	unlink string
+}
+
+// purgeTables lists the tables PurgeDeleted handles, children before parents.
+// Tasks keep their occurrences, time entries and reschedules and rewards
+// their purchases, so such rows are never purged. Uploads are not listed:
+// they are not soft-deleted, and their blobs live in the storage backend and
+// go with DELETE /uploads/{id}; purging only drops the attachment links.
+var purgeTables = []purgeTable{
+	{name: "comments"},
+	{name: "task_checklist_items"},
+	{name: "tasks", entityType: "task", keep: `EXISTS (SELECT 1 FROM task_occurrences o WHERE o.task_id = t.id)
+		OR EXISTS (SELECT 1 FROM time_entries e WHERE e.task_id = t.id)
+		OR EXISTS (SELECT 1 FROM task_reschedules rs WHERE rs.task_id = t.id)`},
+	{name: "goals", entityType: "goal", unlink: `UPDATE tasks SET detached_goal_id=NULL WHERE detached_goal_id IN (SELECT id FROM purged)`},
+	{name: "tags"},
+	{name: "rewards", entityType: "reward", keep: `EXISTS (SELECT 1 FROM reward_purchases p WHERE p.reward_id = t.id)`},
+	{name: "achievements", entityType: "achievement"},
+}
+
+// purgeQuery deletes the table's rows soft-deleted before $1, with their
+// comments and attachments, and returns how many rows went.
+func (p purgeTable) purgeQuery() string {
+	where := `t.deleted_at IS NOT NULL AND t.deleted_at < $1`
+	if p.keep != "" {
+		where += ` AND NOT (` + p.keep + `)`
+	}
+	query := `WITH purged AS (DELETE FROM ` + p.name + ` t WHERE ` + where + ` RETURNING t.id)`
+	if p.entityType != "" {
+		query += `,
+		purged_comments AS (DELETE FROM comments WHERE entity_type = '` + p.entityType + `' AND entity_id IN (SELECT id FROM purged)),
+		purged_attachments AS (DELETE FROM attachments WHERE entity_type = '` + p.entityType + `' AND entity_id IN (SELECT id FROM purged))`
+	}
+	if p.unlink != "" {
+		query += `,
+		unlinked AS (` + p.unlink + `)`
+	}
+	return query + `
+		SELECT count(*) FROM purged`
+}
+
+// ClaimJobRun records the start of a job's run for the scheduled slot. It
+// returns ok=false when another instance has already claimed the slot.
+func (r *Repo) ClaimJobRun(ctx context.Context, job string, scheduledAt time.Time) (string, bool, error) {
+	var id string
+	err := r.Pool.QueryRow(ctx, `INSERT INTO job_runs (job, scheduled_at) VALUES ($1, $2)
+		ON CONFLICT (job, scheduled_at) DO NOTHING RETURNING id`, job, scheduledAt).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return id, true, nil
+}
+
+// FinishJobRun stores the outcome of a claimed run.
+func (r *Repo) FinishJobRun(ctx context.Context, id string, runErr error, detail string) error {
+	status := "succeeded"
+	if runErr != nil {
+		status = "failed"
+		detail = runErr.Error()
+	}
+	_, err := r.Pool.Exec(ctx, `UPDATE job_runs SET status=$1, detail=$2, finished_at=now() WHERE id=$3`, status, detail, id)
+	return err
+}
+
+// LastJobSuccess returns the scheduled time of the job's latest successful
+// run, or nil when none is on record.
+func (r *Repo) LastJobSuccess(ctx context.Context, job string) (*time.Time, error) {
+	var scheduledAt *time.Time
+	err := r.Pool.QueryRow(ctx, `SELECT max(scheduled_at) FROM job_runs WHERE job=$1 AND status='succeeded'`, job).Scan(&scheduledAt)
+	return scheduledAt, err
+}
+
+// DeleteExpiredSessions removes refresh sessions that expired before now.
+func (r *Repo) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	cmd, err := r.Pool.Exec(ctx, `DELETE FROM sessions WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
+
+// DeleteExpiredInvites removes workspace invites that expired before now.
+func (r *Repo) DeleteExpiredInvites(ctx context.Context, now time.Time) (int64, error) {
+	cmd, err := r.Pool.Exec(ctx, `DELETE FROM workspace_invites WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
+
+// PurgeDeleted permanently removes rows soft-deleted before the cutoff, except
+// those kept as history (see purgeTables), along with job history and
+// delivered or failed notifications older than it. It returns the removed
+// row count per table.
+func (r *Repo) PurgeDeleted(ctx context.Context, before time.Time) (map[string]int64, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	purged := map[string]int64{}
+	for _, table := range purgeTables {
+		var count int64
+		if err := tx.QueryRow(ctx, table.purgeQuery(), before).Scan(&count); err != nil {
+			return nil, fmt.Errorf("purge %s: %w", table.name, err)
+		}
+		purged[table.name] = count
+	}
+	cmd, err := tx.Exec(ctx, `DELETE FROM job_runs WHERE started_at < $1`, before)
+	if err != nil {
+		return nil, fmt.Errorf("purge job_runs: %w", err)
+	}
+	purged["job_runs"] = cmd.RowsAffected()
+	cmd, err = tx.Exec(ctx, `DELETE FROM notification_outbox WHERE status <> 'pending' AND created_at < $1`, before)
+	if err != nil {
+		return nil, fmt.Errorf("purge notification_outbox: %w", err)
+	}
+	purged["notification_outbox"] = cmd.RowsAffected()
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
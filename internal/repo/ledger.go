@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// creditEarn records an 'earn' transaction for an entity and adds amount to the
+// workspace balance. It must run inside the caller's transaction.
+func creditEarn(ctx context.Context, tx pgx.Tx, workspaceID, userID string, amount float64, reason, entityType, entityID string, occurrenceDate *time.Time) error {
+	if _, err := tx.Exec(ctx, `INSERT INTO transactions (workspace_id, user_id, type, amount, reason, entity_type, entity_id, occurrence_date)
+		VALUES ($1,$2,'earn',$3,$4,$5,$6,$7)`, workspaceID, userID, amount, reason, entityType, entityID, occurrenceDate); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `UPDATE workspace_balance SET balance = balance + $1, updated_at=now() WHERE workspace_id=$2`, amount, workspaceID)
+	return err
+}
+
+// reverseEarns writes a 'reversal' for every earn of the entity (and occurrence)
+// that has not been reversed yet and debits their sum from the balance. When no
+// such earn exists (rows written before transactions referenced occurrences) the
+// fallback amount is reversed without a reference. Returns ErrBalanceSpent if
+// the balance would go negative and force is false.
+func reverseEarns(ctx context.Context, tx pgx.Tx, workspaceID, userID, entityType, entityID string, occurrenceDate *time.Time, fallback float64, reason string, force bool) (float64, error) {
+	rows, err := tx.Query(ctx, `SELECT id, amount FROM transactions t
+		WHERE t.workspace_id=$1 AND t.entity_type=$2 AND t.entity_id=$3 AND t.type='earn' AND t.occurrence_date IS NOT DISTINCT FROM $4
+		AND NOT EXISTS (SELECT 1 FROM transactions r WHERE r.reverses_transaction_id = t.id)
+		ORDER BY t.created_at`, workspaceID, entityType, entityID, occurrenceDate)
+	if err != nil {
+		return 0, err
+	}
+	type earn struct {
+		id     *string
+		amount float64
+	}
+	var earns []earn
+	for rows.Next() {
+		var item earn
+		if err := rows.Scan(&item.id, &item.amount); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		earns = append(earns, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(earns) == 0 {
+		earns = append(earns, earn{amount: fallback})
+	}
+
+	var total float64
+	for _, item := range earns {
+		total += item.amount
+	}
+	var balance float64
+	if err := tx.QueryRow(ctx, `UPDATE workspace_balance SET balance = balance - $1, updated_at=now()
+		WHERE workspace_id=$2 AND (balance >= $1 OR $3) RETURNING balance`, total, workspaceID, force).Scan(&balance); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrBalanceSpent
+		}
+		return 0, err
+	}
+	for _, item := range earns {
+		if _, err := tx.Exec(ctx, `INSERT INTO transactions (workspace_id, user_id, type, amount, reason, entity_type, entity_id, occurrence_date, reverses_transaction_id)
+			VALUES ($1,$2,'reversal',$3,$4,$5,$6,$7,$8)`, workspaceID, userID, item.amount, reason, entityType, entityID, occurrenceDate, item.id); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,23 @@
+package repo
+
+import "context"
+
+// WithAdvisoryLock runs fn while holding the session-level Postgres advisory
+// lock named by name. When another session holds the lock it returns false
+// without running fn, so only one server instance does the work.
+func (r *Repo) WithAdvisoryLock(ctx context.Context, name string, fn func(context.Context) error) (bool, error) {
+	conn, err := r.Pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, name).Scan(&locked); err != nil {
+		return false, err
+	}
+	if !locked {
+		return false, nil
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, name)
+	return true, fn(ctx)
+}
@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Notification is a pending outbox row together with what a channel needs to
+// deliver it to its recipient.
+type Notification struct {
+	ID       string
+	UserID   string
+	Channel  string
+	Kind     string
+	Payload  map[string]any
+	Attempts int
+
+	Email      string
+	WebhookURL *string
+	Timezone   *string
+	QuietStart *string
+	QuietEnd   *string
+}
+
+// NotificationSettings are a user's delivery preferences. Quiet hours are
+// "HH:MM" strings local to Timezone.
+type NotificationSettings struct {
+	Timezone     *string
+	QuietStart   *string
+	QuietEnd     *string
+	EmailEnabled bool
+	WebhookURL   *string
+}
+
+func (r *Repo) GetNotificationSettings(ctx context.Context, userID string) (map[string]any, error) {
+	var settings NotificationSettings
+	var updatedAt time.Time
+	err := r.Pool.QueryRow(ctx, `SELECT timezone, to_char(quiet_hours_start, 'HH24:MI'), to_char(quiet_hours_end, 'HH24:MI'), email_enabled, webhook_url, updated_at
+		FROM notification_settings WHERE user_id=$1`, userID).Scan(&settings.Timezone, &settings.QuietStart, &settings.QuietEnd, &settings.EmailEnabled, &settings.WebhookURL, &updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return map[string]any{
+			"user_id": userID, "timezone": nil, "quiet_hours_start": nil, "quiet_hours_end": nil, "email_enabled": false, "webhook_url": nil, "updated_at": nil,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"user_id": userID, "timezone": settings.Timezone, "quiet_hours_start": settings.QuietStart, "quiet_hours_end": settings.QuietEnd, "email_enabled": settings.EmailEnabled, "webhook_url": settings.WebhookURL, "updated_at": updatedAt,
+	}, nil
+}
+
+func (r *Repo) UpsertNotificationSettings(ctx context.Context, userID string, settings NotificationSettings) error {
+	_, err := r.Pool.Exec(ctx, `INSERT INTO notification_settings (user_id, timezone, quiet_hours_start, quiet_hours_end, email_enabled, webhook_url, updated_at)
+		VALUES ($1, $2, $3::time, $4::time, $5, $6, now())
+		ON CONFLICT (user_id) DO UPDATE SET timezone=EXCLUDED.timezone, quiet_hours_start=EXCLUDED.quiet_hours_start, quiet_hours_end=EXCLUDED.quiet_hours_end,
+		email_enabled=EXCLUDED.email_enabled, webhook_url=EXCLUDED.webhook_url, updated_at=now()`,
+		userID, settings.Timezone, settings.QuietStart, settings.QuietEnd, settings.EmailEnabled, settings.WebhookURL)
+	return err
+}
+
+// EnqueueNotification adds a notification for the user on every channel the
+// user has enabled. dedupeKey makes enqueueing the same event twice a no-op.
+func (r *Repo) EnqueueNotification(ctx context.Context, userID, kind, dedupeKey string, payload map[string]any) (int, error) {
+	cmd, err := r.Pool.Exec(ctx, `INSERT INTO notification_outbox (user_id, channel, kind, dedupe_key, payload)
+		SELECT $1, channel, $2, $3, $4 FROM (`+userChannelsQuery("$1")+`) channels
+		ON CONFLICT (user_id, channel, dedupe_key) DO NOTHING`, userID, kind, dedupeKey, payload)
+	if err != nil {
+		return 0, err
+	}
+	return int(cmd.RowsAffected()), nil
+}
+
+// enqueueWorkspaceEvent notifies every member of the workspace except the actor
+// about an event, inside the transaction that caused it.
+func enqueueWorkspaceEvent(ctx context.Context, tx pgx.Tx, workspaceID, actorID, kind, dedupeKey string, payload map[string]any) error {
+	_, err := tx.Exec(ctx, `INSERT INTO notification_outbox (user_id, channel, kind, dedupe_key, payload)
+		SELECT m.user_id, channels.channel, $3, $4, $5 FROM workspace_members m
+		CROSS JOIN LATERAL (`+userChannelsQuery("m.user_id")+`) channels
+		WHERE m.workspace_id=$1 AND m.user_id <> $2
+		ON CONFLICT (user_id, channel, dedupe_key) DO NOTHING`, workspaceID, actorID, kind, dedupeKey, payload)
+	return err
+}
+
+// userChannelsQuery lists the delivery channels enabled for the user given by
+// the SQL expression user.
+func userChannelsQuery(user string) string {
+	return `SELECT 'email' AS channel FROM notification_settings WHERE user_id=` + user + ` AND email_enabled
+	UNION ALL SELECT 'webhook' FROM notification_settings WHERE user_id=` + user + ` AND webhook_url IS NOT NULL AND webhook_url <> ''
+	UNION ALL SELECT 'webpush' WHERE EXISTS(SELECT 1 FROM push_subscriptions WHERE user_id=` + user + `)`
+}
+
+// ListDueNotifications returns up to limit pending notifications whose next
+// attempt is due, oldest first.
+func (r *Repo) ListDueNotifications(ctx context.Context, now time.Time, limit int) ([]Notification, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT o.id, o.user_id, o.channel, o.kind, o.payload, o.attempts, u.email,
+		s.webhook_url, s.timezone, to_char(s.quiet_hours_start, 'HH24:MI'), to_char(s.quiet_hours_end, 'HH24:MI')
+		FROM notification_outbox o
+		JOIN users u ON u.id = o.user_id
+		LEFT JOIN notification_settings s ON s.user_id = o.user_id
+		WHERE o.status='pending' AND o.next_attempt_at <= $1
+		ORDER BY o.next_attempt_at LIMIT $2`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []Notification
+	for rows.Next() {
+		var item Notification
+		if err := rows.Scan(&item.ID, &item.UserID, &item.Channel, &item.Kind, &item.Payload, &item.Attempts, &item.Email,
+			&item.WebhookURL, &item.Timezone, &item.QuietStart, &item.QuietEnd); err != nil {
+			return nil, err
+		}
+		res = append(res, item)
+	}
+	return res, rows.Err()
+}
+
+func (r *Repo) MarkNotificationSent(ctx context.Context, id string) error {
+	_, err := r.Pool.Exec(ctx, `UPDATE notification_outbox SET status='sent', attempts=attempts+1, sent_at=now(), last_error=NULL WHERE id=$1`, id)
+	return err
+}
+
+// RetryNotification records a failed attempt. A nil retryAt gives up and marks
+// the notification failed.
+func (r *Repo) RetryNotification(ctx context.Context, id string, retryAt *time.Time, lastError string) error {
+	_, err := r.Pool.Exec(ctx, `UPDATE notification_outbox SET attempts=attempts+1, last_error=$2,
+		status=CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+		next_attempt_at=COALESCE($3, next_attempt_at)
+		WHERE id=$1`, id, lastError, retryAt)
+	return err
+}
+
+// DeferNotification postpones delivery without counting an attempt, e.g. until
+// the recipient's quiet hours end.
+func (r *Repo) DeferNotification(ctx context.Context, id string, until time.Time) error {
+	_, err := r.Pool.Exec(ctx, `UPDATE notification_outbox SET next_attempt_at=$2 WHERE id=$1`, id, until)
+	return err
+}
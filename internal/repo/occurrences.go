@@ -0,0 +1,135 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SkipOccurrence excuses one scheduled occurrence of a recurring task: it is
+// neither charged as missed nor breaks the streak. Only pending occurrences can
+// be skipped; skipping again updates the reason.
+func (r *Repo) SkipOccurrence(ctx context.Context, taskID, workspaceID, userID string, occurrenceDate time.Time, reason string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := scheduledOccurrence(ctx, tx, taskID, workspaceID, userID, occurrenceDate); err != nil {
+		return err
+	}
+	var status string
+	err = tx.QueryRow(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, status, skip_reason, skipped_by)
+		VALUES ($1,$2,'skipped',$3,$4)
+		ON CONFLICT (task_id, occurrence_date) DO UPDATE SET status='skipped', skip_reason=EXCLUDED.skip_reason, skipped_by=EXCLUDED.skipped_by
+		WHERE task_occurrences.status IN ('pending', 'skipped')
+		RETURNING status`, taskID, occurrenceDate, reason, userID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrOccurrenceClosed
+	}
+	if err != nil {
+		return err
+	}
+	if err := bumpTask(ctx, tx, taskID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UnskipOccurrence puts a skipped occurrence back to pending.
+func (r *Repo) UnskipOccurrence(ctx context.Context, taskID, workspaceID, userID string, occurrenceDate time.Time) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := scheduledOccurrence(ctx, tx, taskID, workspaceID, userID, occurrenceDate); err != nil {
+		return err
+	}
+	cmd, err := tx.Exec(ctx, `UPDATE task_occurrences SET status='pending', skip_reason=NULL, skipped_by=NULL
+		WHERE task_id=$1 AND occurrence_date=$2 AND status='skipped'`, taskID, occurrenceDate)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if err := bumpTask(ctx, tx, taskID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// scheduledOccurrence checks that date is a scheduled day of the recurring
+// task and enforces assignee-only tasks.
+func scheduledOccurrence(ctx context.Context, tx pgx.Tx, taskID, workspaceID, userID string, date time.Time) error {
+	var isRecurring, assigneeOnly bool
+	var weekdays []int16
+	var startDate, endDate *time.Time
+	err := tx.QueryRow(ctx, `SELECT is_recurring, assignee_only, recurrence_weekdays, start_date, end_date
+		FROM tasks WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, taskID, workspaceID).Scan(&isRecurring, &assigneeOnly, &weekdays, &startDate, &endDate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	date = truncateDate(date)
+	if !isRecurring || !containsWeekday(int16sToInts(weekdays), int(date.Weekday())) ||
+		(startDate != nil && date.Before(truncateDate(*startDate))) || (endDate != nil && date.After(truncateDate(*endDate))) {
+		return ErrNotOccurrence
+	}
+	if assigneeOnly {
+		return requireAssignee(ctx, tx, taskID, workspaceID, userID)
+	}
+	return nil
+}
+
+// bumpTask marks the task changed so that its instances sync.
+func bumpTask(ctx context.Context, tx pgx.Tx, taskID string) error {
+	_, err := tx.Exec(ctx, `UPDATE tasks SET updated_at=now(), version=version+1 WHERE id=$1`, taskID)
+	return err
+}
+
+func (r *Repo) ListVacations(ctx context.Context, workspaceID string) ([]map[string]any, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT id, start_date, end_date, reason, created_by, created_at
+		FROM workspace_vacations WHERE workspace_id=$1 ORDER BY start_date, created_at`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []map[string]any
+	for rows.Next() {
+		var id, reason string
+		var createdBy *string
+		var startDate, endDate, createdAt time.Time
+		if err := rows.Scan(&id, &startDate, &endDate, &reason, &createdBy, &createdAt); err != nil {
+			return nil, err
+		}
+		res = append(res, map[string]any{
+			"id": id, "workspace_id": workspaceID, "start_date": startDate.Format("2006-01-02"), "end_date": endDate.Format("2006-01-02"),
+			"reason": reason, "created_by": createdBy, "created_at": createdAt,
+		})
+	}
+	return res, rows.Err()
+}
+
+func (r *Repo) CreateVacation(ctx context.Context, workspaceID, userID string, startDate, endDate time.Time, reason string) (string, error) {
+	var id string
+	err := r.Pool.QueryRow(ctx, `INSERT INTO workspace_vacations (workspace_id, start_date, end_date, reason, created_by)
+		VALUES ($1,$2,$3,$4,$5) RETURNING id`, workspaceID, startDate, endDate, reason, userID).Scan(&id)
+	return id, err
+}
+
+func (r *Repo) DeleteVacation(ctx context.Context, id, workspaceID string) error {
+	cmd, err := r.Pool.Exec(ctx, `DELETE FROM workspace_vacations WHERE id=$1 AND workspace_id=$2`, id, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
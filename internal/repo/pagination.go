@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// DefaultPageLimit applies when a PageRequest has no limit.
+	DefaultPageLimit = 50
+	// MaxPageLimit caps the rows returned in one page.
+	MaxPageLimit = 200
+)
+
+// PageRequest asks for one page of a listing. Sort names one of the
+// listing's allow-listed fields, prefixed with "-" for descending order, and
+// Cursor is the NextCursor of the previous page.
+type PageRequest struct {
+	Limit  int
+	Sort   string
+	Cursor string
+}
+
+// Page is one page of a listing. NextCursor is nil on the last page; Total
+// counts every row the listing matches, not just this page.
+type Page struct {
+	Items      []map[string]any `json:"items"`
+	NextCursor *string          `json:"next_cursor"`
+	Total      int              `json:"total"`
+}
+
+// sortField is an allow-listed sort key: a SQL expression that is never NULL
+// and the type its cursor value is cast back to.
+type sortField struct {
+	expr string
+	typ  string
+}
+
+// listing describes a keyset-paginated query over one table.
+type listing struct {
+	table   string
+	columns string
+	// where holds the base conditions; args are their parameters.
+	where       string
+	args        []any
+	sorts       map[string]sortField
+	defaultSort string
+}
+
+// pageCursor is the position after the last row of a page: its sort value
+// and id, for the sort it was produced with.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || json.Unmarshal(data, &c) != nil || c.ID == "" {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// paginate runs a listing and returns the requested page. Rows are ordered by
+// the sort expression with the id as tie-breaker, so the keyset
+// (expression, id) is unique and pages neither skip nor repeat rows. scan
+// reads one row: the listing's columns followed by the sort value as text.
+func (r *Repo) paginate(ctx context.Context, l listing, page PageRequest, scan func(rows pgx.Rows, sortValue *string) (map[string]any, error)) (Page, error) {
+	sort := page.Sort
+	if sort == "" {
+		sort = l.defaultSort
+	}
+	field, ok := l.sorts[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return Page{}, ErrInvalidSort
+	}
+	direction, comparison := "ASC", ">"
+	if strings.HasPrefix(sort, "-") {
+		direction, comparison = "DESC", "<"
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	var total int
+	if err := r.Pool.QueryRow(ctx, `SELECT count(*) FROM `+l.table+` WHERE `+l.where, l.args...).Scan(&total); err != nil {
+		return Page{}, err
+	}
+
+	where := l.where
+	args := append([]any{}, l.args...)
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		if c.Sort != sort {
+			return Page{}, ErrInvalidCursor
+		}
+		args = append(args, c.Value, c.ID)
+		where += fmt.Sprintf(" AND (%s, %s.id) %s ($%d::%s, $%d::uuid)", field.expr, l.table, comparison, len(args)-1, field.typ, len(args))
+	}
+	args = append(args, limit+1)
+	rows, err := r.Pool.Query(ctx, fmt.Sprintf(`SELECT %s, (%s)::text FROM %s WHERE %s ORDER BY %s %s, %s.id %s LIMIT $%d`,
+		l.columns, field.expr, l.table, where, field.expr, direction, l.table, direction, len(args)), args...)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+	res := Page{Items: []map[string]any{}, Total: total}
+	var lastValue string
+	for rows.Next() {
+		if len(res.Items) == limit {
+			// The extra row only tells that another page exists.
+			last := res.Items[len(res.Items)-1]
+			next := encodeCursor(pageCursor{Sort: sort, Value: lastValue, ID: last["id"].(string)})
+			res.NextCursor = &next
+			break
+		}
+		var sortValue string
+		item, err := scan(rows, &sortValue)
+		if err != nil {
+			return Page{}, err
+		}
+		lastValue = sortValue
+		res.Items = append(res.Items, item)
+	}
+	return res, rows.Err()
+}
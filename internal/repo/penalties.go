@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// penaltyTask is a recurring task with a penalty, as seen by the penalty job.
+type penaltyTask struct {
+	id           string
+	workspaceID  string
+	weekdays     []int
+	startDate    *time.Time
+	endDate      *time.Time
+	timezone     *string
+	penalty      float64
+	penaltySince time.Time
+}
+
+// ProcessMissedOccurrences marks occurrences of penalised recurring tasks whose
+// day has ended (in the task's timezone) without completion as missed and writes
+// one 'penalty' transaction per occurrence. Safe to run repeatedly and from
+// several instances: an occurrence is charged at most once. lastRun is the time
+// of the last successful run, nil if unknown; days it already checked are not
+// scanned again, while days missed during downtime still are. Returns the
+// number of penalties written.
+func (r *Repo) ProcessMissedOccurrences(ctx context.Context, now time.Time, lastRun *time.Time) (int, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT id, workspace_id, recurrence_weekdays, start_date, end_date, timezone, penalty, penalty_since
+		FROM tasks WHERE is_recurring AND penalty > 0 AND penalty_since IS NOT NULL AND deleted_at IS NULL`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var tasks []penaltyTask
+	for rows.Next() {
+		var task penaltyTask
+		var weekdays []int16
+		if err := rows.Scan(&task.id, &task.workspaceID, &weekdays, &task.startDate, &task.endDate, &task.timezone, &task.penalty, &task.penaltySince); err != nil {
+			return 0, err
+		}
+		task.weekdays = int16sToInts(weekdays)
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	charged := 0
+	for _, task := range tasks {
+		today := taskToday(task.timezone, now)
+		since := taskToday(task.timezone, task.penaltySince)
+		if lastRun != nil {
+			// The last run checked every day before its own; the day before that
+			// is checked again in case the task's timezone has since moved.
+			if checked := taskToday(task.timezone, *lastRun).AddDate(0, 0, -1); checked.After(since) {
+				since = checked
+			}
+		}
+		dates := missedCandidates(task.weekdays, task.startDate, task.endDate, since, today)
+		if len(dates) == 0 {
+			continue
+		}
+		count, err := r.chargeMissed(ctx, task, dates)
+		if err != nil {
+			return charged, err
+		}
+		charged += count
+	}
+	return charged, nil
+}
+
+// chargeMissed marks the task's pending candidate dates outside workspace
+// vacations as missed and debits the penalty for each, in one transaction.
+func (r *Repo) chargeMissed(ctx context.Context, task penaltyTask, dates []time.Time) (int, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	charged := 0
+	for _, date := range dates {
+		var status string
+		err := tx.QueryRow(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, status)
+			SELECT $1, $2, 'missed'
+			WHERE NOT EXISTS (SELECT 1 FROM workspace_vacations WHERE workspace_id=$3 AND $2::date BETWEEN start_date AND end_date)
+			ON CONFLICT (task_id, occurrence_date) DO UPDATE SET status='missed'
+			WHERE task_occurrences.status = 'pending'
+			RETURNING status`, task.id, date, task.workspaceID).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Completed in time, skipped, on vacation, or marked by an earlier run.
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		// The unique index on penalty transactions makes a second charge for the
+		// same occurrence a no-op, e.g. after a late completion is undone.
+		cmd, err := tx.Exec(ctx, `INSERT INTO transactions (workspace_id, user_id, type, amount, reason, entity_type, entity_id, occurrence_date)
+			VALUES ($1,NULL,'penalty',$2,'missed occurrence','task',$3,$4)
+			ON CONFLICT (entity_id, occurrence_date) WHERE type = 'penalty' DO NOTHING`, task.workspaceID, task.penalty, task.id, date)
+		if err != nil {
+			return 0, err
+		}
+		if cmd.RowsAffected() == 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `UPDATE workspace_balance SET balance = balance - $1, updated_at=now() WHERE workspace_id=$2`, task.penalty, task.workspaceID); err != nil {
+			return 0, err
+		}
+		charged++
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return charged, nil
+}
+
+// missedCandidates lists the scheduled dates before today that a penalty run
+// should check: not before since or the task's start and not after the task
+// ends.
+func missedCandidates(weekdays []int, startDate, endDate *time.Time, since, today time.Time) []time.Time {
+	from := since
+	if startDate != nil && truncateDate(*startDate).After(from) {
+		from = truncateDate(*startDate)
+	}
+	to := today.AddDate(0, 0, -1)
+	if endDate != nil && truncateDate(*endDate).Before(to) {
+		to = truncateDate(*endDate)
+	}
+	var dates []time.Time
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		if containsWeekday(weekdays, int(date.Weekday())) {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// progressPayout returns the part of value earned by progress towards target,
+// capped at maxPercent of value and rounded to cents.
+func progressPayout(value, target, progress, maxPercent float64) float64 {
+	if target <= 0 || progress <= 0 {
+		return 0
+	}
+	ratio := math.Min(progress/target, maxPercent/100)
+	return math.Round(value*ratio*100) / 100
+}
+
+// LogTaskProgress adds amount to a measurable task's progress (per occurrence for
+// recurring tasks) and credits the payout accrued since the last update as an
+// 'earn'. Reaching the target completes the task or occurrence like
+// CompleteTask does; while its checklist is open or a blocker is not done the
+// progress is kept and the task stays open. It returns the new progress, the
+// amount credited and whether the task is now done.
+func (r *Repo) LogTaskProgress(ctx context.Context, id, workspaceID, userID string, occurrenceDate *time.Time, amount float64) (float64, float64, bool, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	task, err := lockCompletionTask(ctx, tx, id, workspaceID)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if task.target == nil {
+		return 0, 0, false, ErrNotMeasurable
+	}
+	if task.assigneeOnly {
+		if err := requireAssignee(ctx, tx, id, workspaceID, userID); err != nil {
+			return 0, 0, false, err
+		}
+	}
+
+	var progress float64
+	paid := task.progressPaid
+	done := task.status == "done"
+	if task.isRecurring {
+		if occurrenceDate == nil {
+			return 0, 0, false, ErrOccurrenceDate
+		}
+		err = tx.QueryRow(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, progress)
+			VALUES ($1,$2,$3)
+			ON CONFLICT (task_id, occurrence_date) DO UPDATE SET progress = task_occurrences.progress + EXCLUDED.progress
+			RETURNING progress, paid, status = 'done'`, id, *occurrenceDate, amount).Scan(&progress, &paid, &done)
+	} else {
+		occurrenceDate = nil
+		err = tx.QueryRow(ctx, `UPDATE tasks SET progress = progress + $1, updated_at=now(), version=version+1
+			WHERE id=$2 RETURNING progress`, amount, id).Scan(&progress)
+	}
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	earned := 0.0
+	if payout := progressPayout(task.value, *task.target, progress, task.maxPayoutPercent); payout > paid {
+		earned = math.Round((payout-paid)*100) / 100
+		if err := creditEarn(ctx, tx, workspaceID, userID, earned, "task progress", "task", id, occurrenceDate); err != nil {
+			return 0, 0, false, err
+		}
+		if task.isRecurring {
+			_, err = tx.Exec(ctx, `UPDATE task_occurrences SET paid=$1 WHERE task_id=$2 AND occurrence_date=$3`, payout, id, *occurrenceDate)
+		} else {
+			_, err = tx.Exec(ctx, `UPDATE tasks SET progress_paid=$1 WHERE id=$2`, payout, id)
+		}
+		if err != nil {
+			return 0, 0, false, err
+		}
+		task.progressPaid = payout
+	}
+
+	if !done && progress >= *task.target {
+		bonus, completed, err := completeLockedTask(ctx, tx, task, userID, occurrenceDate, false)
+		switch {
+		case errors.Is(err, ErrChecklistOpen) || errors.Is(err, ErrBlocked):
+			// The gates hold the completion back, not the progress.
+		case err != nil:
+			return 0, 0, false, err
+		default:
+			earned += bonus
+			done = completed
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, false, err
+	}
+	return progress, earned, done, nil
+}
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PushSubscription is a browser Web Push subscription with its base64url client
+// keys.
+type PushSubscription struct {
+	ID       string
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// SavePushSubscription stores a subscription for the user. The user's own
+// known endpoint gets the new keys. Another user's endpoint is only moved over
+// when the request carries the same client keys, which proves it comes from
+// the browser holding the subscription (as when someone else signs in on the
+// same device); otherwise ErrSubscriptionTaken is returned.
+func (r *Repo) SavePushSubscription(ctx context.Context, userID, endpoint, p256dh, auth string, userAgent *string) (string, error) {
+	var id string
+	err := r.Pool.QueryRow(ctx, `INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, user_agent)
+		VALUES ($1,$2,$3,$4,$5)
+		ON CONFLICT (endpoint) DO UPDATE SET user_id=EXCLUDED.user_id, p256dh=EXCLUDED.p256dh, auth=EXCLUDED.auth, user_agent=EXCLUDED.user_agent
+		WHERE push_subscriptions.user_id = EXCLUDED.user_id
+			OR (push_subscriptions.p256dh = EXCLUDED.p256dh AND push_subscriptions.auth = EXCLUDED.auth)
+		RETURNING id`, userID, endpoint, p256dh, auth, userAgent).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrSubscriptionTaken
+	}
+	return id, err
+}
+
+// DeletePushSubscription removes the user's subscription for endpoint.
+func (r *Repo) DeletePushSubscription(ctx context.Context, userID, endpoint string) error {
+	cmd, err := r.Pool.Exec(ctx, `DELETE FROM push_subscriptions WHERE user_id=$1 AND endpoint=$2`, userID, endpoint)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListPushSubscriptions returns every subscription of the user.
+func (r *Repo) ListPushSubscriptions(ctx context.Context, userID string) ([]PushSubscription, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT id, endpoint, p256dh, auth FROM push_subscriptions WHERE user_id=$1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []PushSubscription
+	for rows.Next() {
+		var item PushSubscription
+		if err := rows.Scan(&item.ID, &item.Endpoint, &item.P256dh, &item.Auth); err != nil {
+			return nil, err
+		}
+		res = append(res, item)
+	}
+	return res, rows.Err()
+}
+
+// RemovePushSubscription drops a subscription the push service reported gone.
+func (r *Repo) RemovePushSubscription(ctx context.Context, id string) error {
+	_, err := r.Pool.Exec(ctx, `DELETE FROM push_subscriptions WHERE id=$1`, id)
+	return err
+}
+
+// TouchPushSubscription records a successful delivery.
+func (r *Repo) TouchPushSubscription(ctx context.Context, id string, at time.Time) error {
+	_, err := r.Pool.Exec(ctx, `UPDATE push_subscriptions SET last_used_at=$2 WHERE id=$1`, id, at)
+	return err
+}
+
+// EnsureVAPIDKeys stores the candidate key pair unless one is already stored,
+// and returns the stored pair. Replicas starting together agree on one pair.
+func (r *Repo) EnsureVAPIDKeys(ctx context.Context, publicKey, privateKey string) (string, string, error) {
+	if _, err := r.Pool.Exec(ctx, `INSERT INTO vapid_keys (id, public_key, private_key) VALUES (1,$1,$2) ON CONFLICT (id) DO NOTHING`, publicKey, privateKey); err != nil {
+		return "", "", err
+	}
+	err := r.Pool.QueryRow(ctx, `SELECT public_key, private_key FROM vapid_keys WHERE id=1`).Scan(&publicKey, &privateKey)
+	return publicKey, privateKey, err
+}
@@ -0,0 +1,213 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// reminderGrace is how late a reminder may still be enqueued, e.g. after a
+// restart; older ones are dropped rather than delivered hours late.
+const reminderGrace = 2 * time.Hour
+
+// Reminder is either a clock time on the due day ("HH:MM") or a number of
+// minutes before the due day ends.
+type Reminder struct {
+	AtTime        *string
+	MinutesBefore *int
+}
+
+func (r *Repo) ListTaskReminders(ctx context.Context, taskID, workspaceID string) ([]map[string]any, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT tr.id, to_char(tr.at_time, 'HH24:MI'), tr.minutes_before
+		FROM task_reminders tr JOIN tasks ON tasks.id = tr.task_id
+		WHERE tr.task_id=$1 AND tasks.workspace_id=$2 AND tasks.deleted_at IS NULL
+		ORDER BY tr.created_at`, taskID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []map[string]any{}
+	for rows.Next() {
+		var id string
+		var atTime *string
+		var minutesBefore *int
+		if err := rows.Scan(&id, &atTime, &minutesBefore); err != nil {
+			return nil, err
+		}
+		res = append(res, map[string]any{"id": id, "task_id": taskID, "at_time": atTime, "minutes_before": minutesBefore})
+	}
+	return res, rows.Err()
+}
+
+// SetTaskReminders replaces the task's reminders.
+func (r *Repo) SetTaskReminders(ctx context.Context, taskID, workspaceID string, reminders []Reminder) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	cmd, err := tx.Exec(ctx, `UPDATE tasks SET updated_at=now(), version=version+1 WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, taskID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM task_reminders WHERE task_id=$1`, taskID); err != nil {
+		return err
+	}
+	for _, reminder := range reminders {
+		if _, err := tx.Exec(ctx, `INSERT INTO task_reminders (task_id, at_time, minutes_before) VALUES ($1, $2::time, $3)`, taskID, reminder.AtTime, reminder.MinutesBefore); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+type dueReminder struct {
+	id          string
+	taskID      string
+	workspaceID string
+	title       string
+	dueDate     *time.Time
+	isRecurring bool
+	weekdays    []int
+	startDate   *time.Time
+	endDate     *time.Time
+	timezone    *string
+	reminder    Reminder
+}
+
+// EnqueueDueReminders enqueues a notification for every reminder that fired in
+// the grace window before now, to the task's assignees or, without assignees,
+// every workspace member. Done tasks and occurrences are skipped. Returns the
+// number of outbox rows added.
+func (r *Repo) EnqueueDueReminders(ctx context.Context, now time.Time) (int, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT tr.id, tasks.id, tasks.workspace_id, tasks.title, tasks.due_date, tasks.is_recurring, tasks.recurrence_weekdays,
+		tasks.start_date, tasks.end_date, tasks.timezone, to_char(tr.at_time, 'HH24:MI'), tr.minutes_before
+		FROM task_reminders tr JOIN tasks ON tasks.id = tr.task_id
+		WHERE tasks.deleted_at IS NULL AND (tasks.is_recurring OR (tasks.status <> 'done' AND tasks.due_date IS NOT NULL))`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var reminders []dueReminder
+	for rows.Next() {
+		var item dueReminder
+		var weekdays []int16
+		if err := rows.Scan(&item.id, &item.taskID, &item.workspaceID, &item.title, &item.dueDate, &item.isRecurring, &weekdays,
+			&item.startDate, &item.endDate, &item.timezone, &item.reminder.AtTime, &item.reminder.MinutesBefore); err != nil {
+			return 0, err
+		}
+		item.weekdays = int16sToInts(weekdays)
+		reminders = append(reminders, item)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	enqueued := 0
+	for _, item := range reminders {
+		loc := taskLocation(item.timezone)
+		for _, day := range reminderDays(item, taskToday(item.timezone, now)) {
+			fireAt, err := reminderFireTime(day, loc, item.reminder)
+			if err != nil {
+				return enqueued, err
+			}
+			if fireAt.After(now) || !fireAt.After(now.Add(-reminderGrace)) {
+				continue
+			}
+			if item.isRecurring {
+				// Done and excused occurrences need no reminder.
+				var settled bool
+				if err := r.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM task_occurrences WHERE task_id=$1 AND occurrence_date=$2 AND status IN ('done', 'skipped'))
+					OR EXISTS(SELECT 1 FROM workspace_vacations WHERE workspace_id=$3 AND $2::date BETWEEN start_date AND end_date)`, item.taskID, day, item.workspaceID).Scan(&settled); err != nil {
+					return enqueued, err
+				}
+				if settled {
+					continue
+				}
+			}
+			recipients, err := r.taskRecipients(ctx, item.taskID, item.workspaceID)
+			if err != nil {
+				return enqueued, err
+			}
+			date := day.Format("2006-01-02")
+			payload := map[string]any{
+				"title": item.title, "body": "Due " + date, "task_id": item.taskID, "workspace_id": item.workspaceID, "occurrence_date": date,
+			}
+			for _, userID := range recipients {
+				count, err := r.EnqueueNotification(ctx, userID, "reminder", fmt.Sprintf("reminder:%s:%s", item.id, date), payload)
+				if err != nil {
+					return enqueued, err
+				}
+				enqueued += count
+			}
+		}
+	}
+	return enqueued, nil
+}
+
+// taskRecipients returns the task's assignees, or every workspace member when
+// the task has none.
+func (r *Repo) taskRecipients(ctx context.Context, taskID, workspaceID string) ([]string, error) {
+	var userIDs []string
+	err := r.Pool.QueryRow(ctx, `SELECT COALESCE(
+		(SELECT array_agg(user_id::text) FROM task_assignees WHERE task_id=$1),
+		(SELECT array_agg(user_id::text) FROM workspace_members WHERE workspace_id=$2),
+		'{}')`, taskID, workspaceID).Scan(&userIDs)
+	return userIDs, err
+}
+
+// reminderDays lists the due days whose reminders may fire around today: the due
+// date of a one-off task, or scheduled days of a recurring task up to a week
+// ahead (minutes_before reaches back at most a week).
+func reminderDays(item dueReminder, today time.Time) []time.Time {
+	if !item.isRecurring {
+		if item.dueDate == nil {
+			return nil
+		}
+		return []time.Time{truncateDate(*item.dueDate)}
+	}
+	var days []time.Time
+	for day := today.AddDate(0, 0, -1); !day.After(today.AddDate(0, 0, 8)); day = day.AddDate(0, 0, 1) {
+		if item.startDate != nil && day.Before(truncateDate(*item.startDate)) {
+			continue
+		}
+		if item.endDate != nil && day.After(truncateDate(*item.endDate)) {
+			continue
+		}
+		if containsWeekday(item.weekdays, int(day.Weekday())) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// reminderFireTime returns when a reminder for the due day fires in loc.
+func reminderFireTime(day time.Time, loc *time.Location, reminder Reminder) (time.Time, error) {
+	if reminder.AtTime != nil {
+		clock, err := time.Parse("15:04", *reminder.AtTime)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc), nil
+	}
+	minutes := 0
+	if reminder.MinutesBefore != nil {
+		minutes = *reminder.MinutesBefore
+	}
+	end := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+	return end.Add(-time.Duration(minutes) * time.Minute), nil
+}
+
+// taskLocation resolves a task's timezone, falling back to UTC.
+func taskLocation(timezone *string) *time.Location {
+	if timezone != nil && *timezone != "" {
+		if loc, err := time.LoadLocation(*timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"errors"
+	"math"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -15,8 +16,59 @@ var (
 	ErrInviteExpired     = errors.New("invite expired")
 	ErrInviteUsed        = errors.New("invite used")
 	ErrAlreadyPurchased  = errors.New("reward already purchased")
+	ErrOccurrenceDate    = errors.New("occurrence date required")
+	ErrBalanceSpent      = errors.New("earned balance already spent")
+	ErrNotAssignee       = errors.New("user is not an assignee")
+	ErrChecklistOpen     = errors.New("checklist has unchecked items")
+	ErrDuplicate         = errors.New("already exists")
+	ErrNotMeasurable     = errors.New("task has no target")
+	ErrTimerRunning      = errors.New("timer already running")
+	ErrNoTimer           = errors.New("no running timer")
+	ErrInvalidDependency = errors.New("dependency is not a task of the workspace")
+	ErrDependencyCycle   = errors.New("dependency would create a cycle")
+	ErrBlocked           = errors.New("task is blocked by unfinished tasks")
+	ErrNotOccurrence     = errors.New("date is not an occurrence of the task")
+	ErrOccurrenceClosed  = errors.New("occurrence already done or missed")
+	ErrInvalidGoal       = errors.New("goal is not a goal of the workspace")
+	ErrRecurringTask     = errors.New("task is recurring")
+	ErrNotAuthor         = errors.New("user is not the author")
+	ErrInvalidSort       = errors.New("sort field not allowed")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrInvalidSnooze     = errors.New("unknown snooze preset")
+	ErrSubscriptionTaken = errors.New("push subscription belongs to another user")
+	ErrTaskDone          = errors.New("task is done")
+	ErrDueDateChange     = errors.New("due date changes go through reschedule")
+	ErrValueDecayed      = errors.New("value of a postponed task is fixed")
+	ErrTimeOverlap       = errors.New("time entry overlaps another")
 )
 
+// TaskOptions holds the optional per-task settings stored next to the core task fields.
+type TaskOptions struct {
+	AssigneeOnly     bool
+	RequireChecklist bool
+	Priority         int
+	// StreakBonusPercent credits this percent of value extra per full week of
+	// consecutive completions of a recurring task.
+	StreakBonusPercent float64
+	// Target and Unit make the task measurable; progress towards Target pays
+	// value proportionally, up to MaxPayoutPercent of it.
+	Target           *float64
+	Unit             *string
+	MaxPayoutPercent float64
+	// Penalty is debited for every occurrence of a recurring task whose day ends
+	// undone; zero disables it.
+	Penalty float64
+	// PayPerHour makes completion pay value per hour of time tracked on the
+	// task (or occurrence) instead of value itself.
+	PayPerHour bool
+	// PostponeDecayPercent takes this percent off value every time the task
+	// is postponed.
+	PostponeDecayPercent float64
+}
+
+// taskAssigneesColumn selects a task's assignee user ids as a text array.
+const taskAssigneesColumn = `COALESCE((SELECT array_agg(ta.user_id::text ORDER BY ta.created_at) FROM task_assignees ta WHERE ta.task_id = tasks.id), '{}') AS assignee_ids`
+
 type Repo struct {
 	Pool *pgxpool.Pool
 }
@@ -215,121 +267,427 @@ func (r *Repo) UpdateGoal(ctx context.Context, id, workspaceID, title, descripti
 	return nil
 }
 
+// DeleteGoal moves the goal to the trash and detaches its live tasks,
+// remembering the goal so that RestoreEntity can attach them again.
 func (r *Repo) DeleteGoal(ctx context.Context, id, workspaceID string) error {
-	cmd, err := r.Pool.Exec(ctx, `UPDATE goals SET deleted_at=now(), updated_at=now(), version=version+1 WHERE id=$1 AND workspace_id=$2`, id, workspaceID)
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	cmd, err := tx.Exec(ctx, `UPDATE goals SET deleted_at=now(), updated_at=now(), version=version+1 WHERE id=$1 AND workspace_id=$2`, id, workspaceID)
 	if err != nil {
 		return err
 	}
 	if cmd.RowsAffected() == 0 {
 		return ErrNotFound
 	}
-	return nil
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET goal_id=NULL, detached_goal_id=$1, updated_at=now(), version=version+1
+		WHERE goal_id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, id, workspaceID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (r *Repo) ListGoals(ctx context.Context, workspaceID string) ([]map[string]any, error) {
-	rows, err := r.Pool.Query(ctx, `SELECT id, title, description, period, start_date, end_date, status, created_at, updated_at, deleted_at, version FROM goals WHERE workspace_id=$1 AND deleted_at IS NULL`, workspaceID)
+// AreWorkspaceGoals reports whether every goal in goalIDs is a live goal of the workspace.
+func (r *Repo) AreWorkspaceGoals(ctx context.Context, workspaceID string, goalIDs []string) (bool, error) {
+	if len(goalIDs) == 0 {
+		return true, nil
+	}
+	var count int
+	err := r.Pool.QueryRow(ctx, `SELECT count(*) FROM goals WHERE workspace_id=$1 AND deleted_at IS NULL AND id::text = ANY($2)`, workspaceID, uniqueStrings(goalIDs)).Scan(&count)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	defer rows.Close()
-	var res []map[string]any
-	for rows.Next() {
+	return count == len(uniqueStrings(goalIDs)), nil
+}
+
+// goalSorts are the fields goals can be sorted by.
+var goalSorts = map[string]sortField{
+	"created_at": {"created_at", "timestamptz"},
+	"updated_at": {"updated_at", "timestamptz"},
+	"title":      {"title", "text"},
+	"start_date": {"coalesce(start_date, 'infinity'::date)", "date"},
+	"end_date":   {"coalesce(end_date, 'infinity'::date)", "date"},
+}
+
+func (r *Repo) ListGoals(ctx context.Context, workspaceID string, page PageRequest) (Page, error) {
+	l := listing{
+		table:       "goals",
+		columns:     "id, title, description, period, start_date, end_date, status, created_at, updated_at, deleted_at, version",
+		where:       "workspace_id=$1 AND deleted_at IS NULL",
+		args:        []any{workspaceID},
+		sorts:       goalSorts,
+		defaultSort: "created_at",
+	}
+	res, err := r.paginate(ctx, l, page, func(rows pgx.Rows, sortValue *string) (map[string]any, error) {
 		var id, title, description, period, status string
 		var startDate, endDate, deletedAt *time.Time
 		var createdAt, updatedAt time.Time
 		var version int
-		if err := rows.Scan(&id, &title, &description, &period, &startDate, &endDate, &status, &createdAt, &updatedAt, &deletedAt, &version); err != nil {
+		if err := rows.Scan(&id, &title, &description, &period, &startDate, &endDate, &status, &createdAt, &updatedAt, &deletedAt, &version, sortValue); err != nil {
 			return nil, err
 		}
-		res = append(res, map[string]any{
+		return map[string]any{
 			"id": id, "workspace_id": workspaceID, "title": title, "description": description, "period": period, "start_date": startDate, "end_date": endDate, "status": status, "created_at": createdAt, "updated_at": updatedAt, "deleted_at": deletedAt, "version": version,
-		})
+		}, nil
+	})
+	if err != nil {
+		return res, err
 	}
-	return res, rows.Err()
+	ids := make([]string, len(res.Items))
+	for i, item := range res.Items {
+		ids[i] = item["id"].(string)
+	}
+	progress, err := r.goalProgress(ctx, workspaceID, ids, truncateDate(time.Now()))
+	if err != nil {
+		return res, err
+	}
+	for _, item := range res.Items {
+		item["progress"] = progress[item["id"].(string)]
+	}
+	return res, nil
+}
+
+// TaskLinks lists what CreateTask and UpdateTask link to a task. A nil list
+// leaves those links as they are; any other list replaces them.
+type TaskLinks struct {
+	AssigneeIDs   []string
+	TagIDs        []string
+	BlockedBy     []string
+	AttachmentIDs []string
 }
 
-func (r *Repo) CreateTask(ctx context.Context, workspaceID string, goalID *string, title, description string, dueDate *time.Time, repeatRule *string, value float64, status string, isRecurring bool, recurrenceWeekdays []int, startDate, endDate *time.Time, timezone *string) (string, error) {
+// CreateTask creates a task together with its links in one transaction.
+func (r *Repo) CreateTask(ctx context.Context, workspaceID string, goalID *string, title, description string, dueDate *time.Time, repeatRule *string, value float64, status string, isRecurring bool, recurrenceWeekdays []int, startDate, endDate *time.Time, timezone *string, opts TaskOptions, links TaskLinks) (string, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
 	var id string
-	err := r.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, goal_id, title, description, due_date, repeat_rule, value, status, is_recurring, recurrence_weekdays, start_date, end_date, timezone)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13) RETURNING id`, workspaceID, goalID, title, description, dueDate, repeatRule, value, status, isRecurring, recurrenceWeekdays, startDate, endDate, timezone).Scan(&id)
-	return id, err
+	err = tx.QueryRow(ctx, `INSERT INTO tasks (workspace_id, goal_id, title, description, due_date, repeat_rule, value, status, is_recurring, recurrence_weekdays, start_date, end_date, timezone, assignee_only, require_checklist, priority, streak_bonus_percent, target, unit, max_payout_percent, penalty, penalty_since, pay_per_hour, postpone_decay_percent)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,CASE WHEN $21 > 0 THEN now() END,$22,$23) RETURNING id`, workspaceID, goalID, title, description, dueDate, repeatRule, value, status, isRecurring, recurrenceWeekdays, startDate, endDate, timezone, opts.AssigneeOnly, opts.RequireChecklist, opts.Priority, opts.StreakBonusPercent, opts.Target, opts.Unit, opts.MaxPayoutPercent, opts.Penalty, opts.PayPerHour, opts.PostponeDecayPercent).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+	if err := setTaskLinks(ctx, tx, id, workspaceID, links); err != nil {
+		return "", err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// setTaskLinks replaces the links of a task given in links within tx.
+func setTaskLinks(ctx context.Context, tx pgx.Tx, id, workspaceID string, links TaskLinks) error {
+	if links.AssigneeIDs != nil {
+		if err := setTaskAssignees(ctx, tx, id, workspaceID, links.AssigneeIDs); err != nil {
+			return err
+		}
+	}
+	if links.TagIDs != nil {
+		if err := setTaskTags(ctx, tx, id, workspaceID, links.TagIDs); err != nil {
+			return err
+		}
+	}
+	if links.BlockedBy != nil {
+		if err := setTaskDependencies(ctx, tx, id, workspaceID, links.BlockedBy); err != nil {
+			return err
+		}
+	}
+	if links.AttachmentIDs != nil {
+		if err := setAttachments(ctx, tx, "task", id, workspaceID, links.AttachmentIDs); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (r *Repo) UpdateTask(ctx context.Context, id, workspaceID string, goalID *string, title, description string, dueDate *time.Time, repeatRule *string, value float64, status string, isRecurring bool, recurrenceWeekdays []int, startDate, endDate *time.Time, timezone *string) error {
-	cmd, err := r.Pool.Exec(ctx, `UPDATE tasks SET goal_id=$1, title=$2, description=$3, due_date=$4, repeat_rule=$5, value=$6, status=$7, is_recurring=$8, recurrence_weekdays=$9, start_date=$10, end_date=$11, timezone=$12, updated_at=now(), version=version+1 WHERE id=$13 AND workspace_id=$14`, goalID, title, description, dueDate, repeatRule, value, status, isRecurring, recurrenceWeekdays, startDate, endDate, timezone, id, workspaceID)
+// UpdateTask overwrites a task. The due date of a one-off task only changes
+// through RescheduleTask, which keeps the history and applies the decay, so a
+// different one fails with ErrDueDateChange and a nil one keeps the current
+// date; once a task has been postponed its value is fixed and a different one
+// fails with ErrValueDecayed. The links are replaced in the same transaction.
+func (r *Repo) UpdateTask(ctx context.Context, id, workspaceID string, goalID *string, title, description string, dueDate *time.Time, repeatRule *string, value float64, status string, isRecurring bool, recurrenceWeekdays []int, startDate, endDate *time.Time, timezone *string, opts TaskOptions, links TaskLinks) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var currentDue *time.Time
+	var currentValue float64
+	var currentRecurring bool
+	var postponedCount int
+	err = tx.QueryRow(ctx, `SELECT due_date, value, is_recurring, postponed_count FROM tasks WHERE id=$1 AND workspace_id=$2 FOR UPDATE`, id, workspaceID).
+		Scan(&currentDue, &currentValue, &currentRecurring, &postponedCount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !currentRecurring && !isRecurring {
+		// An omitted due date leaves the one-off task's date as it is.
+		if dueDate == nil {
+			dueDate = currentDue
+		}
+		if !sameDate(currentDue, dueDate) {
+			return ErrDueDateChange
+		}
+	}
+	if postponedCount > 0 && math.Round(value*100) != math.Round(currentValue*100) {
+		return ErrValueDecayed
+	}
+	cmd, err := tx.Exec(ctx, `UPDATE tasks SET goal_id=$1, title=$2, description=$3, due_date=$4, repeat_rule=$5, value=$6, status=$7, is_recurring=$8, recurrence_weekdays=$9, start_date=$10, end_date=$11, timezone=$12, assignee_only=$13, require_checklist=$14, priority=$15, streak_bonus_percent=$16, target=$17, unit=$18, max_payout_percent=$19, penalty=$22, pay_per_hour=$23, postpone_decay_percent=$24,
+		penalty_since=CASE WHEN $22 = 0 THEN NULL WHEN penalty = 0 OR penalty_since IS NULL THEN now() ELSE penalty_since END,
+		updated_at=now(), version=version+1 WHERE id=$20 AND workspace_id=$21`, goalID, title, description, dueDate, repeatRule, value, status, isRecurring, recurrenceWeekdays, startDate, endDate, timezone, opts.AssigneeOnly, opts.RequireChecklist, opts.Priority, opts.StreakBonusPercent, opts.Target, opts.Unit, opts.MaxPayoutPercent, id, workspaceID, opts.Penalty, opts.PayPerHour, opts.PostponeDecayPercent)
 	if err != nil {
 		return err
 	}
 	if cmd.RowsAffected() == 0 {
 		return ErrNotFound
 	}
-	return nil
+	if err := setTaskLinks(ctx, tx, id, workspaceID, links); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// sameDate reports whether two optional dates fall on the same day.
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return truncateDate(*a).Equal(truncateDate(*b))
 }
 
-func (r *Repo) CompleteTask(ctx context.Context, id, workspaceID, userID string, occurrenceDate *time.Time) (float64, bool, error) {
+// CompleteTask marks a task (or one occurrence of a recurring task) done and
+// credits its value. It fails with ErrBlocked while a task blocking it is not
+// done, unless force is set.
+func (r *Repo) CompleteTask(ctx context.Context, id, workspaceID, userID string, occurrenceDate *time.Time, force bool) (float64, bool, error) {
 	tx, err := r.Pool.Begin(ctx)
 	if err != nil {
 		return 0, false, err
 	}
 	defer tx.Rollback(ctx)
 
-	var value float64
-	var isRecurring bool
-	err = tx.QueryRow(ctx, `SELECT value, is_recurring FROM tasks WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, id, workspaceID).Scan(&value, &isRecurring)
-	if errors.Is(err, pgx.ErrNoRows) {
-		return 0, false, ErrNotFound
-	}
+	task, err := lockCompletionTask(ctx, tx, id, workspaceID)
 	if err != nil {
 		return 0, false, err
 	}
+	if task.assigneeOnly {
+		if err := requireAssignee(ctx, tx, id, workspaceID, userID); err != nil {
+			return 0, false, err
+		}
+	}
+	earned, completed, err := completeLockedTask(ctx, tx, task, userID, occurrenceDate, force)
+	if err != nil || !completed {
+		return 0, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, err
+	}
+	return earned, true, nil
+}
 
-	if isRecurring {
-		if occurrenceDate == nil {
-			return 0, false, errors.New("occurrence date required")
+// completionTask is the part of a task row that completion needs.
+type completionTask struct {
+	id, workspaceID, title, status                            string
+	value, streakBonusPercent, maxPayoutPercent, progressPaid float64
+	target                                                    *float64
+	isRecurring, assigneeOnly, requireChecklist, payPerHour   bool
+	recurrenceWeekdays                                        []int16
+	startDate                                                 *time.Time
+}
+
+// lockCompletionTask loads a live task for completion or progress. The row
+// lock serialises completion with progress updates on the same task.
+func lockCompletionTask(ctx context.Context, tx pgx.Tx, id, workspaceID string) (completionTask, error) {
+	task := completionTask{id: id, workspaceID: workspaceID}
+	err := tx.QueryRow(ctx, `SELECT title, status, value, is_recurring, assignee_only, require_checklist, streak_bonus_percent, recurrence_weekdays, start_date, target, max_payout_percent, progress_paid, pay_per_hour
+		FROM tasks WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL FOR UPDATE`, id, workspaceID).Scan(&task.title, &task.status, &task.value, &task.isRecurring, &task.assigneeOnly, &task.requireChecklist,
+		&task.streakBonusPercent, &task.recurrenceWeekdays, &task.startDate, &task.target, &task.maxPayoutPercent, &task.progressPaid, &task.payPerHour)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return task, ErrNotFound
+	}
+	return task, err
+}
+
+// completeLockedTask is the completion shared by CompleteTask and
+// LogTaskProgress: it enforces the checklist and dependency gates, marks the
+// task or occurrence done, resets a recurring task's checklist, pays the rest
+// of its value and the streak bonus and notifies the workspace. It reports
+// false, without error, when the task or occurrence was already done. The
+// caller checks assignees and commits.
+func completeLockedTask(ctx context.Context, tx pgx.Tx, task completionTask, userID string, occurrenceDate *time.Time, force bool) (float64, bool, error) {
+	id, workspaceID := task.id, task.workspaceID
+	if task.requireChecklist {
+		var open bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM task_checklist_items WHERE task_id=$1 AND done=false AND deleted_at IS NULL)`, id).Scan(&open); err != nil {
+			return 0, false, err
+		}
+		if open {
+			return 0, false, ErrChecklistOpen
+		}
+	}
+
+	if task.isRecurring && occurrenceDate == nil {
+		return 0, false, ErrOccurrenceDate
+	}
+	if !force {
+		var blockerDate *time.Time
+		if task.isRecurring {
+			blockerDate = occurrenceDate
+		}
+		blocked, err := openBlockers(ctx, tx, id, blockerDate)
+		if err != nil {
+			return 0, false, err
+		}
+		if blocked {
+			return 0, false, ErrBlocked
+		}
+	}
+	if !task.isRecurring {
+		occurrenceDate = nil
+	}
+	// Hourly tasks pay for the time tracked so far; running timers stop here.
+	payout := task.value
+	if task.payPerHour {
+		seconds, err := stopTimersForCompletion(ctx, tx, id, occurrenceDate)
+		if err != nil {
+			return 0, false, err
 		}
-		var completed bool
-		if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM task_occurrences WHERE task_id=$1 AND occurrence_date=$2 AND done=true)`, id, *occurrenceDate).Scan(&completed); err != nil {
+		payout = hourlyPayout(task.value, seconds)
+	}
+
+	progressPaid := task.progressPaid
+	if task.isRecurring {
+		var occurrenceStatus string
+		progressPaid = 0
+		err := tx.QueryRow(ctx, `SELECT status, paid FROM task_occurrences WHERE task_id=$1 AND occurrence_date=$2`, id, *occurrenceDate).Scan(&occurrenceStatus, &progressPaid)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 			return 0, false, err
 		}
-		if completed {
+		if occurrenceStatus == "done" {
 			return 0, false, nil
 		}
-		if _, err := tx.Exec(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, done, completed_at)
-			VALUES ($1,$2,true,now())
-			ON CONFLICT (task_id, occurrence_date) DO UPDATE SET done=true, completed_at=now()`, id, *occurrenceDate); err != nil {
+		// Completing a skipped or missed occurrence supersedes the excuse.
+		if _, err := tx.Exec(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, status, completed_at, progress, paid)
+			VALUES ($1,$2,'done',now(),COALESCE($3,0),$4)
+			ON CONFLICT (task_id, occurrence_date) DO UPDATE SET status='done', skip_reason=NULL, skipped_by=NULL, completed_at=now(),
+			progress=GREATEST(task_occurrences.progress, EXCLUDED.progress), paid=GREATEST(task_occurrences.paid, EXCLUDED.paid)`, id, *occurrenceDate, task.target, payout); err != nil {
+			return 0, false, err
+		}
+		// The checklist belongs to the occurrence being worked on: once it is
+		// done the items start over for the next one. Their earns are kept and
+		// filed under this occurrence, so unchecking an item later only
+		// reverses what was earned for the next one.
+		if _, err := tx.Exec(ctx, `UPDATE transactions SET occurrence_date=$2
+			WHERE entity_type='checklist_item' AND occurrence_date IS NULL
+			AND entity_id IN (SELECT ci.id FROM task_checklist_items ci WHERE ci.task_id=$1)`, id, *occurrenceDate); err != nil {
+			return 0, false, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE task_checklist_items SET done=false, done_at=NULL, updated_at=now(), version=version+1
+			WHERE task_id=$1 AND done AND deleted_at IS NULL`, id); err != nil {
 			return 0, false, err
 		}
 	} else {
 		var status string
-		err = tx.QueryRow(ctx, `UPDATE tasks SET status='done', done_at=now(), updated_at=now(), version=version+1
+		err := tx.QueryRow(ctx, `UPDATE tasks SET status='done', done_at=now(), progress=GREATEST(progress, COALESCE(target, 0)), progress_paid=GREATEST(progress_paid, $3), updated_at=now(), version=version+1
 			WHERE id=$1 AND workspace_id=$2 AND status!='done' AND deleted_at IS NULL
-			RETURNING status`, id, workspaceID).Scan(&status)
+			RETURNING status`, id, workspaceID, payout).Scan(&status)
 		if errors.Is(err, pgx.ErrNoRows) {
-			checkErr := tx.QueryRow(ctx, `SELECT status FROM tasks WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, id, workspaceID).Scan(&status)
-			if errors.Is(checkErr, pgx.ErrNoRows) {
-				return 0, false, ErrNotFound
-			}
-			if status == "done" {
-				return 0, false, nil
-			}
-			return 0, false, checkErr
+			return 0, false, nil
 		}
 		if err != nil {
 			return 0, false, err
 		}
 	}
 
-	if _, err := tx.Exec(ctx, `INSERT INTO transactions (workspace_id, user_id, type, amount, reason, entity_type, entity_id)
-		VALUES ($1,$2,'earn',$3,'task completed','task',$4)`, workspaceID, userID, value, id); err != nil {
+	// Measurable tasks may already have been paid in part through progress
+	// updates; completion pays the rest of the full value.
+	earned := math.Max(payout-progressPaid, 0)
+	if earned > 0 {
+		if err := creditEarn(ctx, tx, workspaceID, userID, earned, "task completed", "task", id, occurrenceDate); err != nil {
+			return 0, false, err
+		}
+	}
+	if task.isRecurring && task.streakBonusPercent > 0 {
+		bonus, err := creditStreakBonus(ctx, tx, workspaceID, userID, id, task.value, task.streakBonusPercent, int16sToInts(task.recurrenceWeekdays), task.startDate, *occurrenceDate)
+		if err != nil {
+			return 0, false, err
+		}
+		earned += bonus
+	}
+	// Tasks have no separate review step: completion is when a task is
+	// accepted and paid, so task_completed also stands for "task approved".
+	dedupeKey := "task_completed:" + id
+	if occurrenceDate != nil {
+		dedupeKey += ":" + occurrenceDate.Format("2006-01-02")
+	}
+	if err := enqueueWorkspaceEvent(ctx, tx, workspaceID, userID, "task_completed", dedupeKey, map[string]any{
+		"title": "Task completed", "body": task.title, "task_id": id, "workspace_id": workspaceID, "user_id": userID,
+	}); err != nil {
 		return 0, false, err
 	}
-	if _, err := tx.Exec(ctx, `UPDATE workspace_balance SET balance = balance + $1, updated_at=now() WHERE workspace_id=$2`, value, workspaceID); err != nil {
+	return earned, true, nil
+}
+
+// UncompleteTask resets a completed task (or one occurrence of a recurring task)
+// and writes a 'reversal' transaction compensating the original earn. It fails
+// with ErrBalanceSpent when the balance would drop below zero unless force is set.
+// Assignee-only tasks fail with ErrNotAssignee for other users, except when
+// forced by the owner.
+func (r *Repo) UncompleteTask(ctx context.Context, id, workspaceID, userID string, occurrenceDate *time.Time, force bool) (float64, bool, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	task, err := lockCompletionTask(ctx, tx, id, workspaceID)
+	if err != nil {
+		return 0, false, err
+	}
+	if task.assigneeOnly && !force {
+		if err := requireAssignee(ctx, tx, id, workspaceID, userID); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if task.isRecurring {
+		if occurrenceDate == nil {
+			return 0, false, ErrOccurrenceDate
+		}
+		cmd, err := tx.Exec(ctx, `UPDATE task_occurrences SET status='pending', completed_at=NULL, progress=0, paid=0
+			WHERE task_id=$1 AND occurrence_date=$2 AND status='done'`, id, *occurrenceDate)
+		if err != nil {
+			return 0, false, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return 0, false, nil
+		}
+	} else {
+		occurrenceDate = nil
+		cmd, err := tx.Exec(ctx, `UPDATE tasks SET status='open', done_at=NULL, progress=0, progress_paid=0, updated_at=now(), version=version+1
+			WHERE id=$1 AND workspace_id=$2 AND status='done' AND deleted_at IS NULL`, id, workspaceID)
+		if err != nil {
+			return 0, false, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return 0, false, nil
+		}
+	}
+
+	amount, err := reverseEarns(ctx, tx, workspaceID, userID, "task", id, occurrenceDate, task.value, "task completion reversed", force)
+	if err != nil {
 		return 0, false, err
 	}
 	if err := tx.Commit(ctx); err != nil {
 		return 0, false, err
 	}
-	return value, true, nil
+	return amount, true, nil
 }
 
 func (r *Repo) DeleteTask(ctx context.Context, id, workspaceID string) error {
@@ -343,15 +701,186 @@ func (r *Repo) DeleteTask(ctx context.Context, id, workspaceID string) error {
 	return nil
 }
 
-func (r *Repo) ListTasks(ctx context.Context, workspaceID string) ([]map[string]any, error) {
-	rows, err := r.Pool.Query(ctx, `SELECT id, goal_id, title, description, due_date, repeat_rule, value, status, done_at, created_at, updated_at, deleted_at, version, is_recurring, recurrence_weekdays, start_date, end_date, timezone
-		FROM tasks WHERE workspace_id=$1 AND deleted_at IS NULL`, workspaceID)
+// MoveTask puts the task under goalID, or detaches it when goalID is nil. The
+// goal must be a live goal of the same workspace.
+func (r *Repo) MoveTask(ctx context.Context, id, workspaceID string, goalID *string) error {
+	if goalID != nil {
+		var exists bool
+		if err := r.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM goals WHERE id::text=$1 AND workspace_id=$2 AND deleted_at IS NULL)`, *goalID, workspaceID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrInvalidGoal
+		}
+	}
+	cmd, err := r.Pool.Exec(ctx, `UPDATE tasks SET goal_id=$3, detached_goal_id=NULL, updated_at=now(), version=version+1 WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, id, workspaceID, goalID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Snooze presets accepted by SnoozeTask.
+const (
+	SnoozeTomorrow = "tomorrow"
+	SnoozeNextWeek = "next_week"
+)
+
+// SnoozePresets lists the snooze presets.
+var SnoozePresets = []string{SnoozeTomorrow, SnoozeNextWeek}
+
+// Reschedule is a task's state after its due date moved.
+type Reschedule struct {
+	DueDate        time.Time
+	Value          float64
+	PostponedCount int
+}
+
+// RescheduleTask moves the due date of a one-off task and records the move in
+// its reschedule history. Moving the date later counts as a postponement and
+// takes the task's postpone decay off its value. Moving it to the current due
+// date changes nothing. Done tasks fail with ErrTaskDone.
+func (r *Repo) RescheduleTask(ctx context.Context, id, workspaceID, userID string, dueDate time.Time) (Reschedule, error) {
+	return r.reschedule(ctx, id, workspaceID, userID, nil, func(*time.Location) (time.Time, error) {
+		return truncateDate(dueDate), nil
+	})
+}
+
+// SnoozeTask reschedules a one-off task by a preset, counted from today in
+// the task's timezone: tomorrow, or next Monday for next_week.
+func (r *Repo) SnoozeTask(ctx context.Context, id, workspaceID, userID, preset string, now time.Time) (Reschedule, error) {
+	return r.reschedule(ctx, id, workspaceID, userID, &preset, func(loc *time.Location) (time.Time, error) {
+		return snoozeDate(preset, now, loc)
+	})
+}
+
+// snoozeDate resolves a snooze preset against now in loc.
+func snoozeDate(preset string, now time.Time, loc *time.Location) (time.Time, error) {
+	today := truncateDate(now.In(loc))
+	switch preset {
+	case SnoozeTomorrow:
+		return today.AddDate(0, 0, 1), nil
+	case SnoozeNextWeek:
+		days := (8 - int(today.Weekday())) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+	return time.Time{}, ErrInvalidSnooze
+}
+
+func (r *Repo) reschedule(ctx context.Context, id, workspaceID, userID string, snooze *string, target func(*time.Location) (time.Time, error)) (Reschedule, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return Reschedule{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var dueDate *time.Time
+	var value, decayPercent float64
+	var isRecurring bool
+	var status string
+	var timezone *string
+	var postponedCount int
+	err = tx.QueryRow(ctx, `SELECT due_date, value, is_recurring, status, timezone, postpone_decay_percent, postponed_count
+		FROM tasks WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL FOR UPDATE`, id, workspaceID).Scan(&dueDate, &value, &isRecurring, &status, &timezone, &decayPercent, &postponedCount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Reschedule{}, ErrNotFound
+	}
+	if err != nil {
+		return Reschedule{}, err
+	}
+	if isRecurring {
+		return Reschedule{}, ErrRecurringTask
+	}
+	if status == "done" {
+		return Reschedule{}, ErrTaskDone
+	}
+	newDate, err := target(taskLocation(timezone))
+	if err != nil {
+		return Reschedule{}, err
+	}
+	if dueDate != nil && truncateDate(*dueDate).Equal(newDate) {
+		return Reschedule{DueDate: newDate, Value: value, PostponedCount: postponedCount}, nil
+	}
+	newValue := value
+	if dueDate != nil && newDate.After(truncateDate(*dueDate)) {
+		postponedCount++
+		newValue = math.Round(value*(100-decayPercent)) / 100
+	}
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET due_date=$3, value=$4, postponed_count=$5, updated_at=now(), version=version+1
+		WHERE id=$1 AND workspace_id=$2`, id, workspaceID, newDate, newValue, postponedCount); err != nil {
+		return Reschedule{}, err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO task_reschedules (task_id, from_date, to_date, snooze, value_before, value_after, rescheduled_by)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)`, id, dueDate, newDate, snooze, value, newValue, userID); err != nil {
+		return Reschedule{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Reschedule{}, err
+	}
+	return Reschedule{DueDate: newDate, Value: newValue, PostponedCount: postponedCount}, nil
+}
+
+// ListTaskReschedules returns the reschedule history of a task, newest first.
+func (r *Repo) ListTaskReschedules(ctx context.Context, taskID, workspaceID string) ([]map[string]any, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT rs.id, rs.from_date, rs.to_date, rs.snooze, rs.value_before, rs.value_after, rs.rescheduled_by, rs.created_at
+		FROM task_reschedules rs JOIN tasks t ON t.id = rs.task_id
+		WHERE rs.task_id=$1 AND t.workspace_id=$2 ORDER BY rs.created_at DESC, rs.id`, taskID, workspaceID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var res []map[string]any
+	res := []map[string]any{}
 	for rows.Next() {
+		var id string
+		var fromDate *time.Time
+		var toDate, createdAt time.Time
+		var snooze, rescheduledBy *string
+		var valueBefore, valueAfter float64
+		if err := rows.Scan(&id, &fromDate, &toDate, &snooze, &valueBefore, &valueAfter, &rescheduledBy, &createdAt); err != nil {
+			return nil, err
+		}
+		var from *string
+		if fromDate != nil {
+			formatted := fromDate.Format("2006-01-02")
+			from = &formatted
+		}
+		res = append(res, map[string]any{
+			"id": id, "task_id": taskID, "from_date": from, "to_date": toDate.Format("2006-01-02"), "snooze": snooze,
+			"value_before": valueBefore, "value_after": valueAfter, "rescheduled_by": rescheduledBy, "created_at": createdAt,
+		})
+	}
+	return res, rows.Err()
+}
+
+// taskSorts are the fields tasks can be sorted by.
+var taskSorts = map[string]sortField{
+	"created_at": {"created_at", "timestamptz"},
+	"updated_at": {"updated_at", "timestamptz"},
+	"title":      {"title", "text"},
+	"due_date":   {"coalesce(due_date, 'infinity'::date)", "date"},
+	"priority":   {"priority", "smallint"},
+	"value":      {"value", "numeric"},
+}
+
+// ListTasks returns a page of the workspace's live tasks matching filter.
+func (r *Repo) ListTasks(ctx context.Context, workspaceID string, filter TaskFilter, page PageRequest) (Page, error) {
+	conditions, args := filter.where([]any{workspaceID})
+	l := listing{
+		table:       "tasks",
+		columns:     "id, goal_id, title, description, due_date, repeat_rule, value, status, done_at, created_at, updated_at, deleted_at, version, is_recurring, recurrence_weekdays, start_date, end_date, timezone, assignee_only, require_checklist, priority, streak_bonus_percent, target, unit, max_payout_percent, progress, penalty, pay_per_hour, postpone_decay_percent, postponed_count, " + taskAssigneesColumn + ", " + taskTagsColumn + ", " + taskBlockedByColumn + ", " + taskBlocksColumn + ", " + taskAttachmentsColumn,
+		where:       "workspace_id=$1 AND deleted_at IS NULL" + conditions,
+		args:        args,
+		sorts:       taskSorts,
+		defaultSort: "created_at",
+	}
+	var recurring []streakTask
+	res, err := r.paginate(ctx, l, page, func(rows pgx.Rows, sortValue *string) (map[string]any, error) {
 		var id string
 		var goalID *string
 		var title, description, status string
@@ -364,7 +893,15 @@ func (r *Repo) ListTasks(ctx context.Context, workspaceID string) ([]map[string]
 		var recurrenceWeekdays []int16
 		var startDate, endDate *time.Time
 		var timezone *string
-		if err := rows.Scan(&id, &goalID, &title, &description, &dueDate, &repeatRule, &value, &status, &doneAt, &createdAt, &updatedAt, &deletedAt, &version, &isRecurring, &recurrenceWeekdays, &startDate, &endDate, &timezone); err != nil {
+		var assigneeOnly, requireChecklist bool
+		var priority int
+		var streakBonusPercent, maxPayoutPercent, progress, penalty, postponeDecayPercent float64
+		var payPerHour bool
+		var postponedCount int
+		var target *float64
+		var unit *string
+		var assigneeIDs, tagIDs, blockedBy, blocks, attachmentIDs []string
+		if err := rows.Scan(&id, &goalID, &title, &description, &dueDate, &repeatRule, &value, &status, &doneAt, &createdAt, &updatedAt, &deletedAt, &version, &isRecurring, &recurrenceWeekdays, &startDate, &endDate, &timezone, &assigneeOnly, &requireChecklist, &priority, &streakBonusPercent, &target, &unit, &maxPayoutPercent, &progress, &penalty, &payPerHour, &postponeDecayPercent, &postponedCount, &assigneeIDs, &tagIDs, &blockedBy, &blocks, &attachmentIDs, sortValue); err != nil {
 			return nil, err
 		}
 		var weekdays []int
@@ -374,134 +911,151 @@ func (r *Repo) ListTasks(ctx context.Context, workspaceID string) ([]map[string]
 				weekdays = append(weekdays, int(day))
 			}
 		}
-		res = append(res, map[string]any{
-			"id": id, "workspace_id": workspaceID, "goal_id": goalID, "title": title, "description": description, "due_date": dueDate, "repeat_rule": repeatRule, "value": value, "status": status, "done_at": doneAt, "created_at": createdAt, "updated_at": updatedAt, "deleted_at": deletedAt, "version": version, "is_recurring": isRecurring, "recurrence_weekdays": weekdays, "start_date": startDate, "end_date": endDate, "timezone": timezone,
-		})
+		if isRecurring {
+			recurring = append(recurring, streakTask{id: id, timezone: timezone})
+		}
+		return map[string]any{
+			"id": id, "workspace_id": workspaceID, "goal_id": goalID, "title": title, "description": description, "due_date": dueDate, "repeat_rule": repeatRule, "value": value, "status": status, "done_at": doneAt, "created_at": createdAt, "updated_at": updatedAt, "deleted_at": deletedAt, "version": version, "is_recurring": isRecurring, "recurrence_weekdays": weekdays, "start_date": startDate, "end_date": endDate, "timezone": timezone, "assignee_only": assigneeOnly, "require_checklist": requireChecklist, "priority": priority, "streak_bonus_percent": streakBonusPercent, "target": target, "unit": unit, "max_payout_percent": maxPayoutPercent, "progress": progress, "penalty": penalty, "pay_per_hour": payPerHour, "postpone_decay_percent": postponeDecayPercent, "postponed_count": postponedCount, "assignee_ids": assigneeIDs, "tag_ids": tagIDs, "blocked_by": blockedBy, "blocks": blocks, "attachment_ids": attachmentIDs,
+		}, nil
+	})
+	if err != nil {
+		return Page{}, err
 	}
-	return res, rows.Err()
+	streaks, err := r.loadStreaks(ctx, recurring, time.Now())
+	if err != nil {
+		return Page{}, err
+	}
+	for _, task := range res.Items {
+		streak := streaks[task["id"].(string)]
+		task["current_streak"] = streak.Current
+		task["longest_streak"] = streak.Longest
+	}
+	return res, nil
 }
 
-func (r *Repo) ListTaskInstances(ctx context.Context, workspaceID string, from, to time.Time) ([]map[string]any, error) {
-	rows, err := r.Pool.Query(ctx, `SELECT id, goal_id, title, description, due_date, repeat_rule, value, status, done_at, is_recurring, recurrence_weekdays, start_date, end_date, timezone
+// instancesQuery selects the workspace's live tasks that may fall in [$2, $3]
+// and match conditions, and expands them into their instances: a one-off task
+// yields its due date, a recurring task every matching weekday within its
+// start and end dates. Each row is the task's columns followed by the
+// instance. Recurring instances carry their occurrence row, and pending ones
+// on a workspace vacation day become skipped with the vacation's reason.
+func instancesQuery(conditions string) string {
+	return `WITH selected AS (
+		SELECT id, goal_id, title, description, due_date, repeat_rule, value, status, done_at, is_recurring, recurrence_weekdays, start_date, end_date, timezone, assignee_only, require_checklist, priority, streak_bonus_percent, target, unit, max_payout_percent, progress, penalty, pay_per_hour, postpone_decay_percent, postponed_count, ` + taskAssigneesColumn + `, ` + taskTagsColumn + `, ` + taskBlockedByColumn + `, ` + taskBlocksColumn + `, ` + taskAttachmentsColumn + `
 		FROM tasks
 		WHERE workspace_id=$1 AND deleted_at IS NULL
-		AND ((is_recurring = false AND due_date BETWEEN $2 AND $3) OR is_recurring = true)`, workspaceID, from, to)
+		AND ((is_recurring = false AND due_date BETWEEN $2 AND $3)
+			OR (is_recurring = true AND cardinality(recurrence_weekdays) > 0 AND (start_date IS NULL OR start_date <= $3) AND (end_date IS NULL OR end_date >= $2)))` + conditions + `
+	)
+	SELECT t.*, occ.occurrence_date,
+		CASE WHEN coalesce(o.status, 'pending') = 'pending' AND vac.reason IS NOT NULL THEN 'skipped' ELSE coalesce(o.status, 'pending') END,
+		CASE WHEN coalesce(o.status, 'pending') = 'pending' AND vac.reason IS NOT NULL THEN vac.reason ELSE o.skip_reason END,
+		coalesce(o.progress, 0)
+	FROM selected t
+	CROSS JOIN LATERAL (
+		SELECT t.due_date AS occurrence_date WHERE NOT t.is_recurring
+		UNION ALL
+		SELECT day::date FROM generate_series(greatest($2::date, t.start_date)::timestamp, least($3::date, t.end_date)::timestamp, interval '1 day') AS day
+		WHERE t.is_recurring AND extract(dow FROM day)::smallint = ANY(t.recurrence_weekdays)
+	) occ
+	LEFT JOIN task_occurrences o ON t.is_recurring AND o.task_id = t.id AND o.occurrence_date = occ.occurrence_date
+	LEFT JOIN LATERAL (
+		SELECT v.reason FROM workspace_vacations v
+		WHERE v.workspace_id = $1 AND occ.occurrence_date BETWEEN v.start_date AND v.end_date
+		ORDER BY v.start_date LIMIT 1
+	) vac ON t.is_recurring
+	ORDER BY occ.occurrence_date, t.priority DESC, t.id`
+}
+
+// ListTaskInstances returns one entry per task instance between from and to,
+// ordered by date. Tasks are selected and expanded by a single set-based
+// query. filter.Status is matched against the occurrence status, with "open"
+// meaning pending.
+func (r *Repo) ListTaskInstances(ctx context.Context, workspaceID string, from, to time.Time, filter TaskFilter) ([]map[string]any, error) {
+	wantStatus := filter.Status
+	if wantStatus == "open" {
+		wantStatus = "pending"
+	}
+	filter.Status = ""
+	conditions, args := filter.where([]any{workspaceID, from, to})
+	rows, err := r.Pool.Query(ctx, instancesQuery(conditions), args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	type taskRow struct {
-		id                 string
-		goalID             *string
-		title              string
-		description        string
-		dueDate            *time.Time
-		repeatRule         *string
-		value              float64
-		status             string
-		doneAt             *time.Time
-		isRecurring        bool
-		recurrenceWeekdays []int
-		startDate          *time.Time
-		endDate            *time.Time
-		timezone           *string
-	}
-
-	var tasks []taskRow
-	var recurringIDs []string
+	var res []map[string]any
+	seen := map[string]bool{}
+	var recurring []streakTask
 	for rows.Next() {
-		var row taskRow
+		var id, title, description, taskStatus, status string
+		var goalID, repeatRule, timezone, unit, skipReason *string
+		var dueDate, doneAt, startDate, endDate *time.Time
+		var value, streakBonusPercent, maxPayoutPercent, taskProgress, penalty, postponeDecay, progress float64
+		var target *float64
+		var isRecurring, assigneeOnly, requireChecklist, payPerHour bool
 		var recurrenceWeekdays []int16
-		if err := rows.Scan(&row.id, &row.goalID, &row.title, &row.description, &row.dueDate, &row.repeatRule, &row.value, &row.status, &row.doneAt, &row.isRecurring, &recurrenceWeekdays, &row.startDate, &row.endDate, &row.timezone); err != nil {
+		var priority, postponedCount int
+		var assigneeIDs, tagIDs, blockedBy, blocks, attachmentIDs []string
+		var date time.Time
+		if err := rows.Scan(&id, &goalID, &title, &description, &dueDate, &repeatRule, &value, &taskStatus, &doneAt, &isRecurring, &recurrenceWeekdays, &startDate, &endDate, &timezone, &assigneeOnly, &requireChecklist, &priority, &streakBonusPercent, &target, &unit, &maxPayoutPercent, &taskProgress, &penalty, &payPerHour, &postponeDecay, &postponedCount, &assigneeIDs, &tagIDs, &blockedBy, &blocks, &attachmentIDs,
+			&date, &status, &skipReason, &progress); err != nil {
 			return nil, err
 		}
-		if recurrenceWeekdays != nil {
-			row.recurrenceWeekdays = make([]int, 0, len(recurrenceWeekdays))
-			for _, day := range recurrenceWeekdays {
-				row.recurrenceWeekdays = append(row.recurrenceWeekdays, int(day))
-			}
+		if !isRecurring {
+			status = occurrenceStatusOf(taskStatus)
+			progress = taskProgress
 		}
-		if row.isRecurring {
-			recurringIDs = append(recurringIDs, row.id)
+		if wantStatus != "" && status != wantStatus {
+			continue
 		}
-		tasks = append(tasks, row)
+		if isRecurring && !seen[id] {
+			seen[id] = true
+			recurring = append(recurring, streakTask{id: id, timezone: timezone})
+		}
+		res = append(res, map[string]any{
+			"id": id, "workspace_id": workspaceID, "goal_id": goalID, "title": title, "description": description, "due_date": dueDate, "repeat_rule": repeatRule, "value": value, "status": taskStatus, "done_at": doneAt, "is_recurring": isRecurring, "recurrence_weekdays": int16sToInts(recurrenceWeekdays), "start_date": startDate, "end_date": endDate, "timezone": timezone, "assignee_only": assigneeOnly, "require_checklist": requireChecklist, "priority": priority, "streak_bonus_percent": streakBonusPercent, "target": target, "unit": unit, "max_payout_percent": maxPayoutPercent, "progress": progress, "penalty": penalty, "pay_per_hour": payPerHour, "postpone_decay_percent": postponeDecay, "postponed_count": postponedCount, "assignee_ids": assigneeIDs, "tag_ids": tagIDs, "blocked_by": blockedBy, "blocks": blocks, "attachment_ids": attachmentIDs, "occurrence_date": date.Format("2006-01-02"), "occurrence_status": status, "skip_reason": skipReason, "done": status == "done", "missed": status == "missed",
+		})
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-
-	occurrences := map[string]map[string]bool{}
-	if len(recurringIDs) > 0 {
-		rows, err := r.Pool.Query(ctx, `SELECT task_id, occurrence_date, done FROM task_occurrences
-			WHERE occurrence_date BETWEEN $1 AND $2 AND task_id = ANY($3)`, from, to, recurringIDs)
-		if err != nil {
-			return nil, err
-		}
-		defer rows.Close()
-		for rows.Next() {
-			var taskID string
-			var occurrenceDate time.Time
-			var done bool
-			if err := rows.Scan(&taskID, &occurrenceDate, &done); err != nil {
-				return nil, err
-			}
-			dateKey := occurrenceDate.Format("2006-01-02")
-			if occurrences[taskID] == nil {
-				occurrences[taskID] = map[string]bool{}
-			}
-			occurrences[taskID][dateKey] = done
-		}
-		if err := rows.Err(); err != nil {
-			return nil, err
-		}
+	rows.Close()
+	streaks, err := r.loadStreaks(ctx, recurring, time.Now())
+	if err != nil {
+		return nil, err
 	}
-
-	var res []map[string]any
-	fromDate := truncateDate(from)
-	toDate := truncateDate(to)
-	for _, task := range tasks {
-		if !task.isRecurring {
-			if task.dueDate == nil {
-				continue
-			}
-			res = append(res, map[string]any{
-				"id": task.id, "workspace_id": workspaceID, "goal_id": task.goalID, "title": task.title, "description": task.description, "due_date": task.dueDate, "repeat_rule": task.repeatRule, "value": task.value, "status": task.status, "done_at": task.doneAt, "is_recurring": task.isRecurring, "recurrence_weekdays": task.recurrenceWeekdays, "start_date": task.startDate, "end_date": task.endDate, "timezone": task.timezone, "occurrence_date": task.dueDate.Format("2006-01-02"), "done": task.status == "done",
-			})
-			continue
-		}
-		if len(task.recurrenceWeekdays) == 0 {
-			continue
-		}
-		start := fromDate
-		if task.startDate != nil && task.startDate.After(start) {
-			start = truncateDate(*task.startDate)
-		}
-		end := toDate
-		if task.endDate != nil && task.endDate.Before(end) {
-			end = truncateDate(*task.endDate)
-		}
-		for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
-			if !containsWeekday(task.recurrenceWeekdays, int(date.Weekday())) {
-				continue
-			}
-			dateKey := date.Format("2006-01-02")
-			done := false
-			if occurrences[task.id] != nil {
-				done = occurrences[task.id][dateKey]
-			}
-			res = append(res, map[string]any{
-				"id": task.id, "workspace_id": workspaceID, "goal_id": task.goalID, "title": task.title, "description": task.description, "due_date": task.dueDate, "repeat_rule": task.repeatRule, "value": task.value, "status": task.status, "done_at": task.doneAt, "is_recurring": task.isRecurring, "recurrence_weekdays": task.recurrenceWeekdays, "start_date": task.startDate, "end_date": task.endDate, "timezone": task.timezone, "occurrence_date": dateKey, "done": done,
-			})
-		}
+	for _, instance := range res {
+		streak := streaks[instance["id"].(string)]
+		instance["current_streak"] = streak.Current
+		instance["longest_streak"] = streak.Longest
 	}
 	return res, nil
 }
 
+// occurrenceStatusOf maps a one-off task's status to an occurrence status.
+func occurrenceStatusOf(taskStatus string) string {
+	if taskStatus == "done" {
+		return "done"
+	}
+	return "pending"
+}
+
 func truncateDate(date time.Time) time.Time {
 	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
 }
 
+func int16sToInts(values []int16) []int {
+	if values == nil {
+		return nil
+	}
+	res := make([]int, 0, len(values))
+	for _, value := range values {
+		res = append(res, int(value))
+	}
+	return res
+}
+
 func containsWeekday(weekdays []int, day int) bool {
 	for _, item := range weekdays {
 		if item == day {
@@ -511,10 +1065,24 @@ func containsWeekday(weekdays []int, day int) bool {
 	return false
 }
 
-func (r *Repo) CreateReward(ctx context.Context, workspaceID, title, description string, cost float64, isShared bool, cooldownHours *int, oneTime bool) (string, error) {
+// CreateReward creates a reward with its attachments in one transaction.
+func (r *Repo) CreateReward(ctx context.Context, workspaceID, title, description string, cost float64, isShared bool, cooldownHours *int, oneTime bool, attachmentIDs []string) (string, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
 	var id string
-	err := r.Pool.QueryRow(ctx, `INSERT INTO rewards (workspace_id, title, description, cost, is_shared, cooldown_hours, one_time) VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`, workspaceID, title, description, cost, isShared, cooldownHours, oneTime).Scan(&id)
-	return id, err
+	if err := tx.QueryRow(ctx, `INSERT INTO rewards (workspace_id, title, description, cost, is_shared, cooldown_hours, one_time) VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`, workspaceID, title, description, cost, isShared, cooldownHours, oneTime).Scan(&id); err != nil {
+		return "", err
+	}
+	if len(attachmentIDs) > 0 {
+		if err := setAttachments(ctx, tx, "reward", id, workspaceID, attachmentIDs); err != nil {
+			return "", err
+		}
+	}
+	return id, tx.Commit(ctx)
 }
 
 func (r *Repo) UpdateReward(ctx context.Context, id, workspaceID, title, description string, cost float64, isShared bool, cooldownHours *int, oneTime bool) error {
@@ -539,14 +1107,24 @@ func (r *Repo) DeleteReward(ctx context.Context, id, workspaceID string) error {
 	return nil
 }
 
-func (r *Repo) ListRewards(ctx context.Context, workspaceID string) ([]map[string]any, error) {
-	rows, err := r.Pool.Query(ctx, `SELECT id, title, description, cost, is_shared, cooldown_hours, one_time, created_at, updated_at, deleted_at, version FROM rewards WHERE workspace_id=$1 AND deleted_at IS NULL`, workspaceID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var res []map[string]any
-	for rows.Next() {
+// rewardSorts are the fields rewards can be sorted by.
+var rewardSorts = map[string]sortField{
+	"created_at": {"created_at", "timestamptz"},
+	"updated_at": {"updated_at", "timestamptz"},
+	"title":      {"title", "text"},
+	"cost":       {"cost", "numeric"},
+}
+
+func (r *Repo) ListRewards(ctx context.Context, workspaceID string, page PageRequest) (Page, error) {
+	l := listing{
+		table:       "rewards",
+		columns:     "id, title, description, cost, is_shared, cooldown_hours, one_time, created_at, updated_at, deleted_at, version, " + rewardAttachmentsColumn,
+		where:       "workspace_id=$1 AND deleted_at IS NULL",
+		args:        []any{workspaceID},
+		sorts:       rewardSorts,
+		defaultSort: "created_at",
+	}
+	return r.paginate(ctx, l, page, func(rows pgx.Rows, sortValue *string) (map[string]any, error) {
 		var id, title, description string
 		var cost float64
 		var isShared bool
@@ -555,36 +1133,45 @@ func (r *Repo) ListRewards(ctx context.Context, workspaceID string) ([]map[strin
 		var createdAt, updatedAt time.Time
 		var deletedAt *time.Time
 		var version int
-		if err := rows.Scan(&id, &title, &description, &cost, &isShared, &cooldownHours, &oneTime, &createdAt, &updatedAt, &deletedAt, &version); err != nil {
+		var attachmentIDs []string
+		if err := rows.Scan(&id, &title, &description, &cost, &isShared, &cooldownHours, &oneTime, &createdAt, &updatedAt, &deletedAt, &version, &attachmentIDs, sortValue); err != nil {
 			return nil, err
 		}
-		res = append(res, map[string]any{
-			"id": id, "workspace_id": workspaceID, "title": title, "description": description, "cost": cost, "is_shared": isShared, "cooldown_hours": cooldownHours, "one_time": oneTime, "created_at": createdAt, "updated_at": updatedAt, "deleted_at": deletedAt, "version": version,
-		})
-	}
-	return res, rows.Err()
+		return map[string]any{
+			"id": id, "workspace_id": workspaceID, "title": title, "description": description, "cost": cost, "is_shared": isShared, "cooldown_hours": cooldownHours, "one_time": oneTime, "created_at": createdAt, "updated_at": updatedAt, "deleted_at": deletedAt, "version": version, "attachment_ids": attachmentIDs,
+		}, nil
+	})
 }
 
-func (r *Repo) ListRewardPurchases(ctx context.Context, workspaceID, userID string) ([]map[string]any, error) {
-	rows, err := r.Pool.Query(ctx, `SELECT id, reward_id, cost, purchased_at, note FROM reward_purchases WHERE workspace_id=$1 AND user_id=$2 ORDER BY purchased_at DESC`, workspaceID, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var res []map[string]any
-	for rows.Next() {
+// purchaseSorts are the fields reward purchases can be sorted by.
+var purchaseSorts = map[string]sortField{
+	"purchased_at": {"purchased_at", "timestamptz"},
+	"cost":         {"cost", "numeric"},
+}
+
+// ListRewardPurchases returns a page of the user's purchases in the
+// workspace, newest first unless sorted otherwise.
+func (r *Repo) ListRewardPurchases(ctx context.Context, workspaceID, userID string, page PageRequest) (Page, error) {
+	l := listing{
+		table:       "reward_purchases",
+		columns:     "id, reward_id, cost, purchased_at, note",
+		where:       "workspace_id=$1 AND user_id=$2",
+		args:        []any{workspaceID, userID},
+		sorts:       purchaseSorts,
+		defaultSort: "-purchased_at",
+	}
+	return r.paginate(ctx, l, page, func(rows pgx.Rows, sortValue *string) (map[string]any, error) {
 		var id, rewardID string
 		var cost float64
 		var purchasedAt time.Time
 		var note *string
-		if err := rows.Scan(&id, &rewardID, &cost, &purchasedAt, &note); err != nil {
+		if err := rows.Scan(&id, &rewardID, &cost, &purchasedAt, &note, sortValue); err != nil {
 			return nil, err
 		}
-		res = append(res, map[string]any{
+		return map[string]any{
 			"id": id, "workspace_id": workspaceID, "reward_id": rewardID, "user_id": userID, "cost": cost, "purchased_at": purchasedAt, "note": note,
-		})
-	}
-	return res, rows.Err()
+		}, nil
+	})
 }
 
 func (r *Repo) BuyReward(ctx context.Context, rewardID, workspaceID, userID string) (float64, error) {
@@ -596,7 +1183,8 @@ func (r *Repo) BuyReward(ctx context.Context, rewardID, workspaceID, userID stri
 
 	var cost float64
 	var oneTime bool
-	if err := tx.QueryRow(ctx, `SELECT cost, one_time FROM rewards WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, rewardID, workspaceID).Scan(&cost, &oneTime); err != nil {
+	var title string
+	if err := tx.QueryRow(ctx, `SELECT cost, one_time, title FROM rewards WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, rewardID, workspaceID).Scan(&cost, &oneTime, &title); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, ErrNotFound
 		}
@@ -619,24 +1207,45 @@ func (r *Repo) BuyReward(ctx context.Context, rewardID, workspaceID, userID stri
 		}
 		return 0, err
 	}
-	if _, err := tx.Exec(ctx, `INSERT INTO reward_purchases (workspace_id, reward_id, user_id, cost)
-		VALUES ($1,$2,$3,$4)`, workspaceID, rewardID, userID, cost); err != nil {
+	var purchaseID string
+	if err := tx.QueryRow(ctx, `INSERT INTO reward_purchases (workspace_id, reward_id, user_id, cost)
+		VALUES ($1,$2,$3,$4) RETURNING id`, workspaceID, rewardID, userID, cost).Scan(&purchaseID); err != nil {
 		return 0, err
 	}
 	if _, err := tx.Exec(ctx, `INSERT INTO transactions (workspace_id, user_id, type, amount, reason, entity_type, entity_id)
 		VALUES ($1,$2,'spend',$3,'reward purchased','reward',$4)`, workspaceID, userID, cost, rewardID); err != nil {
 		return 0, err
 	}
+	if err := enqueueWorkspaceEvent(ctx, tx, workspaceID, userID, "reward_purchased", "reward_purchased:"+purchaseID, map[string]any{
+		"title": "Reward purchased", "body": title, "reward_id": rewardID, "workspace_id": workspaceID, "user_id": userID, "cost": cost,
+	}); err != nil {
+		return 0, err
+	}
 	if err := tx.Commit(ctx); err != nil {
 		return 0, err
 	}
 	return cost, nil
 }
 
-func (r *Repo) CreateAchievement(ctx context.Context, workspaceID, title, description string, imageURL *string) (string, error) {
+// CreateAchievement creates an achievement with its attachments in one
+// transaction.
+func (r *Repo) CreateAchievement(ctx context.Context, workspaceID, title, description string, imageURL *string, attachmentIDs []string) (string, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
 	var id string
-	err := r.Pool.QueryRow(ctx, `INSERT INTO achievements (workspace_id, title, description, image_url) VALUES ($1,$2,$3,$4) RETURNING id`, workspaceID, title, description, imageURL).Scan(&id)
-	return id, err
+	if err := tx.QueryRow(ctx, `INSERT INTO achievements (workspace_id, title, description, image_url) VALUES ($1,$2,$3,$4) RETURNING id`, workspaceID, title, description, imageURL).Scan(&id); err != nil {
+		return "", err
+	}
+	if len(attachmentIDs) > 0 {
+		if err := setAttachments(ctx, tx, "achievement", id, workspaceID, attachmentIDs); err != nil {
+			return "", err
+		}
+	}
+	return id, tx.Commit(ctx)
 }
 
 func (r *Repo) UpdateAchievement(ctx context.Context, id, workspaceID, title, description string, imageURL *string, achievedAt *time.Time) error {
@@ -661,27 +1270,37 @@ func (r *Repo) DeleteAchievement(ctx context.Context, id, workspaceID string) er
 	return nil
 }
 
-func (r *Repo) ListAchievements(ctx context.Context, workspaceID string) ([]map[string]any, error) {
-	rows, err := r.Pool.Query(ctx, `SELECT id, title, description, image_url, achieved_at, created_at, updated_at, deleted_at, version FROM achievements WHERE workspace_id=$1 AND deleted_at IS NULL`, workspaceID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var res []map[string]any
-	for rows.Next() {
+// achievementSorts are the fields achievements can be sorted by.
+var achievementSorts = map[string]sortField{
+	"created_at":  {"created_at", "timestamptz"},
+	"updated_at":  {"updated_at", "timestamptz"},
+	"title":       {"title", "text"},
+	"achieved_at": {"coalesce(achieved_at, 'infinity'::timestamptz)", "timestamptz"},
+}
+
+func (r *Repo) ListAchievements(ctx context.Context, workspaceID string, page PageRequest) (Page, error) {
+	l := listing{
+		table:       "achievements",
+		columns:     "id, title, description, image_url, achieved_at, created_at, updated_at, deleted_at, version, " + achievementAttachmentsColumn,
+		where:       "workspace_id=$1 AND deleted_at IS NULL",
+		args:        []any{workspaceID},
+		sorts:       achievementSorts,
+		defaultSort: "created_at",
+	}
+	return r.paginate(ctx, l, page, func(rows pgx.Rows, sortValue *string) (map[string]any, error) {
 		var id, title, description string
 		var imageURL *string
 		var achievedAt, deletedAt *time.Time
 		var createdAt, updatedAt time.Time
 		var version int
-		if err := rows.Scan(&id, &title, &description, &imageURL, &achievedAt, &createdAt, &updatedAt, &deletedAt, &version); err != nil {
+		var attachmentIDs []string
+		if err := rows.Scan(&id, &title, &description, &imageURL, &achievedAt, &createdAt, &updatedAt, &deletedAt, &version, &attachmentIDs, sortValue); err != nil {
 			return nil, err
 		}
-		res = append(res, map[string]any{
-			"id": id, "workspace_id": workspaceID, "title": title, "description": description, "image_url": imageURL, "achieved_at": achievedAt, "created_at": createdAt, "updated_at": updatedAt, "deleted_at": deletedAt, "version": version,
-		})
-	}
-	return res, rows.Err()
+		return map[string]any{
+			"id": id, "workspace_id": workspaceID, "title": title, "description": description, "image_url": imageURL, "achieved_at": achievedAt, "created_at": createdAt, "updated_at": updatedAt, "deleted_at": deletedAt, "version": version, "attachment_ids": attachmentIDs,
+		}, nil
+	})
 }
 
 func (r *Repo) ListSyncChanges(ctx context.Context, workspaceID string, since, until time.Time) (map[string][]map[string]any, error) {
@@ -690,26 +1309,45 @@ func (r *Repo) ListSyncChanges(ctx context.Context, workspaceID string, since, u
 	if err != nil {
 		return nil, err
 	}
-	tasks, err := r.queryEntity(ctx, `SELECT id, goal_id, title, description, due_date, repeat_rule, value, status, done_at, created_at, updated_at, deleted_at, version, is_recurring, recurrence_weekdays, start_date, end_date, timezone
+	tasks, err := r.queryEntity(ctx, `SELECT id, goal_id, title, description, due_date, repeat_rule, value, status, done_at, created_at, updated_at, deleted_at, version, is_recurring, recurrence_weekdays, start_date, end_date, timezone, assignee_only, require_checklist, priority, streak_bonus_percent, target, unit, max_payout_percent, progress, penalty, pay_per_hour, postpone_decay_percent, postponed_count, `+taskAssigneesColumn+`, `+taskTagsColumn+`, `+taskBlockedByColumn+`, `+taskBlocksColumn+`, `+taskAttachmentsColumn+`
 		FROM tasks WHERE workspace_id=$1 AND ((updated_at > $2 AND updated_at <= $3) OR (deleted_at IS NOT NULL AND deleted_at > $2 AND deleted_at <= $3))`, workspaceID, since, until)
 	if err != nil {
 		return nil, err
 	}
-	rewards, err := r.queryEntity(ctx, `SELECT id, title, description, cost, is_shared, cooldown_hours, one_time, created_at, updated_at, deleted_at, version
+	checklistItems, err := r.queryEntity(ctx, `SELECT task_checklist_items.id, task_id, task_checklist_items.title, position, done, done_at, task_checklist_items.value, task_checklist_items.created_at, task_checklist_items.updated_at, task_checklist_items.deleted_at, task_checklist_items.version
+		FROM task_checklist_items JOIN tasks ON tasks.id = task_checklist_items.task_id
+		WHERE tasks.workspace_id=$1 AND ((task_checklist_items.updated_at > $2 AND task_checklist_items.updated_at <= $3) OR (task_checklist_items.deleted_at IS NOT NULL AND task_checklist_items.deleted_at > $2 AND task_checklist_items.deleted_at <= $3))`, workspaceID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := r.queryEntity(ctx, `SELECT id, name, color, created_at, updated_at, deleted_at, version
+		FROM tags WHERE workspace_id=$1 AND ((updated_at > $2 AND updated_at <= $3) OR (deleted_at IS NOT NULL AND deleted_at > $2 AND deleted_at <= $3))`, workspaceID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	rewards, err := r.queryEntity(ctx, `SELECT id, title, description, cost, is_shared, cooldown_hours, one_time, created_at, updated_at, deleted_at, version, `+rewardAttachmentsColumn+`
 		FROM rewards WHERE workspace_id=$1 AND ((updated_at > $2 AND updated_at <= $3) OR (deleted_at IS NOT NULL AND deleted_at > $2 AND deleted_at <= $3))`, workspaceID, since, until)
 	if err != nil {
 		return nil, err
 	}
-	achievements, err := r.queryEntity(ctx, `SELECT id, title, description, image_url, achieved_at, created_at, updated_at, deleted_at, version
+	achievements, err := r.queryEntity(ctx, `SELECT id, title, description, image_url, achieved_at, created_at, updated_at, deleted_at, version, `+achievementAttachmentsColumn+`
 		FROM achievements WHERE workspace_id=$1 AND ((updated_at > $2 AND updated_at <= $3) OR (deleted_at IS NOT NULL AND deleted_at > $2 AND deleted_at <= $3))`, workspaceID, since, until)
 	if err != nil {
 		return nil, err
 	}
+	comments, err := r.queryEntity(ctx, `SELECT id, entity_type, entity_id, author_id, body, mention_ids, created_at, updated_at, edited_at, deleted_at, version
+		FROM comments WHERE workspace_id=$1 AND ((updated_at > $2 AND updated_at <= $3) OR (deleted_at IS NOT NULL AND deleted_at > $2 AND deleted_at <= $3))`, workspaceID, since, until)
+	if err != nil {
+		return nil, err
+	}
 	return map[string][]map[string]any{
-		"goals":        goals,
-		"tasks":        tasks,
-		"rewards":      rewards,
-		"achievements": achievements,
+		"goals":           goals,
+		"tasks":           tasks,
+		"checklist_items": checklistItems,
+		"tags":            tags,
+		"rewards":         rewards,
+		"achievements":    achievements,
+		"comments":        comments,
 	}, nil
 }
 
@@ -746,9 +1384,13 @@ func (r *Repo) UpsertEntity(ctx context.Context, table string, payload map[strin
 			payload["id"], payload["workspace_id"], payload["title"], payload["description"], payload["period"], payload["start_date"], payload["end_date"], payload["status"], payload["created_at"], payload["updated_at"], payload["deleted_at"], payload["version"])
 		return err
 	case "tasks":
+		// As with UpdateTask, a one-off task's due date and a postponed task's
+		// value only change through RescheduleTask, so pushes keep the stored ones.
 		_, err := r.Pool.Exec(ctx, `INSERT INTO tasks (id, workspace_id, goal_id, title, description, due_date, repeat_rule, value, status, done_at, created_at, updated_at, deleted_at, version, is_recurring, recurrence_weekdays, start_date, end_date, timezone)
 			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,COALESCE($11, now()),COALESCE($12, now()),$13,COALESCE($14, 1),$15,$16,$17,$18,$19)
-			ON CONFLICT (id) DO UPDATE SET goal_id=EXCLUDED.goal_id, title=EXCLUDED.title, description=EXCLUDED.description, due_date=EXCLUDED.due_date, repeat_rule=EXCLUDED.repeat_rule, value=EXCLUDED.value, status=EXCLUDED.status, done_at=EXCLUDED.done_at, updated_at=EXCLUDED.updated_at, deleted_at=EXCLUDED.deleted_at, version=EXCLUDED.version, is_recurring=EXCLUDED.is_recurring, recurrence_weekdays=EXCLUDED.recurrence_weekdays, start_date=EXCLUDED.start_date, end_date=EXCLUDED.end_date, timezone=EXCLUDED.timezone
+			ON CONFLICT (id) DO UPDATE SET goal_id=EXCLUDED.goal_id, title=EXCLUDED.title, description=EXCLUDED.description,
+			due_date=CASE WHEN NOT tasks.is_recurring AND NOT EXCLUDED.is_recurring THEN tasks.due_date ELSE EXCLUDED.due_date END, repeat_rule=EXCLUDED.repeat_rule,
+			value=CASE WHEN tasks.postponed_count > 0 THEN tasks.value ELSE EXCLUDED.value END, status=EXCLUDED.status, done_at=EXCLUDED.done_at, updated_at=EXCLUDED.updated_at, deleted_at=EXCLUDED.deleted_at, version=EXCLUDED.version, is_recurring=EXCLUDED.is_recurring, recurrence_weekdays=EXCLUDED.recurrence_weekdays, start_date=EXCLUDED.start_date, end_date=EXCLUDED.end_date, timezone=EXCLUDED.timezone
 			WHERE tasks.updated_at < EXCLUDED.updated_at OR (tasks.updated_at = EXCLUDED.updated_at AND tasks.version < EXCLUDED.version)`,
 			payload["id"], payload["workspace_id"], payload["goal_id"], payload["title"], payload["description"], payload["due_date"], payload["repeat_rule"], payload["value"], payload["status"], payload["done_at"], payload["created_at"], payload["updated_at"], payload["deleted_at"], payload["version"], payload["is_recurring"], payload["recurrence_weekdays"], payload["start_date"], payload["end_date"], payload["timezone"])
 		return err
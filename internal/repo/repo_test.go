@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,7 +13,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func setupTestRepo(t *testing.T) (*Repo, func()) {
+func setupTestRepo(t testing.TB) (*Repo, func()) {
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
 		t.Skip("DATABASE_URL not set")
@@ -52,13 +53,35 @@ func createTestTables(ctx context.Context, pool *pgxpool.Pool) error {
 		`CREATE TABLE users (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), email text, password_hash text, created_at timestamptz DEFAULT now(), updated_at timestamptz DEFAULT now())`,
 		`CREATE TABLE workspaces (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), name text, type text, created_at timestamptz DEFAULT now(), updated_at timestamptz DEFAULT now())`,
 		`CREATE TABLE workspace_members (workspace_id uuid, user_id uuid, role text, permissions jsonb DEFAULT '{}'::jsonb, created_at timestamptz DEFAULT now())`,
-		`CREATE TABLE tasks (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, title text, description text DEFAULT '', value numeric(10,2) DEFAULT 0, status text, done_at timestamptz, deleted_at timestamptz, updated_at timestamptz DEFAULT now(), version int DEFAULT 1, is_recurring boolean DEFAULT false, recurrence_weekdays smallint[] NULL, start_date date NULL, end_date date NULL, timezone text NULL)`,
-		`CREATE TABLE task_occurrences (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), task_id uuid, occurrence_date date NOT NULL, done boolean DEFAULT false, completed_at timestamptz NULL, created_at timestamptz DEFAULT now())`,
-		`CREATE TABLE rewards (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, title text, description text DEFAULT '', cost numeric(10,2), deleted_at timestamptz, updated_at timestamptz DEFAULT now(), version int DEFAULT 1, one_time boolean DEFAULT false)`,
+		`CREATE TABLE tasks (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, title text, description text DEFAULT '', value numeric(10,2) DEFAULT 0, status text, done_at timestamptz, created_at timestamptz DEFAULT now(), deleted_at timestamptz, updated_at timestamptz DEFAULT now(), version int DEFAULT 1, is_recurring boolean DEFAULT false, recurrence_weekdays smallint[] NULL, start_date date NULL, end_date date NULL, timezone text NULL, assignee_only boolean DEFAULT false, require_checklist boolean DEFAULT false, priority smallint DEFAULT 0, goal_id uuid, due_date date, streak_bonus_percent numeric(5,2) DEFAULT 0, target numeric(12,2), unit text, max_payout_percent numeric(6,2) DEFAULT 100, progress numeric(12,2) DEFAULT 0, progress_paid numeric(10,2) DEFAULT 0, penalty numeric(10,2) DEFAULT 0, penalty_since timestamptz, pay_per_hour boolean DEFAULT false, detached_goal_id uuid, postpone_decay_percent numeric(5,2) DEFAULT 0, postponed_count int DEFAULT 0, search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('russian', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(title, '')), 'A') || setweight(to_tsvector('russian', coalesce(description, '')), 'B') || setweight(to_tsvector('english', coalesce(description, '')), 'B')) STORED)`,
+		`CREATE TABLE tags (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, name text, color text, created_at timestamptz DEFAULT now(), updated_at timestamptz DEFAULT now(), deleted_at timestamptz, version int DEFAULT 1)`,
+		`CREATE TABLE task_tags (task_id uuid, tag_id uuid, PRIMARY KEY (task_id, tag_id))`,
+		`CREATE TABLE task_checklist_items (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), task_id uuid, title text, position int DEFAULT 0, done boolean DEFAULT false, done_at timestamptz, value numeric(10,2) DEFAULT 0, created_at timestamptz DEFAULT now(), updated_at timestamptz DEFAULT now(), deleted_at timestamptz, version int DEFAULT 1)`,
+		`CREATE TABLE task_dependencies (task_id uuid, blocked_by_id uuid, created_at timestamptz DEFAULT now(), PRIMARY KEY (task_id, blocked_by_id))`,
+		`CREATE TABLE task_assignees (task_id uuid, user_id uuid, created_at timestamptz DEFAULT now(), PRIMARY KEY (task_id, user_id))`,
+		`CREATE TABLE task_occurrences (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), task_id uuid, occurrence_date date NOT NULL, status text NOT NULL DEFAULT 'pending', skip_reason text NULL, skipped_by uuid NULL, completed_at timestamptz NULL, created_at timestamptz DEFAULT now(), progress numeric(12,2) DEFAULT 0, paid numeric(10,2) DEFAULT 0, UNIQUE (task_id, occurrence_date))`,
+		`CREATE TABLE task_reschedules (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), task_id uuid, from_date date, to_date date, snooze text, value_before numeric(10,2), value_after numeric(10,2), rescheduled_by uuid, created_at timestamptz DEFAULT now())`,
+		`CREATE TABLE calendar_feeds (workspace_id uuid, user_id uuid, token_hash text UNIQUE, created_at timestamptz DEFAULT now(), last_used_at timestamptz, PRIMARY KEY (workspace_id, user_id))`,
+		`CREATE TABLE ical_imports (workspace_id uuid, uid text, task_id uuid, imported_by uuid, imported_at timestamptz DEFAULT now(), PRIMARY KEY (workspace_id, uid))`,
+		`CREATE TABLE workspace_vacations (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, start_date date, end_date date, reason text DEFAULT '', created_by uuid, created_at timestamptz DEFAULT now())`,
+		`CREATE TABLE comments (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, entity_type text, entity_id uuid, author_id uuid, body text, mention_ids uuid[] DEFAULT '{}', created_at timestamptz DEFAULT now(), updated_at timestamptz DEFAULT now(), edited_at timestamptz, deleted_at timestamptz, version int DEFAULT 1)`,
+		`CREATE TABLE comment_edits (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), comment_id uuid, body text, edited_by uuid, edited_at timestamptz DEFAULT now())`,
+		`CREATE TABLE uploads (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, uploaded_by uuid, storage_key text UNIQUE, content_type text, size_bytes bigint, width int, height int, original_name text DEFAULT '', created_at timestamptz DEFAULT now())`,
+		`CREATE TABLE attachments (upload_id uuid REFERENCES uploads(id) ON DELETE CASCADE, entity_type text, entity_id uuid, created_at timestamptz DEFAULT now(), PRIMARY KEY (entity_type, entity_id, upload_id))`,
+		`CREATE TABLE rewards (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, title text, description text DEFAULT '', cost numeric(10,2), deleted_at timestamptz, updated_at timestamptz DEFAULT now(), version int DEFAULT 1, one_time boolean DEFAULT false, search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('russian', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(title, '')), 'A') || setweight(to_tsvector('russian', coalesce(description, '')), 'B') || setweight(to_tsvector('english', coalesce(description, '')), 'B')) STORED)`,
+		`CREATE TABLE achievements (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, title text, description text DEFAULT '', image_url text, achieved_at timestamptz, created_at timestamptz DEFAULT now(), updated_at timestamptz DEFAULT now(), deleted_at timestamptz, version int DEFAULT 1, search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('russian', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(title, '')), 'A') || setweight(to_tsvector('russian', coalesce(description, '')), 'B') || setweight(to_tsvector('english', coalesce(description, '')), 'B')) STORED)`,
 		`CREATE TABLE reward_purchases (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, reward_id uuid, user_id uuid, cost numeric(10,2), purchased_at timestamptz DEFAULT now())`,
-		`CREATE TABLE transactions (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, user_id uuid, type text, amount numeric(10,2), reason text, entity_type text, entity_id uuid, created_at timestamptz DEFAULT now())`,
+		`CREATE TABLE transactions (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, user_id uuid, type text, amount numeric(10,2), reason text, entity_type text, entity_id uuid, occurrence_date date NULL, reverses_transaction_id uuid NULL, created_at timestamptz DEFAULT now())`,
+		`CREATE UNIQUE INDEX idx_transactions_penalty_once ON transactions (entity_id, occurrence_date) WHERE type = 'penalty'`,
 		`CREATE TABLE workspace_balance (workspace_id uuid PRIMARY KEY, balance numeric(10,2) DEFAULT 0, updated_at timestamptz DEFAULT now())`,
-		`CREATE TABLE goals (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, title text, description text DEFAULT '', period text DEFAULT 'day', status text DEFAULT 'active', updated_at timestamptz DEFAULT now(), deleted_at timestamptz, version int DEFAULT 1)`,
+		`CREATE TABLE job_runs (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), job text NOT NULL, scheduled_at timestamptz NOT NULL, started_at timestamptz DEFAULT now(), finished_at timestamptz, status text DEFAULT 'running', detail text DEFAULT '', UNIQUE (job, scheduled_at))`,
+		`CREATE TABLE task_reminders (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), task_id uuid, at_time time, minutes_before int, created_at timestamptz DEFAULT now())`,
+		`CREATE TABLE notification_settings (user_id uuid PRIMARY KEY, timezone text, quiet_hours_start time, quiet_hours_end time, email_enabled boolean DEFAULT false, webhook_url text, updated_at timestamptz DEFAULT now())`,
+		`CREATE TABLE time_entries (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, task_id uuid, user_id uuid, occurrence_date date, started_at timestamptz NOT NULL, ended_at timestamptz, note text DEFAULT '', source text DEFAULT 'manual', created_at timestamptz DEFAULT now(), updated_at timestamptz DEFAULT now())`,
+		`CREATE UNIQUE INDEX ON time_entries (task_id, user_id) WHERE ended_at IS NULL`,
+		`CREATE TABLE push_subscriptions (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), user_id uuid, endpoint text UNIQUE, p256dh text, auth text, user_agent text, created_at timestamptz DEFAULT now(), last_used_at timestamptz)`,
+		`CREATE TABLE notification_outbox (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), user_id uuid, channel text, kind text, dedupe_key text, payload jsonb DEFAULT '{}', status text DEFAULT 'pending', attempts int DEFAULT 0, next_attempt_at timestamptz DEFAULT now(), last_error text, created_at timestamptz DEFAULT now(), sent_at timestamptz, UNIQUE (user_id, channel, dedupe_key))`,
+		`CREATE TABLE goals (id uuid PRIMARY KEY DEFAULT gen_random_uuid(), workspace_id uuid, title text, description text DEFAULT '', period text DEFAULT 'day', start_date date, end_date date, status text DEFAULT 'active', created_at timestamptz DEFAULT now(), updated_at timestamptz DEFAULT now(), deleted_at timestamptz, version int DEFAULT 1, search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('russian', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(title, '')), 'A') || setweight(to_tsvector('russian', coalesce(description, '')), 'B') || setweight(to_tsvector('english', coalesce(description, '')), 'B')) STORED)`,
 	}
 	for _, query := range queries {
 		if _, err := pool.Exec(ctx, query); err != nil {
@@ -92,11 +115,11 @@ func TestCompleteTaskIdempotent(t *testing.T) {
 		t.Fatalf("task: %v", err)
 	}
 
-	value, completed, err := repo.CompleteTask(ctx, taskID, workspaceID, userID, nil)
+	value, completed, err := repo.CompleteTask(ctx, taskID, workspaceID, userID, nil, false)
 	if err != nil || !completed || value != 5 {
 		t.Fatalf("first complete failed: value=%v completed=%v err=%v", value, completed, err)
 	}
-	value, completed, err = repo.CompleteTask(ctx, taskID, workspaceID, userID, nil)
+	value, completed, err = repo.CompleteTask(ctx, taskID, workspaceID, userID, nil, false)
 	if err != nil || completed || value != 0 {
 		t.Fatalf("second complete should be noop: value=%v completed=%v err=%v", value, completed, err)
 	}
@@ -109,6 +132,1702 @@ func TestCompleteTaskIdempotent(t *testing.T) {
 	}
 }
 
+func TestCompleteTaskAssigneeOnly(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'shared') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	var assigneeID, otherID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('as@b.com', 'x') RETURNING id`).Scan(&assigneeID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('ot@b.com', 'x') RETURNING id`).Scan(&otherID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'member'), ($1, $3, 'member')`, workspaceID, assigneeID, otherID); err != nil {
+		t.Fatalf("member: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_balance (workspace_id, balance) VALUES ($1, 0)`, workspaceID); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	var taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, assignee_only) VALUES ($1, 'Chore', 3, 'open', true) RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	if err := repo.SetTaskAssignees(ctx, taskID, workspaceID, []string{assigneeID}); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+
+	if _, _, err := repo.CompleteTask(ctx, taskID, workspaceID, otherID, nil, false); !errors.Is(err, ErrNotAssignee) {
+		t.Fatalf("expected not assignee, got %v", err)
+	}
+	if _, completed, err := repo.CompleteTask(ctx, taskID, workspaceID, assigneeID, nil, false); err != nil || !completed {
+		t.Fatalf("assignee complete failed: completed=%v err=%v", completed, err)
+	}
+}
+
+func TestChecklistGatesCompletion(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	var userID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('cl@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_balance (workspace_id, balance) VALUES ($1, 0)`, workspaceID); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	var taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, require_checklist) VALUES ($1, 'Garage', 10, 'open', true) RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	itemID, err := repo.CreateChecklistItem(ctx, taskID, workspaceID, "Sweep", 2)
+	if err != nil {
+		t.Fatalf("item: %v", err)
+	}
+
+	if _, _, err := repo.CompleteTask(ctx, taskID, workspaceID, userID, nil, false); !errors.Is(err, ErrChecklistOpen) {
+		t.Fatalf("expected checklist open, got %v", err)
+	}
+	amount, changed, err := repo.ToggleChecklistItem(ctx, itemID, taskID, workspaceID, userID, true)
+	if err != nil || !changed || amount != 2 {
+		t.Fatalf("toggle failed: amount=%v changed=%v err=%v", amount, changed, err)
+	}
+	if _, completed, err := repo.CompleteTask(ctx, taskID, workspaceID, userID, nil, false); err != nil || !completed {
+		t.Fatalf("complete failed: completed=%v err=%v", completed, err)
+	}
+	var balance float64
+	if err := repo.Pool.QueryRow(ctx, `SELECT balance FROM workspace_balance WHERE workspace_id=$1`, workspaceID).Scan(&balance); err != nil {
+		t.Fatalf("balance read: %v", err)
+	}
+	if balance != 12 {
+		t.Fatalf("expected balance 12, got %v", balance)
+	}
+
+	// A recurring task's checklist starts over after each occurrence.
+	var recurringID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, require_checklist, is_recurring, recurrence_weekdays, start_date)
+		VALUES ($1, 'Dishes', 1, 'open', true, true, '{0,1,2,3,4,5,6}', '2024-01-01') RETURNING id`, workspaceID).Scan(&recurringID); err != nil {
+		t.Fatalf("recurring task: %v", err)
+	}
+	stepID, err := repo.CreateChecklistItem(ctx, recurringID, workspaceID, "Rinse", 2)
+	if err != nil {
+		t.Fatalf("item: %v", err)
+	}
+	if _, _, err := repo.ToggleChecklistItem(ctx, stepID, recurringID, workspaceID, userID, true); err != nil {
+		t.Fatalf("toggle: %v", err)
+	}
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, completed, err := repo.CompleteTask(ctx, recurringID, workspaceID, userID, &first, false); err != nil || !completed {
+		t.Fatalf("complete occurrence failed: completed=%v err=%v", completed, err)
+	}
+	second := first.AddDate(0, 0, 1)
+	if _, _, err := repo.CompleteTask(ctx, recurringID, workspaceID, userID, &second, false); !errors.Is(err, ErrChecklistOpen) {
+		t.Fatalf("expected the next occurrence to need the checklist again, got %v", err)
+	}
+	// Unchecking on the next occurrence reverses only the earn made for it.
+	if _, _, err := repo.ToggleChecklistItem(ctx, stepID, recurringID, workspaceID, userID, true); err != nil {
+		t.Fatalf("toggle again: %v", err)
+	}
+	amount, _, err = repo.ToggleChecklistItem(ctx, stepID, recurringID, workspaceID, userID, false)
+	if err != nil || amount != -2 {
+		t.Fatalf("expected one reversal of 2, got %v err=%v", amount, err)
+	}
+	var reversals int
+	if err := repo.Pool.QueryRow(ctx, `SELECT count(*) FROM transactions WHERE entity_id=$1 AND type='reversal'`, stepID).Scan(&reversals); err != nil || reversals != 1 {
+		t.Fatalf("expected exactly one reversal, got %d err=%v", reversals, err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `SELECT balance FROM workspace_balance WHERE workspace_id=$1`, workspaceID).Scan(&balance); err != nil || balance != 15 {
+		t.Fatalf("expected balance 15 after the first occurrence, got %v err=%v", balance, err)
+	}
+}
+
+func TestUncompleteTaskReversesEarn(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	var userID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('u@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_balance (workspace_id, balance) VALUES ($1, 0)`, workspaceID); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	var taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status) VALUES ($1, 'Task', 5, 'open') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	if _, _, err := repo.CompleteTask(ctx, taskID, workspaceID, userID, nil, false); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `UPDATE workspace_balance SET balance = 2 WHERE workspace_id=$1`, workspaceID); err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+
+	if _, _, err := repo.UncompleteTask(ctx, taskID, workspaceID, userID, nil, false); !errors.Is(err, ErrBalanceSpent) {
+		t.Fatalf("expected balance spent, got %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `UPDATE tasks SET assignee_only=true WHERE id=$1`, taskID); err != nil {
+		t.Fatalf("assignee only: %v", err)
+	}
+	if _, _, err := repo.UncompleteTask(ctx, taskID, workspaceID, userID, nil, false); !errors.Is(err, ErrNotAssignee) {
+		t.Fatalf("expected ErrNotAssignee, got %v", err)
+	}
+	value, uncompleted, err := repo.UncompleteTask(ctx, taskID, workspaceID, userID, nil, true)
+	if err != nil || !uncompleted || value != 5 {
+		t.Fatalf("forced uncomplete failed: value=%v uncompleted=%v err=%v", value, uncompleted, err)
+	}
+	_, uncompleted, err = repo.UncompleteTask(ctx, taskID, workspaceID, userID, nil, true)
+	if err != nil || uncompleted {
+		t.Fatalf("second uncomplete should be noop: uncompleted=%v err=%v", uncompleted, err)
+	}
+	var reversals int
+	if err := repo.Pool.QueryRow(ctx, `SELECT count(*) FROM transactions WHERE type='reversal' AND reverses_transaction_id IS NOT NULL`).Scan(&reversals); err != nil {
+		t.Fatalf("reversal read: %v", err)
+	}
+	if reversals != 1 {
+		t.Fatalf("expected 1 reversal, got %d", reversals)
+	}
+}
+
+func TestLogTaskProgressPaysProportionally(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	var userID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('p@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_balance (workspace_id, balance) VALUES ($1, 0)`, workspaceID); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	var taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, target, max_payout_percent) VALUES ($1, 'Read', 10, 'open', 30, 150) RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+
+	progress, earned, done, err := repo.LogTaskProgress(ctx, taskID, workspaceID, userID, nil, 15)
+	if err != nil || progress != 15 || earned != 5 || done {
+		t.Fatalf("half progress: progress=%v earned=%v done=%v err=%v", progress, earned, done, err)
+	}
+	progress, earned, done, err = repo.LogTaskProgress(ctx, taskID, workspaceID, userID, nil, 60)
+	if err != nil || progress != 75 || earned != 10 || !done {
+		t.Fatalf("capped overachievement: progress=%v earned=%v done=%v err=%v", progress, earned, done, err)
+	}
+	var balance float64
+	if err := repo.Pool.QueryRow(ctx, `SELECT balance FROM workspace_balance WHERE workspace_id=$1`, workspaceID).Scan(&balance); err != nil {
+		t.Fatalf("balance read: %v", err)
+	}
+	if balance != 15 {
+		t.Fatalf("expected balance 15, got %v", balance)
+	}
+	if _, _, err := repo.CompleteTask(ctx, taskID, workspaceID, userID, nil, false); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+}
+
+func TestLogTaskProgressCompletesThroughGates(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, userID, otherID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'family') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('pg@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('pg2@b.com', 'x') RETURNING id`).Scan(&otherID); err != nil {
+		t.Fatalf("other user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'owner'), ($1, $3, 'member')`, workspaceID, userID, otherID); err != nil {
+		t.Fatalf("members: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO notification_settings (user_id, email_enabled) VALUES ($1, true)`, otherID); err != nil {
+		t.Fatalf("settings: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_balance (workspace_id, balance) VALUES ($1, 0)`, workspaceID); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	var taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, target, require_checklist) VALUES ($1, 'Read', 10, 'open', 20, true) RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	itemID, err := repo.CreateChecklistItem(ctx, taskID, workspaceID, "Notes", 0)
+	if err != nil {
+		t.Fatalf("item: %v", err)
+	}
+
+	// The open checklist holds completion back but keeps the progress.
+	progress, earned, done, err := repo.LogTaskProgress(ctx, taskID, workspaceID, userID, nil, 20)
+	if err != nil || progress != 20 || earned != 10 || done {
+		t.Fatalf("gated progress: progress=%v earned=%v done=%v err=%v", progress, earned, done, err)
+	}
+	if _, _, err := repo.ToggleChecklistItem(ctx, itemID, taskID, workspaceID, userID, true); err != nil {
+		t.Fatalf("toggle: %v", err)
+	}
+	if _, _, done, err = repo.LogTaskProgress(ctx, taskID, workspaceID, userID, nil, 1); err != nil || !done {
+		t.Fatalf("expected completion, done=%v err=%v", done, err)
+	}
+	var events int
+	if err := repo.Pool.QueryRow(ctx, `SELECT count(*) FROM notification_outbox WHERE user_id=$1 AND kind='task_completed'`, otherID).Scan(&events); err != nil {
+		t.Fatalf("outbox: %v", err)
+	}
+	if events == 0 {
+		t.Fatal("expected a task_completed event for the other member")
+	}
+}
+
+func TestProgressPayout(t *testing.T) {
+	if payout := progressPayout(10, 30, 10, 100); payout != 3.33 {
+		t.Fatalf("expected 3.33, got %v", payout)
+	}
+	if payout := progressPayout(10, 30, 90, 100); payout != 10 {
+		t.Fatalf("payout should be capped at value, got %v", payout)
+	}
+	if payout := progressPayout(10, 30, 90, 150); payout != 15 {
+		t.Fatalf("overachievement should be capped at 150%%, got %v", payout)
+	}
+}
+
+func TestCompleteTaskPaysPerHour(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	var userID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('h@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_balance (workspace_id, balance) VALUES ($1, 0)`, workspaceID); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	var taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, pay_per_hour) VALUES ($1, 'Piano', 8, 'open', true) RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)
+	if _, err := repo.AddTimeEntry(ctx, taskID, workspaceID, userID, nil, start, start.Add(90*time.Minute), ""); err != nil {
+		t.Fatalf("time entry: %v", err)
+	}
+	if _, err := repo.StartTimer(ctx, taskID, workspaceID, userID, nil); err != nil {
+		t.Fatalf("start timer: %v", err)
+	}
+	if _, err := repo.StartTimer(ctx, taskID, workspaceID, userID, nil); !errors.Is(err, ErrTimerRunning) {
+		t.Fatalf("expected ErrTimerRunning, got %v", err)
+	}
+	// One timer at a time: a second hourly task cannot run alongside.
+	var otherID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, pay_per_hour) VALUES ($1, 'Guitar', 8, 'open', true) RETURNING id`, workspaceID).Scan(&otherID); err != nil {
+		t.Fatalf("other task: %v", err)
+	}
+	if _, err := repo.StartTimer(ctx, otherID, workspaceID, userID, nil); !errors.Is(err, ErrTimeOverlap) {
+		t.Fatalf("expected ErrTimeOverlap for a second timer, got %v", err)
+	}
+	// Manual entries may not overlap earlier ones or the running timer.
+	if _, err := repo.AddTimeEntry(ctx, taskID, workspaceID, userID, nil, start.Add(time.Hour), start.Add(2*time.Hour), ""); !errors.Is(err, ErrTimeOverlap) {
+		t.Fatalf("expected ErrTimeOverlap, got %v", err)
+	}
+	if _, err := repo.AddTimeEntry(ctx, taskID, workspaceID, userID, nil, time.Now().Add(-time.Minute), time.Now(), ""); !errors.Is(err, ErrTimeOverlap) {
+		t.Fatalf("expected ErrTimeOverlap with the running timer, got %v", err)
+	}
+	earned, completed, err := repo.CompleteTask(ctx, taskID, workspaceID, userID, nil, false)
+	if err != nil || !completed || earned != 12 {
+		t.Fatalf("expected 12 for 1.5 hours at 8/hour, got earned=%v completed=%v err=%v", earned, completed, err)
+	}
+	if _, _, err := repo.StopTimer(ctx, taskID, workspaceID, userID); !errors.Is(err, ErrNoTimer) {
+		t.Fatalf("completion should stop running timers, got %v", err)
+	}
+	if _, err := repo.AddTimeEntry(ctx, taskID, workspaceID, userID, nil, start.AddDate(0, 0, 1), start.AddDate(0, 0, 1).Add(time.Hour), ""); !errors.Is(err, ErrTaskDone) {
+		t.Fatalf("expected ErrTaskDone, got %v", err)
+	}
+	if _, err := repo.StartTimer(ctx, taskID, workspaceID, userID, nil); !errors.Is(err, ErrTaskDone) {
+		t.Fatalf("expected ErrTaskDone for a timer on a done task, got %v", err)
+	}
+}
+
+func TestHourlyPayout(t *testing.T) {
+	if payout := hourlyPayout(10, 20*60); payout != 3.33 {
+		t.Fatalf("expected 3.33, got %v", payout)
+	}
+}
+
+func TestCompleteTaskBlockedByDependency(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	var userID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('d@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_balance (workspace_id, balance) VALUES ($1, 0)`, workspaceID); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	var draftID, essayID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status) VALUES ($1, 'Write draft', 5, 'open') RETURNING id`, workspaceID).Scan(&draftID); err != nil {
+		t.Fatalf("draft: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status) VALUES ($1, 'Submit essay', 5, 'open') RETURNING id`, workspaceID).Scan(&essayID); err != nil {
+		t.Fatalf("essay: %v", err)
+	}
+	if err := repo.SetTaskDependencies(ctx, essayID, workspaceID, []string{draftID}); err != nil {
+		t.Fatalf("dependencies: %v", err)
+	}
+	if err := repo.SetTaskDependencies(ctx, draftID, workspaceID, []string{essayID}); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected ErrDependencyCycle, got %v", err)
+	}
+	if err := repo.UpdateTask(ctx, draftID, workspaceID, nil, "Write outline", "", nil, nil, 5, "open", false, nil, nil, nil, nil, TaskOptions{}, TaskLinks{BlockedBy: []string{essayID}}); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected ErrDependencyCycle on update, got %v", err)
+	}
+	var title string
+	if err := repo.Pool.QueryRow(ctx, `SELECT title FROM tasks WHERE id=$1`, draftID).Scan(&title); err != nil || title != "Write draft" {
+		t.Fatalf("rejected update should roll back: title=%q err=%v", title, err)
+	}
+	if _, _, err := repo.CompleteTask(ctx, essayID, workspaceID, userID, nil, false); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("expected ErrBlocked, got %v", err)
+	}
+	if _, completed, err := repo.CompleteTask(ctx, draftID, workspaceID, userID, nil, false); err != nil || !completed {
+		t.Fatalf("complete draft: completed=%v err=%v", completed, err)
+	}
+	if _, completed, err := repo.CompleteTask(ctx, essayID, workspaceID, userID, nil, false); err != nil || !completed {
+		t.Fatalf("essay should be unblocked: completed=%v err=%v", completed, err)
+	}
+
+	// Restoring a task must not bring back a cycle closed while it was deleted.
+	var reviewID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status) VALUES ($1, 'Review essay', 5, 'open') RETURNING id`, workspaceID).Scan(&reviewID); err != nil {
+		t.Fatalf("review: %v", err)
+	}
+	if err := repo.SetTaskDependencies(ctx, reviewID, workspaceID, []string{essayID}); err != nil {
+		t.Fatalf("review dependencies: %v", err)
+	}
+	if err := repo.DeleteTask(ctx, essayID, workspaceID); err != nil {
+		t.Fatalf("delete essay: %v", err)
+	}
+	if err := repo.SetTaskDependencies(ctx, draftID, workspaceID, []string{reviewID}); err != nil {
+		t.Fatalf("draft dependencies: %v", err)
+	}
+	if err := repo.RestoreEntity(ctx, "task", essayID, workspaceID); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected ErrDependencyCycle on restore, got %v", err)
+	}
+	var deleted bool
+	if err := repo.Pool.QueryRow(ctx, `SELECT deleted_at IS NOT NULL FROM tasks WHERE id=$1`, essayID).Scan(&deleted); err != nil || !deleted {
+		t.Fatalf("rejected restore should leave the task in the trash: deleted=%v err=%v", deleted, err)
+	}
+}
+
+func TestCreateTaskLinksInOneTransaction(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, blockerID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, status) VALUES ($1, 'Draft', 'open') RETURNING id`, workspaceID).Scan(&blockerID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	tagID, err := repo.CreateTag(ctx, workspaceID, "school", nil)
+	if err != nil {
+		t.Fatalf("tag: %v", err)
+	}
+
+	id, err := repo.CreateTask(ctx, workspaceID, nil, "Essay", "", nil, nil, 5, "open", false, nil, nil, nil, nil, TaskOptions{MaxPayoutPercent: 100},
+		TaskLinks{TagIDs: []string{tagID}, BlockedBy: []string{blockerID}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var tagIDs, blockedBy []string
+	if err := repo.Pool.QueryRow(ctx, `SELECT `+taskTagsColumn+`, `+taskBlockedByColumn+` FROM tasks WHERE id=$1`, id).Scan(&tagIDs, &blockedBy); err != nil {
+		t.Fatalf("read links: %v", err)
+	}
+	if len(tagIDs) != 1 || tagIDs[0] != tagID || len(blockedBy) != 1 || blockedBy[0] != blockerID {
+		t.Fatalf("unexpected links: tags=%v blocked_by=%v", tagIDs, blockedBy)
+	}
+
+	// A link that fails leaves no half-created task behind.
+	if _, err := repo.CreateTask(ctx, workspaceID, nil, "Broken", "", nil, nil, 5, "open", false, nil, nil, nil, nil, TaskOptions{MaxPayoutPercent: 100},
+		TaskLinks{TagIDs: []string{tagID}, BlockedBy: []string{workspaceID}}); !errors.Is(err, ErrInvalidDependency) {
+		t.Fatalf("expected ErrInvalidDependency, got %v", err)
+	}
+	var count int
+	if err := repo.Pool.QueryRow(ctx, `SELECT count(*) FROM tasks WHERE workspace_id=$1 AND title='Broken'`, workspaceID).Scan(&count); err != nil || count != 0 {
+		t.Fatalf("expected the failed task to be rolled back, got %d err=%v", count, err)
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	edges := map[string][]string{"c": {"b"}, "b": {"a"}}
+	if !dependencyCycle(edges, "a", []string{"c"}) {
+		t.Fatalf("a blocked by c closes a -> c -> b -> a")
+	}
+	if !dependencyCycle(edges, "a", []string{"a"}) {
+		t.Fatalf("a task cannot block itself")
+	}
+	if dependencyCycle(edges, "d", []string{"c", "a"}) {
+		t.Fatalf("d has no dependents, no cycle possible")
+	}
+}
+
+func TestTopoOrder(t *testing.T) {
+	ids := []string{"submit", "outline", "draft", "other"}
+	edges := map[string][]string{"submit": {"draft", "external"}, "draft": {"outline"}}
+	order, levels := topoOrder(ids, edges)
+	if strings.Join(order, ",") != "outline,draft,submit,other" {
+		t.Fatalf("unexpected order %v", order)
+	}
+	if levels["submit"] != 2 || levels["draft"] != 1 || levels["other"] != 0 {
+		t.Fatalf("unexpected levels %v", levels)
+	}
+}
+
+func TestProcessMissedOccurrencesChargesOnce(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_balance (workspace_id, balance) VALUES ($1, 10)`, workspaceID); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	// Every day since 2024-01-01, penalty enabled then; 2024-01-02 was completed.
+	var taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, is_recurring, recurrence_weekdays, start_date, penalty, penalty_since)
+		VALUES ($1, 'Daily', 5, 'open', true, '{0,1,2,3,4,5,6}', '2024-01-01', 2, '2024-01-01T00:00:00Z') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, status) VALUES ($1, '2024-01-02', 'done')`, taskID); err != nil {
+		t.Fatalf("occurrence: %v", err)
+	}
+
+	now := time.Date(2024, 1, 4, 12, 0, 0, 0, time.UTC)
+	charged, err := repo.ProcessMissedOccurrences(ctx, now, nil)
+	if err != nil || charged != 2 {
+		t.Fatalf("expected 2 penalties, got %d err=%v", charged, err)
+	}
+	charged, err = repo.ProcessMissedOccurrences(ctx, now, nil)
+	if err != nil || charged != 0 {
+		t.Fatalf("second run should charge nothing, got %d err=%v", charged, err)
+	}
+	var balance float64
+	if err := repo.Pool.QueryRow(ctx, `SELECT balance FROM workspace_balance WHERE workspace_id=$1`, workspaceID).Scan(&balance); err != nil {
+		t.Fatalf("balance read: %v", err)
+	}
+	if balance != 6 {
+		t.Fatalf("expected balance 6, got %v", balance)
+	}
+
+	// After two weeks of downtime every day since the last run is charged.
+	runID, _, err := repo.ClaimJobRun(ctx, "missed-penalties", now)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if err := repo.FinishJobRun(ctx, runID, nil, ""); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	lastRun, err := repo.LastJobSuccess(ctx, "missed-penalties")
+	if err != nil || lastRun == nil || !lastRun.Equal(now) {
+		t.Fatalf("expected last success at %v, got %v err=%v", now, lastRun, err)
+	}
+	charged, err = repo.ProcessMissedOccurrences(ctx, time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC), lastRun)
+	if err != nil || charged != 16 {
+		t.Fatalf("expected 16 penalties for 2024-01-04..19, got %d err=%v", charged, err)
+	}
+}
+
+func TestSkippedAndVacationOccurrencesAreExcused(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	var userID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('a@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_balance (workspace_id, balance) VALUES ($1, 10)`, workspaceID); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	var taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, is_recurring, recurrence_weekdays, start_date, penalty, penalty_since)
+		VALUES ($1, 'Daily', 5, 'open', true, '{0,1,2,3,4,5,6}', '2024-01-01', 2, '2024-01-01T00:00:00Z') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	day := func(value string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return parsed
+	}
+	// 2024-01-01 is skipped, 2024-01-02..03 are a vacation; only 2024-01-04 is missed.
+	if err := repo.SkipOccurrence(ctx, taskID, workspaceID, userID, day("2024-01-01"), "sick"); err != nil {
+		t.Fatalf("skip: %v", err)
+	}
+	if _, err := repo.CreateVacation(ctx, workspaceID, userID, day("2024-01-02"), day("2024-01-03"), "trip"); err != nil {
+		t.Fatalf("vacation: %v", err)
+	}
+	charged, err := repo.ProcessMissedOccurrences(ctx, time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC), nil)
+	if err != nil || charged != 1 {
+		t.Fatalf("expected 1 penalty, got %d err=%v", charged, err)
+	}
+	if err := repo.SkipOccurrence(ctx, taskID, workspaceID, userID, day("2024-01-04"), "late"); !errors.Is(err, ErrOccurrenceClosed) {
+		t.Fatalf("expected ErrOccurrenceClosed for a missed occurrence, got %v", err)
+	}
+
+	instances, err := repo.ListTaskInstances(ctx, workspaceID, day("2024-01-01"), day("2024-01-04"), TaskFilter{})
+	if err != nil {
+		t.Fatalf("instances: %v", err)
+	}
+	want := []string{"skipped", "skipped", "skipped", "missed"}
+	if len(instances) != len(want) {
+		t.Fatalf("expected %d instances, got %d", len(want), len(instances))
+	}
+	for i, instance := range instances {
+		if instance["occurrence_status"] != want[i] {
+			t.Fatalf("instance %d: expected %s, got %v", i, want[i], instance["occurrence_status"])
+		}
+	}
+}
+
+func TestTaskHistory(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, firstID, secondID, taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'family') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('a@b.com', 'x') RETURNING id`).Scan(&firstID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('c@d.com', 'x') RETURNING id`).Scan(&secondID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_balance (workspace_id, balance) VALUES ($1, 0)`, workspaceID); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, is_recurring, recurrence_weekdays, start_date)
+		VALUES ($1, 'Daily', 5, 'open', true, '{0,1,2,3,4,5,6}', '2024-01-01') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	day := func(value string) *time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return &parsed
+	}
+	// The first member completes 01-01, undoes it and the second member
+	// completes it instead; 01-02 is done by the first and 01-03 skipped.
+	if _, _, err := repo.CompleteTask(ctx, taskID, workspaceID, firstID, day("2024-01-01"), false); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if _, _, err := repo.UncompleteTask(ctx, taskID, workspaceID, firstID, day("2024-01-01"), false); err != nil {
+		t.Fatalf("uncomplete: %v", err)
+	}
+	if _, _, err := repo.CompleteTask(ctx, taskID, workspaceID, secondID, day("2024-01-01"), false); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if _, _, err := repo.CompleteTask(ctx, taskID, workspaceID, firstID, day("2024-01-02"), false); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if err := repo.SkipOccurrence(ctx, taskID, workspaceID, firstID, *day("2024-01-03"), "sick"); err != nil {
+		t.Fatalf("skip: %v", err)
+	}
+
+	history, err := repo.TaskHistory(ctx, taskID, workspaceID, nil, nil)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 3 || history[0]["status"] != "skipped" {
+		t.Fatalf("expected 3 entries starting with the skipped day, got %v", history)
+	}
+	history, err = repo.TaskHistory(ctx, taskID, workspaceID, day("2024-01-01"), day("2024-01-02"))
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 entries in range, got %d", len(history))
+	}
+	latest, first := history[0], history[1]
+	if *latest["occurrence_date"].(*string) != "2024-01-02" || *latest["completed_by"].(*string) != firstID || latest["net"] != 5.0 {
+		t.Fatalf("unexpected 2024-01-02 entry: %v", latest)
+	}
+	if *first["completed_by"].(*string) != secondID || first["earned"] != 10.0 || first["reversed"] != 5.0 || first["net"] != 5.0 {
+		t.Fatalf("unexpected 2024-01-01 entry: %v", first)
+	}
+	if transactions := first["transactions"].([]map[string]any); len(transactions) != 3 || transactions[0]["reversed"] != true {
+		t.Fatalf("expected earn, reversal and earn, got %v", transactions)
+	}
+	if _, err := repo.TaskHistory(ctx, taskID, "00000000-0000-0000-0000-000000000000", nil, nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another workspace, got %v", err)
+	}
+}
+
+func TestRestoreFromTrash(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, goalID, otherGoalID, keptID, movedID, deletedID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	for _, goal := range []*string{&goalID, &otherGoalID} {
+		if err := repo.Pool.QueryRow(ctx, `INSERT INTO goals (workspace_id, title) VALUES ($1, 'Goal') RETURNING id`, workspaceID).Scan(goal); err != nil {
+			t.Fatalf("goal: %v", err)
+		}
+	}
+	for _, task := range []*string{&keptID, &movedID, &deletedID} {
+		if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, goal_id, title, status) VALUES ($1, $2, 'Task', 'open') RETURNING id`, workspaceID, goalID).Scan(task); err != nil {
+			t.Fatalf("task: %v", err)
+		}
+	}
+	goalOf := func(taskID string) *string {
+		var goal *string
+		if err := repo.Pool.QueryRow(ctx, `SELECT goal_id::text FROM tasks WHERE id=$1`, taskID).Scan(&goal); err != nil {
+			t.Fatalf("task goal: %v", err)
+		}
+		return goal
+	}
+
+	if err := repo.DeleteTask(ctx, deletedID, workspaceID); err != nil {
+		t.Fatalf("delete task: %v", err)
+	}
+	if err := repo.DeleteGoal(ctx, goalID, workspaceID); err != nil {
+		t.Fatalf("delete goal: %v", err)
+	}
+	if goalOf(keptID) != nil {
+		t.Fatalf("expected tasks of a deleted goal to be detached")
+	}
+	if err := repo.MoveTask(ctx, movedID, workspaceID, &otherGoalID); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	trash, err := repo.ListTrash(ctx, workspaceID)
+	if err != nil || len(trash) != 2 || trash[0]["entity_type"] != "goal" {
+		t.Fatalf("expected the goal then the task in the trash, got %v err=%v", trash, err)
+	}
+
+	// The task deleted before its goal comes back without it.
+	if err := repo.RestoreEntity(ctx, "task", deletedID, workspaceID); err != nil {
+		t.Fatalf("restore task: %v", err)
+	}
+	if goalOf(deletedID) != nil {
+		t.Fatalf("expected a task restored into a deleted goal to be detached")
+	}
+	if err := repo.RestoreEntity(ctx, "goal", goalID, workspaceID); err != nil {
+		t.Fatalf("restore goal: %v", err)
+	}
+	for _, taskID := range []string{keptID, deletedID} {
+		if goal := goalOf(taskID); goal == nil || *goal != goalID {
+			t.Fatalf("expected task %s back under its goal, got %v", taskID, goal)
+		}
+	}
+	if goal := goalOf(movedID); goal == nil || *goal != otherGoalID {
+		t.Fatalf("expected the moved task to keep its new goal, got %v", goal)
+	}
+	if err := repo.RestoreEntity(ctx, "goal", goalID, workspaceID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a goal not in the trash, got %v", err)
+	}
+	if trash, err := repo.ListTrash(ctx, workspaceID); err != nil || len(trash) != 0 {
+		t.Fatalf("expected an empty trash, got %v err=%v", trash, err)
+	}
+}
+
+func TestMoveAndRescheduleTask(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, otherWorkspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Other', 'personal') RETURNING id`).Scan(&otherWorkspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	var userID, goalID, foreignGoalID, taskID, recurringID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('a@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO goals (workspace_id, title) VALUES ($1, 'Goal') RETURNING id`, workspaceID).Scan(&goalID); err != nil {
+		t.Fatalf("goal: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO goals (workspace_id, title) VALUES ($1, 'Foreign') RETURNING id`, otherWorkspaceID).Scan(&foreignGoalID); err != nil {
+		t.Fatalf("goal: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, status, due_date) VALUES ($1, 'Once', 'open', '2024-01-01') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, status, is_recurring, recurrence_weekdays) VALUES ($1, 'Daily', 'open', true, '{1}') RETURNING id`, workspaceID).Scan(&recurringID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+
+	if err := repo.MoveTask(ctx, taskID, workspaceID, &goalID); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if err := repo.MoveTask(ctx, taskID, workspaceID, &foreignGoalID); !errors.Is(err, ErrInvalidGoal) {
+		t.Fatalf("expected ErrInvalidGoal, got %v", err)
+	}
+	for goal, want := range map[string]bool{goalID: true, foreignGoalID: false, "not-a-uuid": false} {
+		if valid, err := repo.AreWorkspaceGoals(ctx, workspaceID, []string{goal}); err != nil || valid != want {
+			t.Fatalf("goal %s: valid=%v err=%v, want %v", goal, valid, err, want)
+		}
+	}
+	due := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	if _, err := repo.RescheduleTask(ctx, taskID, workspaceID, userID, due); err != nil {
+		t.Fatalf("reschedule: %v", err)
+	}
+	if _, err := repo.RescheduleTask(ctx, recurringID, workspaceID, userID, due); !errors.Is(err, ErrRecurringTask) {
+		t.Fatalf("expected ErrRecurringTask, got %v", err)
+	}
+	var gotGoal string
+	var gotDue time.Time
+	var version int
+	if err := repo.Pool.QueryRow(ctx, `SELECT goal_id, due_date, version FROM tasks WHERE id=$1`, taskID).Scan(&gotGoal, &gotDue, &version); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if gotGoal != goalID || !gotDue.Equal(due) || version != 3 {
+		t.Fatalf("unexpected task state: goal=%s due=%v version=%d", gotGoal, gotDue, version)
+	}
+}
+
+func TestReschedulePostponesWithDecay(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, userID, taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('a@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, status, value, due_date, timezone, postpone_decay_percent)
+		VALUES ($1, 'Report', 'open', 20, '2024-01-01', 'Asia/Tokyo', 10) RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+
+	// 2024-01-05 20:00 UTC is already Saturday 2024-01-06 in Tokyo.
+	now := time.Date(2024, 1, 5, 20, 0, 0, 0, time.UTC)
+	result, err := repo.SnoozeTask(ctx, taskID, workspaceID, userID, SnoozeTomorrow, now)
+	if err != nil {
+		t.Fatalf("snooze: %v", err)
+	}
+	if result.DueDate.Format("2006-01-02") != "2024-01-07" || result.Value != 18 || result.PostponedCount != 1 {
+		t.Fatalf("unexpected snooze result: %+v", result)
+	}
+	if result, err = repo.SnoozeTask(ctx, taskID, workspaceID, userID, SnoozeNextWeek, now); err != nil || result.DueDate.Format("2006-01-02") != "2024-01-08" || result.Value != 16.2 {
+		t.Fatalf("unexpected next week result: %+v err=%v", result, err)
+	}
+	// Moving the date earlier is not a postponement; the same date is a no-op.
+	if result, err = repo.RescheduleTask(ctx, taskID, workspaceID, userID, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)); err != nil || result.Value != 16.2 || result.PostponedCount != 2 {
+		t.Fatalf("unexpected earlier result: %+v err=%v", result, err)
+	}
+	if _, err := repo.RescheduleTask(ctx, taskID, workspaceID, userID, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("reschedule: %v", err)
+	}
+
+	history, err := repo.ListTaskReschedules(ctx, taskID, workspaceID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected 3 reschedules, got %d", len(history))
+	}
+	if first := history[2]; *first["from_date"].(*string) != "2024-01-01" || first["to_date"] != "2024-01-07" || *first["snooze"].(*string) != SnoozeTomorrow || first["value_after"] != 18.0 {
+		t.Fatalf("unexpected first reschedule: %v", first)
+	}
+
+	// Updates can neither move the due date nor undo the decay.
+	later := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	if err := repo.UpdateTask(ctx, taskID, workspaceID, nil, "Report", "", &later, nil, 16.2, "open", false, nil, nil, nil, nil, TaskOptions{}, TaskLinks{}); !errors.Is(err, ErrDueDateChange) {
+		t.Fatalf("expected ErrDueDateChange, got %v", err)
+	}
+	current := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	if err := repo.UpdateTask(ctx, taskID, workspaceID, nil, "Report", "", &current, nil, 20, "open", false, nil, nil, nil, nil, TaskOptions{}, TaskLinks{}); !errors.Is(err, ErrValueDecayed) {
+		t.Fatalf("expected ErrValueDecayed, got %v", err)
+	}
+	// Editing the title without a due date keeps the date.
+	if err := repo.UpdateTask(ctx, taskID, workspaceID, nil, "Final report", "", nil, nil, 16.2, "open", false, nil, nil, nil, nil, TaskOptions{}, TaskLinks{}); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	// Sync pushes cannot move the date or undo the decay either.
+	if err := repo.UpsertEntity(ctx, "tasks", map[string]any{
+		"id": taskID, "workspace_id": workspaceID, "title": "Pushed", "description": "", "due_date": "2024-02-01", "value": 20.0, "status": "open",
+		"updated_at": time.Now().Add(time.Hour), "version": 100, "is_recurring": false,
+	}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	var title string
+	var dueDate time.Time
+	var value float64
+	if err := repo.Pool.QueryRow(ctx, `SELECT title, due_date, value FROM tasks WHERE id=$1`, taskID).Scan(&title, &dueDate, &value); err != nil {
+		t.Fatalf("read task: %v", err)
+	}
+	if title != "Pushed" || !dueDate.Equal(current) || value != 16.2 {
+		t.Fatalf("expected the pushed title with the stored date and value, got %s %v %v", title, dueDate, value)
+	}
+
+	if _, err := repo.Pool.Exec(ctx, `UPDATE tasks SET status='done' WHERE id=$1`, taskID); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	if _, err := repo.RescheduleTask(ctx, taskID, workspaceID, userID, later); !errors.Is(err, ErrTaskDone) {
+		t.Fatalf("expected ErrTaskDone, got %v", err)
+	}
+}
+
+func TestSnoozeDate(t *testing.T) {
+	cases := []struct {
+		preset string
+		now    time.Time
+		want   string
+	}{
+		{SnoozeTomorrow, time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC), "2024-02-01"},
+		{SnoozeNextWeek, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), "2024-01-08"},
+		{SnoozeNextWeek, time.Date(2024, 1, 7, 10, 0, 0, 0, time.UTC), "2024-01-08"},
+	}
+	for _, c := range cases {
+		got, err := snoozeDate(c.preset, c.now, time.UTC)
+		if err != nil || got.Format("2006-01-02") != c.want {
+			t.Fatalf("%s from %v: expected %s, got %v err=%v", c.preset, c.now, c.want, got, err)
+		}
+	}
+	if _, err := snoozeDate("someday", time.Now(), time.UTC); !errors.Is(err, ErrInvalidSnooze) {
+		t.Fatalf("expected ErrInvalidSnooze, got %v", err)
+	}
+}
+
+func TestCalendarFeedTokens(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, userID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Family', 'family') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('feed@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'owner')`, workspaceID, userID); err != nil {
+		t.Fatalf("member: %v", err)
+	}
+
+	if _, err := repo.GetCalendarFeed(ctx, workspaceID, userID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected no feed, got %v", err)
+	}
+	if _, err := repo.SetCalendarFeed(ctx, workspaceID, userID, "first"); err != nil {
+		t.Fatalf("set feed: %v", err)
+	}
+	feed, err := repo.CalendarFeedByToken(ctx, "first")
+	if err != nil || feed.WorkspaceID != workspaceID || feed.UserID != userID || feed.WorkspaceName != "Family" {
+		t.Fatalf("unexpected feed %+v: %v", feed, err)
+	}
+	if info, err := repo.GetCalendarFeed(ctx, workspaceID, userID); err != nil || info["last_used_at"] == (*time.Time)(nil) {
+		t.Fatalf("expected use to be recorded, got %v: %v", info, err)
+	}
+
+	// Regenerating revokes the previous token.
+	if _, err := repo.SetCalendarFeed(ctx, workspaceID, userID, "second"); err != nil {
+		t.Fatalf("regenerate feed: %v", err)
+	}
+	if _, err := repo.CalendarFeedByToken(ctx, "first"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected old token to be revoked, got %v", err)
+	}
+	if _, err := repo.CalendarFeedByToken(ctx, "second"); err != nil {
+		t.Fatalf("new token: %v", err)
+	}
+
+	// Leaving the workspace disables the feed.
+	if _, err := repo.Pool.Exec(ctx, `DELETE FROM workspace_members WHERE workspace_id=$1 AND user_id=$2`, workspaceID, userID); err != nil {
+		t.Fatalf("leave: %v", err)
+	}
+	if _, err := repo.CalendarFeedByToken(ctx, "second"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected former member's token to fail, got %v", err)
+	}
+
+	if err := repo.DeleteCalendarFeed(ctx, workspaceID, userID); err != nil {
+		t.Fatalf("delete feed: %v", err)
+	}
+	if err := repo.DeleteCalendarFeed(ctx, workspaceID, userID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected revoked feed to be gone, got %v", err)
+	}
+}
+
+func TestSavePushSubscriptionOwnership(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var ownerID, otherID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('push-owner@b.com', 'x') RETURNING id`).Scan(&ownerID); err != nil {
+		t.Fatalf("owner: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('push-other@b.com', 'x') RETURNING id`).Scan(&otherID); err != nil {
+		t.Fatalf("other: %v", err)
+	}
+	const endpoint = "https://push.example.com/sub/1"
+	id, err := repo.SavePushSubscription(ctx, ownerID, endpoint, "key", "secret", nil)
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	// The owner may refresh the keys.
+	if again, err := repo.SavePushSubscription(ctx, ownerID, endpoint, "key2", "secret2", nil); err != nil || again != id {
+		t.Fatalf("expected refresh of %s, got %s: %v", id, again, err)
+	}
+
+	// Another user knowing only the endpoint cannot take it over.
+	if _, err := repo.SavePushSubscription(ctx, otherID, endpoint, "forged", "forged", nil); !errors.Is(err, ErrSubscriptionTaken) {
+		t.Fatalf("expected ErrSubscriptionTaken, got %v", err)
+	}
+	subs, err := repo.ListPushSubscriptions(ctx, ownerID)
+	if err != nil || len(subs) != 1 {
+		t.Fatalf("expected owner to keep the subscription, got %v: %v", subs, err)
+	}
+
+	// The same browser, proven by its keys, moves to the user signed in now.
+	if moved, err := repo.SavePushSubscription(ctx, otherID, endpoint, "key2", "secret2", nil); err != nil || moved != id {
+		t.Fatalf("expected move of %s, got %s: %v", id, moved, err)
+	}
+	if subs, err := repo.ListPushSubscriptions(ctx, ownerID); err != nil || len(subs) != 0 {
+		t.Fatalf("expected subscription to leave the owner, got %v: %v", subs, err)
+	}
+}
+
+func TestImportTasksSkipsDuplicates(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, userID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('imp@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	skipped := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	tasks := []ImportTask{
+		{UID: "gym", Title: "Gym", IsRecurring: true, Weekdays: []int{1, 3}, StartDate: &start, SkippedDates: []time.Time{skipped}},
+		{UID: "gym", Title: "Gym again"},
+		{Title: "No uid", DueDate: &start},
+	}
+
+	preview, err := repo.ImportTasks(ctx, workspaceID, userID, tasks, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if preview[0].TaskID != "" || preview[0].Duplicate || !preview[1].Duplicate || preview[2].Duplicate {
+		t.Fatalf("unexpected dry run results %+v", preview)
+	}
+	var count int
+	if err := repo.Pool.QueryRow(ctx, `SELECT count(*) FROM tasks WHERE workspace_id=$1`, workspaceID).Scan(&count); err != nil || count != 0 {
+		t.Fatalf("dry run created %d tasks: %v", count, err)
+	}
+
+	results, err := repo.ImportTasks(ctx, workspaceID, userID, tasks, false)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if results[0].TaskID == "" || !results[1].Duplicate || results[2].TaskID == "" {
+		t.Fatalf("unexpected import results %+v", results)
+	}
+	var status string
+	if err := repo.Pool.QueryRow(ctx, `SELECT status FROM task_occurrences WHERE task_id=$1 AND occurrence_date=$2`, results[0].TaskID, skipped).Scan(&status); err != nil || status != "skipped" {
+		t.Fatalf("expected skipped occurrence, got %q: %v", status, err)
+	}
+
+	again, err := repo.ImportTasks(ctx, workspaceID, userID, tasks[:1], false)
+	if err != nil {
+		t.Fatalf("import again: %v", err)
+	}
+	if !again[0].Duplicate {
+		t.Fatalf("expected re-import to be a duplicate, got %+v", again)
+	}
+}
+
+func TestGoalProgress(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, goalID, emptyGoalID, doneID, openID, deletedID, recurringID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO goals (workspace_id, title, start_date, end_date) VALUES ($1, 'Goal', '2024-01-01', '2024-01-14') RETURNING id`, workspaceID).Scan(&goalID); err != nil {
+		t.Fatalf("goal: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO goals (workspace_id, title) VALUES ($1, 'Empty') RETURNING id`, workspaceID).Scan(&emptyGoalID); err != nil {
+		t.Fatalf("goal: %v", err)
+	}
+	for task, status := range map[*string]string{&doneID: "done", &openID: "open", &deletedID: "done"} {
+		if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, goal_id, title, status) VALUES ($1, $2, 'Task', $3) RETURNING id`, workspaceID, goalID, status).Scan(task); err != nil {
+			t.Fatalf("task: %v", err)
+		}
+	}
+	if _, err := repo.Pool.Exec(ctx, `UPDATE tasks SET deleted_at=now() WHERE id=$1`, deletedID); err != nil {
+		t.Fatalf("delete task: %v", err)
+	}
+	// Mondays and Wednesdays: Jan 1, 3, 8 and 10 fall within the goal.
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, goal_id, title, status, is_recurring, recurrence_weekdays, start_date)
+		VALUES ($1, $2, 'Daily', 'open', true, '{1,3}', '2023-12-01') RETURNING id`, workspaceID, goalID).Scan(&recurringID); err != nil {
+		t.Fatalf("recurring task: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, status) VALUES ($1, '2023-12-27', 'done'), ($1, '2024-01-03', 'done'), ($1, '2024-01-08', 'skipped')`, recurringID); err != nil {
+		t.Fatalf("occurrences: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_vacations (workspace_id, start_date, end_date) VALUES ($1, '2024-01-10', '2024-01-10')`, workspaceID); err != nil {
+		t.Fatalf("vacation: %v", err)
+	}
+	var earnID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO transactions (workspace_id, type, amount, reason, entity_type, entity_id) VALUES ($1, 'earn', 5, 'x', 'task', $2) RETURNING id`, workspaceID, recurringID).Scan(&earnID); err != nil {
+		t.Fatalf("earn: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO transactions (workspace_id, type, amount, reason, entity_type, entity_id, reverses_transaction_id) VALUES
+		($1, 'earn', 10, 'x', 'task', $2, NULL), ($1, 'reversal', 5, 'x', 'task', $3, $4), ($1, 'penalty', 2, 'x', 'task', $3, NULL)`, workspaceID, doneID, recurringID, earnID); err != nil {
+		t.Fatalf("transactions: %v", err)
+	}
+	var itemID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO task_checklist_items (task_id, title, value, done) VALUES ($1, 'Step', 3, true) RETURNING id`, openID).Scan(&itemID); err != nil {
+		t.Fatalf("checklist item: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO transactions (workspace_id, type, amount, reason, entity_type, entity_id) VALUES ($1, 'earn', 3, 'x', 'checklist_item', $2)`, workspaceID, itemID); err != nil {
+		t.Fatalf("checklist earn: %v", err)
+	}
+
+	progress, err := repo.GoalProgress(ctx, goalID, workspaceID)
+	if err != nil {
+		t.Fatalf("progress: %v", err)
+	}
+	percent, _ := progress["percent"].(*float64)
+	if progress["tasks_total"] != 2 || progress["tasks_done"] != 1 || progress["occurrences_scheduled"] != 2 || progress["occurrences_done"] != 1 ||
+		progress["earned"] != 13.0 || percent == nil || *percent != 50 {
+		t.Fatalf("unexpected progress %v", progress)
+	}
+
+	page, err := repo.ListGoals(ctx, workspaceID, PageRequest{})
+	if err != nil {
+		t.Fatalf("list goals: %v", err)
+	}
+	for _, goal := range page.Items {
+		goalProgress, _ := goal["progress"].(map[string]any)
+		if goal["id"] == emptyGoalID && (goalProgress == nil || goalProgress["tasks_total"] != 0 || goalProgress["percent"] != (*float64)(nil)) {
+			t.Fatalf("unexpected empty goal progress %v", goalProgress)
+		}
+		if goal["id"] == goalID && (goalProgress == nil || goalProgress["earned"] != 13.0) {
+			t.Fatalf("unexpected listed goal progress %v", goalProgress)
+		}
+	}
+
+	if _, err := repo.GoalProgress(ctx, emptyGoalID, "00000000-0000-0000-0000-000000000000"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found in other workspace, got %v", err)
+	}
+}
+
+func TestPurgeDeletedKeepsHistory(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Purge', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	deletedAt := time.Now().Add(-48 * time.Hour)
+	var unusedID, usedID, rewardID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, status, deleted_at) VALUES ($1, 'Unused', 'open', $2) RETURNING id`, workspaceID, deletedAt).Scan(&unusedID); err != nil {
+		t.Fatalf("unused task: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, status, is_recurring, deleted_at) VALUES ($1, 'Used', 'open', true, $2) RETURNING id`, workspaceID, deletedAt).Scan(&usedID); err != nil {
+		t.Fatalf("used task: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, status) VALUES ($1, '2024-01-01', 'done')`, usedID); err != nil {
+		t.Fatalf("occurrence: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO comments (workspace_id, entity_type, entity_id, body) VALUES ($1, 'task', $2, 'Gone with the task')`, workspaceID, unusedID); err != nil {
+		t.Fatalf("comment: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO rewards (workspace_id, title, cost, deleted_at) VALUES ($1, 'Cinema', 5, $2) RETURNING id`, workspaceID, deletedAt).Scan(&rewardID); err != nil {
+		t.Fatalf("reward: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO reward_purchases (workspace_id, reward_id, cost) VALUES ($1, $2, 5)`, workspaceID, rewardID); err != nil {
+		t.Fatalf("purchase: %v", err)
+	}
+	var goalID, detachedID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO goals (workspace_id, title, deleted_at) VALUES ($1, 'Old goal', $2) RETURNING id`, workspaceID, deletedAt).Scan(&goalID); err != nil {
+		t.Fatalf("goal: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, status, detached_goal_id) VALUES ($1, 'Detached', 'open', $2) RETURNING id`, workspaceID, goalID).Scan(&detachedID); err != nil {
+		t.Fatalf("detached task: %v", err)
+	}
+
+	purged, err := repo.PurgeDeleted(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if purged["tasks"] != 1 || purged["rewards"] != 0 || purged["goals"] != 1 {
+		t.Fatalf("unexpected purge counts %v", purged)
+	}
+	var detachedGoal *string
+	if err := repo.Pool.QueryRow(ctx, `SELECT detached_goal_id::text FROM tasks WHERE id=$1`, detachedID).Scan(&detachedGoal); err != nil || detachedGoal != nil {
+		t.Fatalf("expected the purged goal cleared from detached tasks, got %v err=%v", detachedGoal, err)
+	}
+	var tasks, comments, rewards int
+	if err := repo.Pool.QueryRow(ctx, `SELECT (SELECT count(*) FROM tasks WHERE id=$1), (SELECT count(*) FROM comments WHERE entity_id=$2), (SELECT count(*) FROM rewards WHERE id=$3)`,
+		usedID, unusedID, rewardID).Scan(&tasks, &comments, &rewards); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if tasks != 1 || comments != 0 || rewards != 1 {
+		t.Fatalf("expected used task and purchased reward kept and comment purged, got tasks=%d comments=%d rewards=%d", tasks, comments, rewards)
+	}
+}
+
+func TestClaimJobRunOnce(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	slot := time.Date(2024, 1, 1, 3, 30, 0, 0, time.UTC)
+	runID, claimed, err := repo.ClaimJobRun(ctx, "purge-deleted", slot)
+	if err != nil || !claimed {
+		t.Fatalf("first claim failed: claimed=%v err=%v", claimed, err)
+	}
+	if _, claimed, err := repo.ClaimJobRun(ctx, "purge-deleted", slot); err != nil || claimed {
+		t.Fatalf("slot should be claimed once: claimed=%v err=%v", claimed, err)
+	}
+	if err := repo.FinishJobRun(ctx, runID, errors.New("boom"), ""); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	var status, detail string
+	if err := repo.Pool.QueryRow(ctx, `SELECT status, detail FROM job_runs WHERE id=$1`, runID).Scan(&status, &detail); err != nil {
+		t.Fatalf("read run: %v", err)
+	}
+	if status != "failed" || detail != "boom" {
+		t.Fatalf("unexpected run state: %s %q", status, detail)
+	}
+}
+
+func TestEnqueueDueRemindersOnce(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	var userID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ('r@b.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'owner')`, workspaceID, userID); err != nil {
+		t.Fatalf("member: %v", err)
+	}
+	if err := repo.UpsertNotificationSettings(ctx, userID, NotificationSettings{EmailEnabled: true}); err != nil {
+		t.Fatalf("settings: %v", err)
+	}
+	var taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, due_date) VALUES ($1, 'Pay rent', 1, 'open', '2024-01-01') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	atTime := "09:00"
+	if err := repo.SetTaskReminders(ctx, taskID, workspaceID, []Reminder{{AtTime: &atTime}}); err != nil {
+		t.Fatalf("reminders: %v", err)
+	}
+
+	if count, err := repo.EnqueueDueReminders(ctx, time.Date(2024, 1, 1, 8, 59, 0, 0, time.UTC)); err != nil || count != 0 {
+		t.Fatalf("reminder should not fire early: count=%d err=%v", count, err)
+	}
+	now := time.Date(2024, 1, 1, 9, 1, 0, 0, time.UTC)
+	if count, err := repo.EnqueueDueReminders(ctx, now); err != nil || count != 1 {
+		t.Fatalf("expected 1 notification, got %d err=%v", count, err)
+	}
+	if count, err := repo.EnqueueDueReminders(ctx, now.Add(time.Minute)); err != nil || count != 0 {
+		t.Fatalf("reminder should be enqueued once, got %d err=%v", count, err)
+	}
+	due, err := repo.ListDueNotifications(ctx, now, 10)
+	if err != nil || len(due) != 1 || due[0].Channel != "email" || due[0].Email != "r@b.com" {
+		t.Fatalf("unexpected outbox: %+v err=%v", due, err)
+	}
+}
+
+func TestReminderFireTime(t *testing.T) {
+	day := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	atTime := "09:00"
+	fireAt, err := reminderFireTime(day, moscow, Reminder{AtTime: &atTime})
+	if err != nil || !fireAt.Equal(time.Date(2024, 1, 10, 6, 0, 0, 0, time.UTC)) {
+		t.Fatalf("09:00 Moscow is 06:00 UTC, got %v err=%v", fireAt, err)
+	}
+	hour := 60
+	fireAt, _ = reminderFireTime(day, time.UTC, Reminder{MinutesBefore: &hour})
+	if !fireAt.Equal(time.Date(2024, 1, 10, 23, 0, 0, 0, time.UTC)) {
+		t.Fatalf("an hour before the day ends is 23:00, got %v", fireAt)
+	}
+}
+
+func TestMissedCandidates(t *testing.T) {
+	date := func(value string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return parsed
+	}
+	// Mondays only; 2024-01-01 is a Monday.
+	start := date("2023-12-01")
+	dates := missedCandidates([]int{1}, &start, nil, date("2023-12-01"), date("2024-01-15"))
+	if len(dates) != 6 || !dates[0].Equal(date("2023-12-04")) || !dates[5].Equal(date("2024-01-08")) {
+		t.Fatalf("expected every Monday from 2023-12-04 to 2024-01-08, got %v", dates)
+	}
+	if dates := missedCandidates([]int{1}, nil, nil, date("2024-01-09"), date("2024-01-15")); len(dates) != 0 {
+		t.Fatalf("days before the penalty was enabled should be skipped, got %v", dates)
+	}
+}
+
+func TestTaskFilterWhere(t *testing.T) {
+	priority := 2
+	recurring := false
+	filter := TaskFilter{Priority: &priority, Recurring: &recurring, Text: "50%_off"}
+	conditions, args := filter.where([]any{"ws"})
+	expected := ` AND tasks.priority=$2 AND tasks.is_recurring=$3 AND (tasks.title ILIKE $4 OR tasks.description ILIKE $4)`
+	if conditions != expected {
+		t.Fatalf("unexpected conditions: %s", conditions)
+	}
+	if len(args) != 4 || args[3] != `%50\%\_off%` {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+	if conditions, args := (TaskFilter{}).where(nil); conditions != "" || len(args) != 0 {
+		t.Fatalf("empty filter should add nothing: %q %v", conditions, args)
+	}
+}
+
+func TestDeleteTagUnlinksTasks(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status) VALUES ($1, 'Task', 5, 'open') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	tagID, err := repo.CreateTag(ctx, workspaceID, "home", nil)
+	if err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+	if err := repo.SetTaskTags(ctx, taskID, workspaceID, []string{tagID}); err != nil {
+		t.Fatalf("set tags: %v", err)
+	}
+	if err := repo.DeleteTag(ctx, tagID, workspaceID); err != nil {
+		t.Fatalf("delete tag: %v", err)
+	}
+	if err := repo.DeleteTag(ctx, tagID, workspaceID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound on second delete, got %v", err)
+	}
+
+	page, err := repo.ListTasks(ctx, workspaceID, TaskFilter{}, PageRequest{})
+	if err != nil {
+		t.Fatalf("list tasks: %v", err)
+	}
+	if len(page.Items) != 1 || len(page.Items[0]["tag_ids"].([]string)) != 0 || page.Items[0]["version"] != 3 {
+		t.Fatalf("expected the task untagged at version 3, got %v", page.Items)
+	}
+	page, err = repo.ListTasks(ctx, workspaceID, TaskFilter{TagID: &tagID}, PageRequest{})
+	if err != nil {
+		t.Fatalf("list tasks by tag: %v", err)
+	}
+	if len(page.Items) != 0 {
+		t.Fatalf("deleted tag should match nothing, got %v", page.Items)
+	}
+}
+
+func TestListTaskInstancesFiltersOccurrenceStatus(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	// Tuesdays: Jan 2 and 9, 2024.
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, value, status, is_recurring, recurrence_weekdays, start_date)
+		VALUES ($1, 'Weekly', 5, 'open', true, '{2}', '2024-01-01') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, status) VALUES ($1, '2024-01-02', 'done')`, taskID); err != nil {
+		t.Fatalf("occurrence: %v", err)
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+	for status, want := range map[string]string{"done": "2024-01-02", "open": "2024-01-09", "pending": "2024-01-09"} {
+		instances, err := repo.ListTaskInstances(ctx, workspaceID, from, to, TaskFilter{Status: status})
+		if err != nil {
+			t.Fatalf("list instances: %v", err)
+		}
+		if len(instances) != 1 || instances[0]["occurrence_date"] != want {
+			t.Fatalf("status %s: expected only %s, got %v", status, want, instances)
+		}
+	}
+}
+
+func TestStreakStats(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	date := func(value string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return parsed
+	}
+	var workspaceID, taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Test', 'personal') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	// Mondays and Wednesdays; 2024-01-01 is a Monday.
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, status, is_recurring, recurrence_weekdays) VALUES ($1, 'Run', 'open', true, '{1,3}') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	for _, day := range []string{"2024-01-01", "2024-01-03", "2024-01-08", "2024-01-15", "2024-01-17"} {
+		if _, err := repo.Pool.Exec(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, status) VALUES ($1, $2, 'done')`, taskID, day); err != nil {
+			t.Fatalf("occurrence: %v", err)
+		}
+	}
+	streakAt := func(today string) Streak {
+		t.Helper()
+		streaks, err := repo.loadStreaks(ctx, []streakTask{{id: taskID}}, date(today).Add(12*time.Hour))
+		if err != nil {
+			t.Fatalf("streaks: %v", err)
+		}
+		return streaks[taskID]
+	}
+
+	if streak := streakAt("2024-01-22"); streak.Current != 2 || streak.Longest != 3 {
+		t.Fatalf("expected current 2 longest 3, got %+v", streak)
+	}
+	if streak := streakAt("2024-01-23"); streak.Current != 0 {
+		t.Fatalf("missed Monday should reset streak, got %+v", streak)
+	}
+
+	// A skipped Wednesday neither breaks nor extends the run.
+	if _, err := repo.Pool.Exec(ctx, `INSERT INTO task_occurrences (task_id, occurrence_date, status) VALUES ($1, '2024-01-10', 'skipped')`, taskID); err != nil {
+		t.Fatalf("skip: %v", err)
+	}
+	if streak := streakAt("2024-01-22"); streak.Current != 5 || streak.Longest != 5 {
+		t.Fatalf("expected current 5 longest 5 with excused day, got %+v", streak)
+	}
+
+	// Days after the end date are not scheduled, so they break nothing.
+	if _, err := repo.Pool.Exec(ctx, `UPDATE tasks SET end_date='2024-01-17' WHERE id=$1`, taskID); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	if streak := streakAt("2024-02-01"); streak.Current != 5 || streak.Longest != 5 {
+		t.Fatalf("expected current 5 after the end date, got %+v", streak)
+	}
+}
+
+func TestStreakEndingAt(t *testing.T) {
+	date := func(value string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return parsed
+	}
+	// Mondays and Wednesdays; 2024-01-01 is a Monday.
+	weekdays := []int{1, 3}
+	done := map[string]bool{"2024-01-01": true, "2024-01-03": true, "2024-01-08": true, "2024-01-15": true, "2024-01-17": true}
+	if streak := streakEndingAt(weekdays, done, nil, nil, date("2024-01-08")); streak != 3 {
+		t.Fatalf("expected streak 3 ending at 2024-01-08, got %d", streak)
+	}
+	// A skipped Wednesday neither breaks nor extends the run.
+	excused := map[string]bool{"2024-01-10": true}
+	if streak := streakEndingAt(weekdays, done, excused, nil, date("2024-01-17")); streak != 5 {
+		t.Fatalf("expected streak 5 ending at 2024-01-17, got %d", streak)
+	}
+}
+
+func TestCommentMentionsAndEdits(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, authorID, aliceID, bobID, taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Home', 'shared') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	for email, id := range map[string]*string{"me@example.com": &authorID, "alice@example.com": &aliceID, "bob@example.com": &bobID} {
+		if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ($1, 'x') RETURNING id`, email).Scan(id); err != nil {
+			t.Fatalf("user: %v", err)
+		}
+		if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'member')`, workspaceID, *id); err != nil {
+			t.Fatalf("member: %v", err)
+		}
+		if _, err := repo.Pool.Exec(ctx, `INSERT INTO notification_settings (user_id, email_enabled) VALUES ($1, true)`, *id); err != nil {
+			t.Fatalf("settings: %v", err)
+		}
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, status) VALUES ($1, 'Dishes', 'open') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+
+	commentID, err := repo.CreateComment(ctx, workspaceID, "task", taskID, authorID, "@alice your turn")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := repo.UpdateComment(ctx, commentID, workspaceID, "task", taskID, authorID, "@alice and @bob, your turn"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := repo.UpdateComment(ctx, commentID, workspaceID, "task", taskID, aliceID, "hijack"); !errors.Is(err, ErrNotAuthor) {
+		t.Fatalf("expected ErrNotAuthor, got %v", err)
+	}
+	var mentions int
+	if err := repo.Pool.QueryRow(ctx, `SELECT count(*) FROM notification_outbox WHERE kind='mention' AND user_id IN ($1, $2)`, aliceID, bobID).Scan(&mentions); err != nil {
+		t.Fatalf("outbox: %v", err)
+	}
+	if mentions != 2 {
+		t.Fatalf("expected one mention each for alice and bob, got %d", mentions)
+	}
+	edits, err := repo.ListCommentEdits(ctx, commentID, workspaceID, "task", taskID)
+	if err != nil || len(edits) != 1 || edits[0]["body"] != "@alice your turn" {
+		t.Fatalf("expected the original body in history, got %v err=%v", edits, err)
+	}
+}
+
+func TestAttachmentsAndUploadDeletion(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID, uploaderID, otherID, taskID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Home', 'shared') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	for email, id := range map[string]*string{"me@example.com": &uploaderID, "other@example.com": &otherID} {
+		if err := repo.Pool.QueryRow(ctx, `INSERT INTO users (email, password_hash) VALUES ($1, 'x') RETURNING id`, email).Scan(id); err != nil {
+			t.Fatalf("user: %v", err)
+		}
+		if _, err := repo.Pool.Exec(ctx, `INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'member')`, workspaceID, *id); err != nil {
+			t.Fatalf("member: %v", err)
+		}
+	}
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO tasks (workspace_id, title, status) VALUES ($1, 'Clean room', 'open') RETURNING id`, workspaceID).Scan(&taskID); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	var uploadIDs []string
+	for _, key := range []string{workspaceID + "/before.jpg", workspaceID + "/after.jpg"} {
+		id, err := repo.CreateUpload(ctx, workspaceID, uploaderID, key, "image/jpeg", 10, nil, nil, "photo.jpg")
+		if err != nil {
+			t.Fatalf("upload: %v", err)
+		}
+		uploadIDs = append(uploadIDs, id)
+	}
+	if valid, err := repo.AreWorkspaceUploads(ctx, workspaceID, append(uploadIDs, otherID)); err != nil || valid {
+		t.Fatalf("expected a foreign id to be rejected, got %v err=%v", valid, err)
+	}
+	if err := repo.SetAttachments(ctx, "task", taskID, workspaceID, []string{uploadIDs[1], uploadIDs[0]}); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	tasks, err := repo.ListTasks(ctx, workspaceID, TaskFilter{}, PageRequest{})
+	if err != nil || len(tasks.Items) != 1 {
+		t.Fatalf("list: %v err=%v", tasks, err)
+	}
+	if got := tasks.Items[0]["attachment_ids"].([]string); len(got) != 2 || got[0] != uploadIDs[1] || got[1] != uploadIDs[0] {
+		t.Fatalf("expected attachments in request order, got %v", got)
+	}
+
+	if _, err := repo.DeleteUpload(ctx, uploadIDs[0], workspaceID, otherID); !errors.Is(err, ErrNotAuthor) {
+		t.Fatalf("expected ErrNotAuthor, got %v", err)
+	}
+	key, err := repo.DeleteUpload(ctx, uploadIDs[0], workspaceID, uploaderID)
+	if err != nil || key != workspaceID+"/before.jpg" {
+		t.Fatalf("expected the storage key back, got %q err=%v", key, err)
+	}
+	var version, attached int
+	if err := repo.Pool.QueryRow(ctx, `SELECT version, (SELECT count(*) FROM attachments WHERE entity_id = tasks.id) FROM tasks WHERE id=$1`, taskID).Scan(&version, &attached); err != nil {
+		t.Fatalf("task: %v", err)
+	}
+	if version != 3 || attached != 1 {
+		t.Fatalf("expected the task bumped twice with one attachment left, got version %d and %d attachments", version, attached)
+	}
+}
+
+func TestSearchRanksAcrossEntities(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Home', 'shared') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	inserts := []string{
+		`INSERT INTO tasks (workspace_id, title, description, status) VALUES ($1, 'Купить молоко', 'в магазине у дома', 'open')`,
+		`INSERT INTO tasks (workspace_id, title, description, status) VALUES ($1, 'Walk the dog', 'then buy <b>milk</b> on the way back', 'open')`,
+		`INSERT INTO tasks (workspace_id, title, description, status, deleted_at) VALUES ($1, 'Buy milk again', '', 'open', now())`,
+		`INSERT INTO goals (workspace_id, title, description) VALUES ($1, 'Milk-free month', '')`,
+		`INSERT INTO rewards (workspace_id, title, description, cost) VALUES ($1, 'Молочный коктейль', '', 5)`,
+	}
+	for _, insert := range inserts {
+		if _, err := repo.Pool.Exec(ctx, insert, workspaceID); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	results, total, err := repo.Search(ctx, workspaceID, "milk", SearchEntities, 10)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if total != 2 || len(results) != 2 {
+		t.Fatalf("expected the live goal and task to match, got %d: %v", total, results)
+	}
+	if results[0]["entity_type"] != "goal" {
+		t.Fatalf("expected the title match to rank first, got %v", results[0])
+	}
+	if snippet := results[1]["snippet"].(string); !strings.Contains(snippet, "<mark>milk</mark>") || strings.Contains(snippet, "<b>") {
+		t.Fatalf("expected an escaped, highlighted snippet, got %q", snippet)
+	}
+
+	results, total, err = repo.Search(ctx, workspaceID, "молоко", []string{"task"}, 10)
+	if err != nil || total != 1 || results[0]["title_highlight"] != "Купить <mark>молоко</mark>" {
+		t.Fatalf("expected the Russian task, got %v err=%v", results, err)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := highlight("a <b> " + highlightStart + "milk" + highlightStop + " & co")
+	if want := "a &lt;b&gt; <mark>milk</mark> &amp; co"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestListGoalsPagination(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var workspaceID string
+	if err := repo.Pool.QueryRow(ctx, `INSERT INTO workspaces (name, type) VALUES ('Home', 'shared') RETURNING id`).Scan(&workspaceID); err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	// Two goals share a title so the id has to break the tie.
+	for _, title := range []string{"b", "a", "d", "b", "c"} {
+		if _, err := repo.Pool.Exec(ctx, `INSERT INTO goals (workspace_id, title) VALUES ($1, $2)`, workspaceID, title); err != nil {
+			t.Fatalf("goal: %v", err)
+		}
+	}
+
+	var titles []string
+	page := PageRequest{Limit: 2, Sort: "-title"}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		res, err := repo.ListGoals(ctx, workspaceID, page)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if res.Total != 5 {
+			t.Fatalf("expected a total of 5, got %d", res.Total)
+		}
+		for _, goal := range res.Items {
+			titles = append(titles, goal["title"].(string))
+		}
+		if res.NextCursor == nil {
+			break
+		}
+		page.Cursor = *res.NextCursor
+	}
+	if strings.Join(titles, "") != "dcbba" {
+		t.Fatalf("expected every goal once in descending title order, got %v", titles)
+	}
+
+	if _, err := repo.ListGoals(ctx, workspaceID, PageRequest{Sort: "version"}); !errors.Is(err, ErrInvalidSort) {
+		t.Fatalf("expected ErrInvalidSort, got %v", err)
+	}
+	first, err := repo.ListGoals(ctx, workspaceID, PageRequest{Limit: 1})
+	if err != nil || first.NextCursor == nil {
+		t.Fatalf("first page: %v err=%v", first, err)
+	}
+	if _, err := repo.ListGoals(ctx, workspaceID, PageRequest{Sort: "title", Cursor: *first.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected a cursor from another sort to be rejected, got %v", err)
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	c := pageCursor{Sort: "-created_at", Value: "2024-01-01 10:00:00+00", ID: "3f1c"}
+	decoded, err := decodeCursor(encodeCursor(c))
+	if err != nil || decoded != c {
+		t.Fatalf("expected %v back, got %v err=%v", c, decoded, err)
+	}
+	for _, raw := range []string{"not base64!", "e30", "bm90IGpzb24"} {
+		if _, err := decodeCursor(raw); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("expected ErrInvalidCursor for %q, got %v", raw, err)
+		}
+	}
+}
+
+func TestMentionHandles(t *testing.T) {
+	got := mentionHandles("@Alice can you and @bob@example.com check? mail carol@example.com, thanks @alice.")
+	want := []string{"alice", "bob@example.com"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := mentionHandles("no mentions here"); len(got) != 0 {
+		t.Fatalf("expected no handles, got %v", got)
+	}
+}
+
+func TestStreakBonus(t *testing.T) {
+	if bonus := streakBonus(10, 10, 15, 7); bonus != 2 {
+		t.Fatalf("expected 2 weeks of 10%% on 10, got %v", bonus)
+	}
+	if bonus := streakBonus(10, 10, 6, 7); bonus != 0 {
+		t.Fatalf("partial week should not earn a bonus, got %v", bonus)
+	}
+	if bonus := streakBonus(10, 50, 70, 7); bonus != 10 {
+		t.Fatalf("bonus should be capped at value, got %v", bonus)
+	}
+}
+
 func TestBuyRewardInsufficientFunds(t *testing.T) {
 	repo, cleanup := setupTestRepo(t)
 	defer cleanup()
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+	"html"
+	"strings"
+)
+
+// SearchEntities lists the entity types search covers, in the order their
+// tables are queried.
+var SearchEntities = []string{"goal", "task", "reward", "achievement"}
+
+// Highlight markers wrap matched words inside ts_headline output. They are
+// private-use characters so that they survive HTML escaping of the text
+// around them and cannot be confused with user content.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+const (
+	titleHeadlineOptions   = "HighlightAll=true, StartSel=" + highlightStart + ", StopSel=" + highlightStop
+	snippetHeadlineOptions = "MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=\" … \", StartSel=" + highlightStart + ", StopSel=" + highlightStop
+)
+
+// searchBranch selects the live rows of one table matching the query.
+func searchBranch(entityType, table string) string {
+	return `SELECT '` + entityType + `' AS entity_type, t.id, t.title, coalesce(t.description, '') AS description, ts_rank_cd(t.search_vector, q.query) AS rank, t.updated_at
+		FROM ` + table + ` t, q WHERE '` + entityType + `' = ANY($3::text[]) AND t.workspace_id = $1 AND t.deleted_at IS NULL AND t.search_vector @@ q.query`
+}
+
+var searchQuery = `WITH q AS (SELECT websearch_to_tsquery('russian', $2) || websearch_to_tsquery('english', $2) AS query),
+	hits AS (
+		SELECT *, count(*) OVER () AS total FROM (
+			` + searchBranch("goal", "goals") + `
+			UNION ALL ` + searchBranch("task", "tasks") + `
+			UNION ALL ` + searchBranch("reward", "rewards") + `
+			UNION ALL ` + searchBranch("achievement", "achievements") + `
+		) matches
+		ORDER BY rank DESC, updated_at DESC, id
+		LIMIT $4
+	)
+	SELECT h.entity_type, h.id, h.title, h.rank, h.total,
+		ts_headline('russian', h.title, q.query, $5),
+		ts_headline('russian', h.description, q.query, $6)
+	FROM hits h, q
+	ORDER BY h.rank DESC, h.updated_at DESC, h.id`
+
+// Search ranks the workspace's goals, tasks, rewards and achievements of the
+// given types against a web-style query (quoted phrases, OR, -exclusions)
+// and returns at most limit hits plus the total number of matches. Titles and
+// snippets come back HTML-escaped with matches wrapped in <mark>.
+func (r *Repo) Search(ctx context.Context, workspaceID, query string, types []string, limit int) ([]map[string]any, int, error) {
+	rows, err := r.Pool.Query(ctx, searchQuery, workspaceID, query, types, limit, titleHeadlineOptions, snippetHeadlineOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	res := []map[string]any{}
+	total := 0
+	for rows.Next() {
+		var entityType, id, title, titleHeadline, snippet string
+		var rank float32
+		var count int64
+		if err := rows.Scan(&entityType, &id, &title, &rank, &count, &titleHeadline, &snippet); err != nil {
+			return nil, 0, err
+		}
+		total = int(count)
+		res = append(res, map[string]any{
+			"entity_type": entityType, "id": id, "title": title, "rank": rank,
+			"title_highlight": highlight(titleHeadline), "snippet": highlight(snippet),
+		})
+	}
+	return res, total, rows.Err()
+}
+
+// highlight escapes ts_headline output for HTML and turns the markers into
+// <mark> tags.
+func highlight(headline string) string {
+	escaped := html.EscapeString(headline)
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(escaped)
+}
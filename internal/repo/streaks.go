@@ -0,0 +1,198 @@
+package repo
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxStreakBonusRatio caps the streak bonus at this fraction of the task value.
+const maxStreakBonusRatio = 1.0
+
+type Streak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+type streakTask struct {
+	id       string
+	timezone *string
+}
+
+// streaksQuery computes the streaks of the tasks in $1, whose current dates
+// are in $2, without loading their history. It lists the scheduled days from
+// the later of the task start and its first completion to the earlier of its
+// end and today. Excused days (skipped or on a workspace vacation) and today,
+// while still pending, neither extend nor break a run; any other day not done
+// starts a new run, so the runs are numbered by the breaks before them.
+const streaksQuery = `WITH days AS (
+		SELECT t.id, day::date AS day, x.today, coalesce(o.status = 'done', false) AS done,
+			coalesce(o.status = 'skipped', false) OR EXISTS (
+				SELECT 1 FROM workspace_vacations v WHERE v.workspace_id = t.workspace_id AND day::date BETWEEN v.start_date AND v.end_date
+			) AS excused
+		FROM unnest($1::uuid[], $2::date[]) AS x(id, today)
+		JOIN tasks t ON t.id = x.id
+		CROSS JOIN LATERAL generate_series(
+			greatest(t.start_date, (SELECT min(occurrence_date) FROM task_occurrences WHERE task_id = t.id AND status = 'done'))::timestamp,
+			least(t.end_date, x.today)::timestamp, interval '1 day') AS day
+		LEFT JOIN task_occurrences o ON o.task_id = t.id AND o.occurrence_date = day::date
+		WHERE extract(dow FROM day)::smallint = ANY(t.recurrence_weekdays)
+	), runs AS (
+		SELECT id, done, count(*) FILTER (WHERE NOT done) OVER (PARTITION BY id ORDER BY day) AS run
+		FROM days WHERE done OR (NOT excused AND day < today)
+	), lengths AS (
+		SELECT id, run, count(*) FILTER (WHERE done)::int AS length FROM runs GROUP BY id, run
+	)
+	SELECT id, (array_agg(length ORDER BY run DESC))[1], max(length) FROM lengths GROUP BY id`
+
+// loadStreaks computes current and longest streaks for recurring tasks as of
+// now with streaksQuery. Tasks never completed have no streak.
+func (r *Repo) loadStreaks(ctx context.Context, tasks []streakTask, now time.Time) (map[string]Streak, error) {
+	res := make(map[string]Streak, len(tasks))
+	if len(tasks) == 0 {
+		return res, nil
+	}
+	ids := make([]string, 0, len(tasks))
+	todays := make([]time.Time, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.id)
+		todays = append(todays, taskToday(task.timezone, now))
+	}
+	rows, err := r.Pool.Query(ctx, streaksQuery, ids, todays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var streak Streak
+		if err := rows.Scan(&id, &streak.Current, &streak.Longest); err != nil {
+			return nil, err
+		}
+		res[id] = streak
+	}
+	return res, rows.Err()
+}
+
+type queryer interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// settledOccurrences returns completed and excused occurrence dates keyed by
+// task id and YYYY-MM-DD, optionally limited to dates up to until. Skipped
+// occurrences and days within a workspace vacation are excused.
+func settledOccurrences(ctx context.Context, q queryer, taskIDs []string, until *time.Time) (map[string]map[string]bool, map[string]map[string]bool, error) {
+	rows, err := q.Query(ctx, `SELECT task_id, occurrence_date, status FROM task_occurrences
+		WHERE task_id = ANY($1) AND status IN ('done', 'skipped') AND ($2::date IS NULL OR occurrence_date <= $2)
+		UNION ALL
+		SELECT t.id, day::date, 'skipped' FROM tasks t
+		JOIN workspace_vacations v ON v.workspace_id = t.workspace_id
+		CROSS JOIN generate_series(v.start_date, v.end_date, interval '1 day') AS day
+		WHERE t.id = ANY($1) AND ($2::date IS NULL OR v.start_date <= $2)`, taskIDs, until)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	done := map[string]map[string]bool{}
+	excused := map[string]map[string]bool{}
+	for rows.Next() {
+		var taskID, status string
+		var occurrenceDate time.Time
+		if err := rows.Scan(&taskID, &occurrenceDate, &status); err != nil {
+			return nil, nil, err
+		}
+		target := excused
+		if status == "done" {
+			target = done
+		}
+		if target[taskID] == nil {
+			target[taskID] = map[string]bool{}
+		}
+		target[taskID][occurrenceDate.Format("2006-01-02")] = true
+	}
+	return done, excused, rows.Err()
+}
+
+// streakEndingAt counts consecutive completed scheduled days ending at date,
+// passing over excused days.
+func streakEndingAt(weekdays []int, done, excused map[string]bool, startDate *time.Time, date time.Time) int {
+	if len(weekdays) == 0 {
+		return 0
+	}
+	var start time.Time
+	if startDate != nil {
+		start = truncateDate(*startDate)
+	} else if len(done) > 0 {
+		start = earliestDate(done)
+	}
+	count := 0
+	for day := truncateDate(date); !day.Before(start); day = day.AddDate(0, 0, -1) {
+		if !containsWeekday(weekdays, int(day.Weekday())) {
+			continue
+		}
+		key := day.Format("2006-01-02")
+		if excused[key] && !done[key] {
+			continue
+		}
+		if !done[key] {
+			break
+		}
+		count++
+	}
+	return count
+}
+
+// creditStreakBonus credits the streak bonus for a just-completed occurrence as
+// its own 'earn' line and returns the amount.
+func creditStreakBonus(ctx context.Context, tx pgx.Tx, workspaceID, userID, taskID string, value, percent float64, weekdays []int, startDate *time.Time, occurrenceDate time.Time) (float64, error) {
+	done, excused, err := settledOccurrences(ctx, tx, []string{taskID}, &occurrenceDate)
+	if err != nil {
+		return 0, err
+	}
+	streak := streakEndingAt(weekdays, done[taskID], excused[taskID], startDate, occurrenceDate)
+	bonus := streakBonus(value, percent, streak, len(weekdays))
+	if bonus <= 0 {
+		return 0, nil
+	}
+	if err := creditEarn(ctx, tx, workspaceID, userID, bonus, "streak bonus", "task", taskID, &occurrenceDate); err != nil {
+		return 0, err
+	}
+	return bonus, nil
+}
+
+// streakBonus returns the extra fire for completing an occurrence that extends a
+// streak: percent of value for every full week of consecutive occurrences.
+func streakBonus(value, percent float64, streak, perWeek int) float64 {
+	if percent <= 0 || perWeek == 0 {
+		return 0
+	}
+	weeks := streak / perWeek
+	ratio := math.Min(percent/100*float64(weeks), maxStreakBonusRatio)
+	return math.Round(value*ratio*100) / 100
+}
+
+func earliestDate(dates map[string]bool) time.Time {
+	var earliest time.Time
+	for key := range dates {
+		date, err := time.Parse("2006-01-02", key)
+		if err != nil {
+			continue
+		}
+		if earliest.IsZero() || date.Before(earliest) {
+			earliest = date
+		}
+	}
+	return earliest
+}
+
+// taskToday returns the current calendar date in the task's timezone.
+func taskToday(timezone *string, now time.Time) time.Time {
+	if timezone != nil && *timezone != "" {
+		if loc, err := time.LoadLocation(*timezone); err == nil {
+			now = now.In(loc)
+		}
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// taskTagsColumn selects a task's tag ids as a text array.
+const taskTagsColumn = `COALESCE((SELECT array_agg(tt.tag_id::text ORDER BY tt.tag_id) FROM task_tags tt WHERE tt.task_id = tasks.id), '{}') AS tag_ids`
+
+func (r *Repo) CreateTag(ctx context.Context, workspaceID, name string, color *string) (string, error) {
+	var id string
+	err := r.Pool.QueryRow(ctx, `INSERT INTO tags (workspace_id, name, color) VALUES ($1,$2,$3) RETURNING id`, workspaceID, name, color).Scan(&id)
+	if isUniqueViolation(err) {
+		return "", ErrDuplicate
+	}
+	return id, err
+}
+
+func (r *Repo) UpdateTag(ctx context.Context, id, workspaceID, name string, color *string) error {
+	cmd, err := r.Pool.Exec(ctx, `UPDATE tags SET name=$1, color=$2, updated_at=now(), version=version+1 WHERE id=$3 AND workspace_id=$4 AND deleted_at IS NULL`, name, color, id, workspaceID)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteTag soft-deletes a tag and unlinks it from its tasks, bumping their
+// versions, in one transaction.
+func (r *Repo) DeleteTag(ctx context.Context, id, workspaceID string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	cmd, err := tx.Exec(ctx, `UPDATE tags SET deleted_at=now(), updated_at=now(), version=version+1 WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, id, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET updated_at=now(), version=version+1 WHERE id IN (SELECT task_id FROM task_tags WHERE tag_id=$1)`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM task_tags WHERE tag_id=$1`, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *Repo) ListTags(ctx context.Context, workspaceID string) ([]map[string]any, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT id, name, color, created_at, updated_at, deleted_at, version FROM tags WHERE workspace_id=$1 AND deleted_at IS NULL ORDER BY lower(name)`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []map[string]any
+	for rows.Next() {
+		var id, name string
+		var color *string
+		var createdAt, updatedAt time.Time
+		var deletedAt *time.Time
+		var version int
+		if err := rows.Scan(&id, &name, &color, &createdAt, &updatedAt, &deletedAt, &version); err != nil {
+			return nil, err
+		}
+		res = append(res, map[string]any{
+			"id": id, "workspace_id": workspaceID, "name": name, "color": color, "created_at": createdAt, "updated_at": updatedAt, "deleted_at": deletedAt, "version": version,
+		})
+	}
+	return res, rows.Err()
+}
+
+// AreWorkspaceTags reports whether every tag in tagIDs is a live tag of the workspace.
+func (r *Repo) AreWorkspaceTags(ctx context.Context, workspaceID string, tagIDs []string) (bool, error) {
+	if len(tagIDs) == 0 {
+		return true, nil
+	}
+	var count int
+	err := r.Pool.QueryRow(ctx, `SELECT count(*) FROM tags WHERE workspace_id=$1 AND deleted_at IS NULL AND id::text = ANY($2)`, workspaceID, uniqueStrings(tagIDs)).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count == len(uniqueStrings(tagIDs)), nil
+}
+
+// SetTaskTags replaces the tags of a task and bumps its version.
+func (r *Repo) SetTaskTags(ctx context.Context, taskID, workspaceID string, tagIDs []string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setTaskTags(ctx, tx, taskID, workspaceID, tagIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// setTaskTags is SetTaskTags within tx.
+func setTaskTags(ctx context.Context, tx pgx.Tx, taskID, workspaceID string, tagIDs []string) error {
+	cmd, err := tx.Exec(ctx, `UPDATE tasks SET updated_at=now(), version=version+1 WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, taskID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM task_tags WHERE task_id=$1`, taskID); err != nil {
+		return err
+	}
+	for _, tagID := range uniqueStrings(tagIDs) {
+		if _, err := tx.Exec(ctx, `INSERT INTO task_tags (task_id, tag_id) VALUES ($1, $2)`, taskID, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,242 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TimeReportGroups are the groupings TimeReport accepts.
+var TimeReportGroups = map[string]bool{"task": true, "goal": true, "member": true}
+
+// hourlyPayout is value per hour for the tracked seconds, rounded to cents.
+func hourlyPayout(value float64, seconds int64) float64 {
+	return math.Round(value*float64(seconds)/3600*100) / 100
+}
+
+// timedTask loads what starting or logging time on a task needs to check and
+// enforces assignee-only tasks.
+func timedTask(ctx context.Context, tx pgx.Tx, id, workspaceID, userID string, occurrenceDate *time.Time) (*time.Time, error) {
+	var isRecurring, assigneeOnly bool
+	err := tx.QueryRow(ctx, `SELECT is_recurring, assignee_only FROM tasks WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, id, workspaceID).Scan(&isRecurring, &assigneeOnly)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if assigneeOnly {
+		if err := requireAssignee(ctx, tx, id, workspaceID, userID); err != nil {
+			return nil, err
+		}
+	}
+	if !isRecurring {
+		return nil, nil
+	}
+	if occurrenceDate == nil {
+		return nil, ErrOccurrenceDate
+	}
+	return occurrenceDate, nil
+}
+
+// checkTimeEntry fails with ErrTaskDone when the task or occurrence is done
+// and with ErrTimeOverlap when the span from startedAt to endedAt overlaps
+// another of the user's entries, on any task, or their running timer. A nil
+// startedAt means now and a nil endedAt a timer that keeps running. The lock
+// serialises the user's entries so that two overlapping ones cannot both pass.
+func checkTimeEntry(ctx context.Context, tx pgx.Tx, taskID, userID string, occurrenceDate, startedAt, endedAt *time.Time) error {
+	var done bool
+	var err error
+	if occurrenceDate != nil {
+		err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM task_occurrences WHERE task_id=$1 AND occurrence_date=$2 AND status='done')`, taskID, *occurrenceDate).Scan(&done)
+	} else {
+		err = tx.QueryRow(ctx, `SELECT status = 'done' FROM tasks WHERE id=$1`, taskID).Scan(&done)
+	}
+	if err != nil {
+		return err
+	}
+	if done {
+		return ErrTaskDone
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('time_entries:' || $1))`, userID); err != nil {
+		return err
+	}
+	var overlaps bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM time_entries WHERE user_id=$1
+		AND started_at < COALESCE($3::timestamptz, 'infinity') AND COALESCE(ended_at, 'infinity') > COALESCE($2::timestamptz, now()))`,
+		userID, startedAt, endedAt).Scan(&overlaps); err != nil {
+		return err
+	}
+	if overlaps {
+		return ErrTimeOverlap
+	}
+	return nil
+}
+
+// StartTimer starts a running time entry for the user on the task (or one
+// occurrence of a recurring task). It fails with ErrTimerRunning when the
+// user's timer already runs on the task and, like AddTimeEntry, with
+// ErrTaskDone or ErrTimeOverlap, so a user runs at most one timer at a time.
+func (r *Repo) StartTimer(ctx context.Context, taskID, workspaceID, userID string, occurrenceDate *time.Time) (string, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+	occurrenceDate, err = timedTask(ctx, tx, taskID, workspaceID, userID, occurrenceDate)
+	if err != nil {
+		return "", err
+	}
+	var running bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM time_entries WHERE task_id=$1 AND user_id=$2 AND ended_at IS NULL)`, taskID, userID).Scan(&running); err != nil {
+		return "", err
+	}
+	if running {
+		return "", ErrTimerRunning
+	}
+	if err := checkTimeEntry(ctx, tx, taskID, userID, occurrenceDate, nil, nil); err != nil {
+		return "", err
+	}
+	var id string
+	err = tx.QueryRow(ctx, `INSERT INTO time_entries (workspace_id, task_id, user_id, occurrence_date, started_at, source)
+		VALUES ($1,$2,$3,$4,now(),'timer') RETURNING id`, workspaceID, taskID, userID, occurrenceDate).Scan(&id)
+	if isUniqueViolation(err) {
+		return "", ErrTimerRunning
+	}
+	if err != nil {
+		return "", err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// StopTimer stops the user's running timer on the task and returns the entry
+// id and its duration in seconds.
+func (r *Repo) StopTimer(ctx context.Context, taskID, workspaceID, userID string) (string, int64, error) {
+	var id string
+	var seconds int64
+	err := r.Pool.QueryRow(ctx, `UPDATE time_entries SET ended_at=now(), updated_at=now()
+		WHERE task_id=$1 AND workspace_id=$2 AND user_id=$3 AND ended_at IS NULL
+		RETURNING id, extract(epoch FROM ended_at - started_at)::bigint`, taskID, workspaceID, userID).Scan(&id, &seconds)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", 0, ErrNoTimer
+	}
+	return id, seconds, err
+}
+
+// AddTimeEntry records time spent on a task after the fact. It fails with
+// ErrTaskDone on a done task or occurrence, and with ErrTimeOverlap when the
+// span overlaps another of the user's entries, on any task, or their running
+// timer.
+func (r *Repo) AddTimeEntry(ctx context.Context, taskID, workspaceID, userID string, occurrenceDate *time.Time, startedAt, endedAt time.Time, note string) (string, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+	occurrenceDate, err = timedTask(ctx, tx, taskID, workspaceID, userID, occurrenceDate)
+	if err != nil {
+		return "", err
+	}
+	if err := checkTimeEntry(ctx, tx, taskID, userID, occurrenceDate, &startedAt, &endedAt); err != nil {
+		return "", err
+	}
+	var id string
+	if err := tx.QueryRow(ctx, `INSERT INTO time_entries (workspace_id, task_id, user_id, occurrence_date, started_at, ended_at, note, source)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,'manual') RETURNING id`, workspaceID, taskID, userID, occurrenceDate, startedAt, endedAt, note).Scan(&id); err != nil {
+		return "", err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// DeleteTimeEntry removes one of the user's own time entries.
+func (r *Repo) DeleteTimeEntry(ctx context.Context, entryID, taskID, workspaceID, userID string) error {
+	cmd, err := r.Pool.Exec(ctx, `DELETE FROM time_entries WHERE id=$1 AND task_id=$2 AND workspace_id=$3 AND user_id=$4`, entryID, taskID, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repo) ListTimeEntries(ctx context.Context, taskID, workspaceID string) ([]map[string]any, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT id, user_id, occurrence_date, started_at, ended_at, note, source,
+		extract(epoch FROM COALESCE(ended_at, now()) - started_at)::bigint
+		FROM time_entries WHERE task_id=$1 AND workspace_id=$2 ORDER BY started_at DESC`, taskID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []map[string]any
+	for rows.Next() {
+		var id, userID, note, source string
+		var occurrenceDate, endedAt *time.Time
+		var startedAt time.Time
+		var seconds int64
+		if err := rows.Scan(&id, &userID, &occurrenceDate, &startedAt, &endedAt, &note, &source, &seconds); err != nil {
+			return nil, err
+		}
+		var occurrence *string
+		if occurrenceDate != nil {
+			formatted := occurrenceDate.Format("2006-01-02")
+			occurrence = &formatted
+		}
+		res = append(res, map[string]any{
+			"id": id, "task_id": taskID, "user_id": userID, "occurrence_date": occurrence, "started_at": startedAt, "ended_at": endedAt,
+			"running": endedAt == nil, "duration_seconds": seconds, "note": note, "source": source,
+		})
+	}
+	return res, rows.Err()
+}
+
+// TimeReport sums finished time entries that started within [from, to) by task,
+// goal or member.
+func (r *Repo) TimeReport(ctx context.Context, workspaceID, groupBy string, from, to time.Time) ([]map[string]any, error) {
+	var key, label, join string
+	switch groupBy {
+	case "task":
+		key, label, join = "t.id::text", "t.title", ""
+	case "goal":
+		key, label, join = "g.id::text", "g.title", "LEFT JOIN goals g ON g.id = t.goal_id"
+	case "member":
+		key, label, join = "u.id::text", "u.email", "JOIN users u ON u.id = e.user_id"
+	default:
+		return nil, fmt.Errorf("unknown time report grouping %q", groupBy)
+	}
+	rows, err := r.Pool.Query(ctx, `SELECT `+key+`, `+label+`, sum(extract(epoch FROM e.ended_at - e.started_at))::bigint, count(*)
+		FROM time_entries e JOIN tasks t ON t.id = e.task_id `+join+`
+		WHERE e.workspace_id=$1 AND e.ended_at IS NOT NULL AND e.started_at >= $2 AND e.started_at < $3
+		GROUP BY 1, 2 ORDER BY 3 DESC`, workspaceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []map[string]any{}
+	for rows.Next() {
+		var id, name *string
+		var seconds, entries int64
+		if err := rows.Scan(&id, &name, &seconds, &entries); err != nil {
+			return nil, err
+		}
+		res = append(res, map[string]any{"id": id, "name": name, "duration_seconds": seconds, "entries": entries})
+	}
+	return res, rows.Err()
+}
+
+// stopTimersForCompletion stops every running timer on the task (occurrence)
+// being completed and returns the total seconds tracked on it.
+func stopTimersForCompletion(ctx context.Context, tx pgx.Tx, taskID string, occurrenceDate *time.Time) (int64, error) {
+	if _, err := tx.Exec(ctx, `UPDATE time_entries SET ended_at=now(), updated_at=now()
+		WHERE task_id=$1 AND occurrence_date IS NOT DISTINCT FROM $2 AND ended_at IS NULL`, taskID, occurrenceDate); err != nil {
+		return 0, err
+	}
+	var seconds int64
+	err := tx.QueryRow(ctx, `SELECT COALESCE(sum(extract(epoch FROM ended_at - started_at)), 0)::bigint
+		FROM time_entries WHERE task_id=$1 AND occurrence_date IS NOT DISTINCT FROM $2`, taskID, occurrenceDate).Scan(&seconds)
+	return seconds, err
+}
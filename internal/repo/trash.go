@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+// TrashEntities maps the entity types that are soft-deleted and can be
+// restored to their tables.
+var TrashEntities = map[string]string{"goal": "goals", "task": "tasks", "reward": "rewards", "achievement": "achievements"}
+
+// ListTrash returns the workspace's deleted goals, tasks, rewards and
+// achievements, most recently deleted first.
+func (r *Repo) ListTrash(ctx context.Context, workspaceID string) ([]map[string]any, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT 'goal', id, title, deleted_at FROM goals WHERE workspace_id=$1 AND deleted_at IS NOT NULL
+		UNION ALL SELECT 'task', id, title, deleted_at FROM tasks WHERE workspace_id=$1 AND deleted_at IS NOT NULL
+		UNION ALL SELECT 'reward', id, title, deleted_at FROM rewards WHERE workspace_id=$1 AND deleted_at IS NOT NULL
+		UNION ALL SELECT 'achievement', id, title, deleted_at FROM achievements WHERE workspace_id=$1 AND deleted_at IS NOT NULL
+		ORDER BY 4 DESC, 2`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []map[string]any{}
+	for rows.Next() {
+		var entityType, id, title string
+		var deletedAt time.Time
+		if err := rows.Scan(&entityType, &id, &title, &deletedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, map[string]any{"entity_type": entityType, "id": id, "title": title, "deleted_at": deletedAt})
+	}
+	return res, rows.Err()
+}
+
+// RestoreEntity takes a goal, task, reward or achievement out of the trash and
+// bumps its version so that sync picks it up. A restored goal gets back the
+// tasks detached when it was deleted; a restored task whose goal is still in
+// the trash is detached from it in turn. A task whose dependencies would close
+// a cycle with edges added while it was deleted fails with
+// ErrDependencyCycle. It returns ErrNotFound when the entity is not in the
+// trash.
+func (r *Repo) RestoreEntity(ctx context.Context, entityType, id, workspaceID string) error {
+	table, ok := TrashEntities[entityType]
+	if !ok {
+		return ErrNotFound
+	}
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE ` + table + ` SET deleted_at=NULL, updated_at=now(), version=version+1 WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NOT NULL`
+	if entityType == "task" {
+		// Same lock as setTaskDependencies: the cycle check below must not
+		// race a dependency edit.
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('task_dependencies:' || $1))`, workspaceID); err != nil {
+			return err
+		}
+		query = `UPDATE tasks SET deleted_at=NULL, updated_at=now(), version=version+1,
+			goal_id = CASE WHEN EXISTS (SELECT 1 FROM goals g WHERE g.id = tasks.goal_id AND g.deleted_at IS NOT NULL) THEN NULL ELSE goal_id END,
+			detached_goal_id = CASE WHEN EXISTS (SELECT 1 FROM goals g WHERE g.id = tasks.goal_id AND g.deleted_at IS NOT NULL) THEN goal_id ELSE detached_goal_id END
+			WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NOT NULL`
+	}
+	cmd, err := tx.Exec(ctx, query, id, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if entityType == "task" {
+		edges, err := workspaceDependencies(ctx, tx, workspaceID)
+		if err != nil {
+			return err
+		}
+		if dependencyCycle(edges, id, edges[id]) {
+			return ErrDependencyCycle
+		}
+	}
+	if entityType == "goal" {
+		// Tasks moved elsewhere since keep their new goal.
+		if _, err := tx.Exec(ctx, `UPDATE tasks SET goal_id=$1, detached_goal_id=NULL, updated_at=now(), version=version+1
+			WHERE detached_goal_id=$1 AND goal_id IS NULL AND workspace_id=$2`, id, workspaceID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
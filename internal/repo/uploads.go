@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AttachmentEntities maps the entity types uploads can be attached to to their
+// tables.
+var AttachmentEntities = map[string]string{"task": "tasks", "achievement": "achievements", "reward": "rewards"}
+
+// taskAttachmentsColumn, achievementAttachmentsColumn and
+// rewardAttachmentsColumn select the upload ids attached to a row as a text
+// array, in attachment order.
+const (
+	taskAttachmentsColumn        = `COALESCE((SELECT array_agg(a.upload_id::text ORDER BY a.created_at, a.upload_id) FROM attachments a WHERE a.entity_type = 'task' AND a.entity_id = tasks.id), '{}') AS attachment_ids`
+	achievementAttachmentsColumn = `COALESCE((SELECT array_agg(a.upload_id::text ORDER BY a.created_at, a.upload_id) FROM attachments a WHERE a.entity_type = 'achievement' AND a.entity_id = achievements.id), '{}') AS attachment_ids`
+	rewardAttachmentsColumn      = `COALESCE((SELECT array_agg(a.upload_id::text ORDER BY a.created_at, a.upload_id) FROM attachments a WHERE a.entity_type = 'reward' AND a.entity_id = rewards.id), '{}') AS attachment_ids`
+)
+
+// Upload is the stored metadata of an uploaded file.
+type Upload struct {
+	ID           string
+	WorkspaceID  string
+	StorageKey   string
+	ContentType  string
+	Size         int64
+	OriginalName string
+}
+
+func (r *Repo) CreateUpload(ctx context.Context, workspaceID, userID, storageKey, contentType string, size int64, width, height *int, originalName string) (string, error) {
+	var id string
+	err := r.Pool.QueryRow(ctx, `INSERT INTO uploads (workspace_id, uploaded_by, storage_key, content_type, size_bytes, width, height, original_name)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING id`, workspaceID, userID, storageKey, contentType, size, width, height, originalName).Scan(&id)
+	return id, err
+}
+
+func (r *Repo) GetUpload(ctx context.Context, id, workspaceID string) (Upload, error) {
+	upload := Upload{ID: id, WorkspaceID: workspaceID}
+	err := r.Pool.QueryRow(ctx, `SELECT storage_key, content_type, size_bytes, original_name FROM uploads WHERE id=$1 AND workspace_id=$2`, id, workspaceID).
+		Scan(&upload.StorageKey, &upload.ContentType, &upload.Size, &upload.OriginalName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Upload{}, ErrNotFound
+	}
+	return upload, err
+}
+
+func (r *Repo) ListUploads(ctx context.Context, workspaceID string) ([]map[string]any, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT u.id, u.uploaded_by::text, u.content_type, u.size_bytes, u.width, u.height, u.original_name, u.created_at,
+		COALESCE((SELECT jsonb_agg(jsonb_build_object('entity_type', a.entity_type, 'entity_id', a.entity_id)) FROM attachments a WHERE a.upload_id = u.id), '[]')
+		FROM uploads u WHERE u.workspace_id=$1 ORDER BY u.created_at DESC`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []map[string]any
+	for rows.Next() {
+		var id, contentType, originalName string
+		var uploadedBy *string
+		var size int64
+		var width, height *int
+		var createdAt time.Time
+		var attachedTo []map[string]any
+		if err := rows.Scan(&id, &uploadedBy, &contentType, &size, &width, &height, &originalName, &createdAt, &attachedTo); err != nil {
+			return nil, err
+		}
+		res = append(res, map[string]any{
+			"id": id, "workspace_id": workspaceID, "uploaded_by": uploadedBy, "content_type": contentType, "size_bytes": size,
+			"width": width, "height": height, "original_name": originalName, "created_at": createdAt, "attached_to": attachedTo,
+		})
+	}
+	return res, rows.Err()
+}
+
+// DeleteUpload removes an upload and its attachments and returns its storage
+// key so the caller can delete the blob. Uploaders delete their own uploads,
+// workspace owners any upload.
+func (r *Repo) DeleteUpload(ctx context.Context, id, workspaceID, userID string) (string, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+	var storageKey string
+	var allowed bool
+	err = tx.QueryRow(ctx, `SELECT storage_key, uploaded_by IS NOT DISTINCT FROM $3::uuid
+		OR EXISTS(SELECT 1 FROM workspace_members WHERE workspace_id=$2 AND user_id=$3 AND role='owner')
+		FROM uploads WHERE id=$1 AND workspace_id=$2 FOR UPDATE`, id, workspaceID, userID).Scan(&storageKey, &allowed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", ErrNotAuthor
+	}
+	// Entities losing an attachment are bumped so that their attachment_ids sync.
+	for entityType, table := range AttachmentEntities {
+		if _, err := tx.Exec(ctx, `UPDATE `+table+` SET updated_at=now(), version=version+1
+			WHERE id IN (SELECT entity_id FROM attachments WHERE upload_id=$1 AND entity_type=$2)`, id, entityType); err != nil {
+			return "", err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM uploads WHERE id=$1`, id); err != nil {
+		return "", err
+	}
+	return storageKey, tx.Commit(ctx)
+}
+
+// AreWorkspaceUploads reports whether every id is an upload of the workspace.
+func (r *Repo) AreWorkspaceUploads(ctx context.Context, workspaceID string, uploadIDs []string) (bool, error) {
+	if len(uploadIDs) == 0 {
+		return true, nil
+	}
+	var count int
+	err := r.Pool.QueryRow(ctx, `SELECT count(*) FROM uploads WHERE workspace_id=$1 AND id::text = ANY($2)`, workspaceID, uniqueStrings(uploadIDs)).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count == len(uniqueStrings(uploadIDs)), nil
+}
+
+// SetAttachments replaces the uploads attached to a task, achievement or
+// reward and bumps its version.
+func (r *Repo) SetAttachments(ctx context.Context, entityType, entityID, workspaceID string, uploadIDs []string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setAttachments(ctx, tx, entityType, entityID, workspaceID, uploadIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// setAttachments is SetAttachments within tx.
+func setAttachments(ctx context.Context, tx pgx.Tx, entityType, entityID, workspaceID string, uploadIDs []string) error {
+	table, ok := AttachmentEntities[entityType]
+	if !ok {
+		return ErrNotFound
+	}
+	cmd, err := tx.Exec(ctx, `UPDATE `+table+` SET updated_at=now(), version=version+1 WHERE id=$1 AND workspace_id=$2 AND deleted_at IS NULL`, entityID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM attachments WHERE entity_type=$1 AND entity_id=$2`, entityType, entityID); err != nil {
+		return err
+	}
+	// Insert one by one so created_at keeps the requested order.
+	for _, uploadID := range uniqueStrings(uploadIDs) {
+		if _, err := tx.Exec(ctx, `INSERT INTO attachments (upload_id, entity_type, entity_id, created_at)
+			SELECT id, $2, $3, clock_timestamp() FROM uploads WHERE id::text=$1 AND workspace_id=$4`, uploadID, entityType, entityID, workspaceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Message is a push message as decrypted by the FakeService.
+type Message struct {
+	Endpoint string
+	TTL      string
+	Payload  []byte
+}
+
+// FakeService is a local push service for tests and development. It issues
+// subscriptions with real client keys, checks the VAPID signature of every
+// request and decrypts the payload like a browser would.
+type FakeService struct {
+	Server *httptest.Server
+
+	mu       sync.Mutex
+	clients  map[string]*fakeClient
+	received []Message
+}
+
+type fakeClient struct {
+	private *ecdh.PrivateKey
+	auth    []byte
+	gone    bool
+}
+
+// NewFakeService starts a FakeService; call Close when done.
+func NewFakeService() *FakeService {
+	f := &FakeService{clients: map[string]*fakeClient{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+// Close shuts down the underlying server.
+func (f *FakeService) Close() {
+	f.Server.Close()
+}
+
+// Subscribe creates a subscription the way a browser's pushManager would.
+func (f *FakeService) Subscribe() (Subscription, error) {
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return Subscription{}, err
+	}
+	auth := make([]byte, authLen)
+	if _, err := rand.Read(auth); err != nil {
+		return Subscription{}, err
+	}
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return Subscription{}, err
+	}
+	endpoint := f.Server.URL + "/push/" + encode(id)
+	f.mu.Lock()
+	f.clients[endpoint] = &fakeClient{private: private, auth: auth}
+	f.mu.Unlock()
+	return Subscription{Endpoint: endpoint, P256dh: encode(private.PublicKey().Bytes()), Auth: encode(auth)}, nil
+}
+
+// Expire makes the service answer 410 Gone for the endpoint, as when a user
+// revokes notification permission.
+func (f *FakeService) Expire(endpoint string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if client, ok := f.clients[endpoint]; ok {
+		client.gone = true
+	}
+}
+
+// Received returns the messages delivered so far.
+func (f *FakeService) Received() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.received...)
+}
+
+func (f *FakeService) handle(w http.ResponseWriter, r *http.Request) {
+	endpoint := f.Server.URL + r.URL.Path
+	f.mu.Lock()
+	client, ok := f.clients[endpoint]
+	f.mu.Unlock()
+	if !ok || client.gone {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	if err := f.verifyVAPID(r.Header.Get("Authorization")); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+		http.Error(w, "aes128gcm and TTL required", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	payload, err := Decrypt(client.private, client.auth, body)
+	if err != nil {
+		http.Error(w, "decrypt: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.received = append(f.received, Message{Endpoint: endpoint, TTL: r.Header.Get("TTL"), Payload: payload})
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusCreated)
+}
+
+// verifyVAPID checks a "vapid t=<jwt>, k=<key>" header against the service
+// origin.
+func (f *FakeService) verifyVAPID(header string) error {
+	params := map[string]string{}
+	rest, ok := strings.CutPrefix(header, "vapid ")
+	if !ok {
+		return errors.New("vapid authorization required")
+	}
+	for _, part := range strings.Split(rest, ",") {
+		if key, value, found := strings.Cut(strings.TrimSpace(part), "="); found {
+			params[key] = value
+		}
+	}
+	point, err := decode(params["k"])
+	if err != nil {
+		return fmt.Errorf("vapid key: %w", err)
+	}
+	public, err := ecdsaPublicKey(point)
+	if err != nil {
+		return fmt.Errorf("vapid key: %w", err)
+	}
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(params["t"], claims, func(*jwt.Token) (any, error) {
+		return public, nil
+	}, jwt.WithValidMethods([]string{"ES256"}), jwt.WithAudience(f.Server.URL), jwt.WithExpirationRequired())
+	if err != nil {
+		return fmt.Errorf("vapid token: %w", err)
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return errors.New("vapid subject required")
+	}
+	return nil
+}
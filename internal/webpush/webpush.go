@@ -0,0 +1,307 @@
+// Package webpush sends Web Push messages: RFC 8291 payload encryption with
+// the aes128gcm content coding (RFC 8188) and VAPID authentication (RFC 8292).
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"firegoals/internal/netguard"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	recordSize = 4096
+	// MaxPayload is the largest plaintext that fits one record after the
+	// padding delimiter and the GCM tag, within what push services accept.
+	MaxPayload = 3993
+	saltLen    = 16
+	authLen    = 16
+)
+
+var (
+	// ErrGone means the push service no longer knows the subscription; it
+	// should be deleted.
+	ErrGone = errors.New("push subscription gone")
+	// ErrPayloadTooLarge is returned for payloads above MaxPayload.
+	ErrPayloadTooLarge = errors.New("push payload too large")
+)
+
+// Subscription is a browser push subscription: the endpoint and the
+// base64url-encoded client keys from PushSubscription.toJSON().
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// Validate checks that the client keys are a P-256 point and a 16-byte auth
+// secret.
+func (s Subscription) Validate() error {
+	point, err := decode(s.P256dh)
+	if err != nil {
+		return fmt.Errorf("p256dh: %w", err)
+	}
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return fmt.Errorf("p256dh: %w", err)
+	}
+	if auth, err := decode(s.Auth); err != nil || len(auth) != authLen {
+		return errors.New("auth: expected 16 bytes")
+	}
+	return nil
+}
+
+// GenerateVAPIDKeys returns a new P-256 key pair as base64url strings: the
+// uncompressed public point and the private scalar.
+func GenerateVAPIDKeys() (string, string, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return encode(key.PublicKey().Bytes()), encode(key.Bytes()), nil
+}
+
+// Sender delivers encrypted messages to push services.
+type Sender struct {
+	// Client defaults to a netguard client, which only reaches public https
+	// hosts.
+	Client *http.Client
+	// VAPIDPublicKey and VAPIDPrivateKey are base64url, as produced by
+	// GenerateVAPIDKeys.
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	// Subject is a mailto: or https: contact for the push service operator.
+	Subject string
+}
+
+// Send encrypts payload for the subscription and posts it to its endpoint.
+// ttl is how long the push service may hold the message for an offline client.
+func (s *Sender) Send(ctx context.Context, sub Subscription, payload []byte, ttl time.Duration) error {
+	client := s.Client
+	if client == nil {
+		if err := netguard.CheckURL(sub.Endpoint); err != nil {
+			return fmt.Errorf("endpoint: %w", err)
+		}
+		client = netguard.Client(10 * time.Second)
+	}
+	body, err := Encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+	authorization, err := s.vapidHeader(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Urgency", "normal")
+	req.Header.Set("Authorization", authorization)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("push service responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// vapidHeader builds the "vapid t=<jwt>, k=<key>" Authorization value scoped to
+// the endpoint's origin.
+func (s *Sender) vapidHeader(endpoint string) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	key, err := vapidSigningKey(s.VAPIDPrivateKey)
+	if err != nil {
+		return "", err
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": parsed.Scheme + "://" + parsed.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": s.Subject,
+	}).SignedString(key)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + token + ", k=" + s.VAPIDPublicKey, nil
+}
+
+// vapidSigningKey turns a base64url private scalar into an ECDSA key.
+func vapidSigningKey(private string) (*ecdsa.PrivateKey, error) {
+	raw, err := decode(private)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	public, err := ecdsaPublicKey(key.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsa.PrivateKey{PublicKey: *public, D: new(big.Int).SetBytes(raw)}, nil
+}
+
+// ecdsaPublicKey converts an uncompressed P-256 point.
+func ecdsaPublicKey(point []byte) (*ecdsa.PublicKey, error) {
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(point[1:33]),
+		Y:     new(big.Int).SetBytes(point[33:65]),
+	}, nil
+}
+
+// Encrypt encodes payload as a single aes128gcm record for the subscription's
+// keys (RFC 8291 section 3).
+func Encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encrypt(sub, payload, asPrivate, salt)
+}
+
+// encrypt is Encrypt with the application server's ephemeral key and the salt
+// given, which makes the output reproducible for test vectors.
+func encrypt(sub Subscription, payload []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(payload) > MaxPayload {
+		return nil, ErrPayloadTooLarge
+	}
+	if err := sub.Validate(); err != nil {
+		return nil, err
+	}
+	uaPublicBytes, _ := decode(sub.P256dh)
+	uaPublic, _ := ecdh.P256().NewPublicKey(uaPublicBytes)
+	authSecret, _ := decode(sub.Auth)
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+	gcm, nonce, err := contentCipher(sharedSecret, authSecret, salt, uaPublic.Bytes(), asPublic)
+	if err != nil {
+		return nil, err
+	}
+	// A single (last) record: payload, the 0x02 delimiter, no padding.
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	header := make([]byte, 0, saltLen+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// Decrypt reverses Encrypt on the receiving side. It is what a browser does and
+// backs the fake push service used in tests.
+func Decrypt(uaPrivate *ecdh.PrivateKey, authSecret, body []byte) ([]byte, error) {
+	if len(body) < saltLen+5 {
+		return nil, errors.New("body too short")
+	}
+	salt := body[:saltLen]
+	idLen := int(body[saltLen+4])
+	if len(body) < saltLen+5+idLen {
+		return nil, errors.New("body too short")
+	}
+	asPublicBytes := body[saltLen+5 : saltLen+5+idLen]
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := uaPrivate.ECDH(asPublic)
+	if err != nil {
+		return nil, err
+	}
+	gcm, nonce, err := contentCipher(sharedSecret, authSecret, salt, uaPrivate.PublicKey().Bytes(), asPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, body[saltLen+5+idLen:], nil)
+	if err != nil {
+		return nil, err
+	}
+	plaintext = bytes.TrimRight(plaintext, "\x00")
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		return nil, errors.New("missing last-record delimiter")
+	}
+	return plaintext[:len(plaintext)-1], nil
+}
+
+// contentCipher derives the content encryption key and nonce from the ECDH
+// secret, the subscription's auth secret and the record salt.
+func contentCipher(sharedSecret, authSecret, salt, uaPublic, asPublic []byte) (cipher.AEAD, []byte, error) {
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, authSecret, keyInfo), ikm); err != nil {
+		return nil, nil, err
+	}
+	cek := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: aes128gcm\x00")), cek); err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, 12)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gcm, nonce, nil
+}
+
+func encode(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+// decode accepts base64url with or without padding, as browsers differ.
+func decode(value string) ([]byte, error) {
+	if raw, err := base64.RawURLEncoding.DecodeString(value); err == nil {
+		return raw, nil
+	}
+	return base64.URLEncoding.DecodeString(value)
+}
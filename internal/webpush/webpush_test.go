@@ -0,0 +1,89 @@
+package webpush
+
+import (
+	"context"
+	"crypto/ecdh"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSenderDeliversToFakeService(t *testing.T) {
+	service := NewFakeService()
+	defer service.Close()
+	public, private, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("keys: %v", err)
+	}
+	sender := &Sender{Client: service.Server.Client(), VAPIDPublicKey: public, VAPIDPrivateKey: private, Subject: "mailto:ops@example.com"}
+	sub, err := service.Subscribe()
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	if err := sender.Send(context.Background(), sub, []byte(`{"title":"Task approved"}`), time.Hour); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	received := service.Received()
+	if len(received) != 1 || string(received[0].Payload) != `{"title":"Task approved"}` || received[0].TTL != "3600" {
+		t.Fatalf("unexpected messages %+v", received)
+	}
+
+	service.Expire(sub.Endpoint)
+	if err := sender.Send(context.Background(), sub, []byte("again"), time.Hour); !errors.Is(err, ErrGone) {
+		t.Fatalf("expected ErrGone, got %v", err)
+	}
+}
+
+func TestSenderRejectedWithWrongVAPIDKey(t *testing.T) {
+	service := NewFakeService()
+	defer service.Close()
+	public, _, _ := GenerateVAPIDKeys()
+	_, otherPrivate, _ := GenerateVAPIDKeys()
+	sender := &Sender{Client: service.Server.Client(), VAPIDPublicKey: public, VAPIDPrivateKey: otherPrivate, Subject: "mailto:ops@example.com"}
+	sub, _ := service.Subscribe()
+	if err := sender.Send(context.Background(), sub, []byte("hi"), time.Minute); err == nil || errors.Is(err, ErrGone) {
+		t.Fatalf("expected signature rejection, got %v", err)
+	}
+}
+
+func TestEncryptRejectsOversizedPayload(t *testing.T) {
+	service := NewFakeService()
+	defer service.Close()
+	sub, _ := service.Subscribe()
+	if _, err := Encrypt(sub, make([]byte, MaxPayload+1)); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("expected ErrPayloadTooLarge, got %v", err)
+	}
+}
+
+// TestEncryptMatchesRFC8291Vector checks the example in RFC 8291 Appendix A,
+// with its server key and salt in place of random ones.
+func TestEncryptMatchesRFC8291Vector(t *testing.T) {
+	sub := Subscription{
+		Endpoint: "https://push.example.net/push/JzLQ3raZJfFBR0aqvOMsLrt54w4rJUsV",
+		P256dh:   "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4",
+		Auth:     "BTBZMqHH6r4Tts7J_aSIgg",
+	}
+	asPrivateBytes, _ := decode("yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw")
+	asPrivate, err := ecdh.P256().NewPrivateKey(asPrivateBytes)
+	if err != nil {
+		t.Fatalf("server key: %v", err)
+	}
+	salt, _ := decode("DGv6ra1nlYgDCS1FRnbzlw")
+	const want = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+
+	body, err := encrypt(sub, []byte("When I grow up, I want to be a watermelon"), asPrivate, salt)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if got := encode(body); got != want {
+		t.Fatalf("ciphertext mismatch\n got %s\nwant %s", got, want)
+	}
+
+	uaPrivateBytes, _ := decode("q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94")
+	uaPrivate, _ := ecdh.P256().NewPrivateKey(uaPrivateBytes)
+	authSecret, _ := decode(sub.Auth)
+	if plaintext, err := Decrypt(uaPrivate, authSecret, body); err != nil || string(plaintext) != "When I grow up, I want to be a watermelon" {
+		t.Fatalf("decrypt: %q, %v", plaintext, err)
+	}
+}
@@ -0,0 +1,9 @@
+-- Task completion undo: reversal transactions reference the earn they compensate.
+-- occurrence_date lets a reversal find the earn of a specific recurring occurrence.
+
+ALTER TABLE transactions
+  ADD COLUMN IF NOT EXISTS occurrence_date date NULL,
+  ADD COLUMN IF NOT EXISTS reverses_transaction_id uuid NULL REFERENCES transactions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_entity ON transactions (entity_type, entity_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reverses ON transactions (reverses_transaction_id) WHERE reverses_transaction_id IS NOT NULL;
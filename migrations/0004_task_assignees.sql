@@ -0,0 +1,14 @@
+-- Task assignment: one or many workspace members per task.
+-- assignee_only restricts completion to assignees and workspace owners.
+
+CREATE TABLE IF NOT EXISTS task_assignees (
+  task_id uuid REFERENCES tasks(id) ON DELETE CASCADE,
+  user_id uuid REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (task_id, user_id)
+);
+
+ALTER TABLE tasks
+  ADD COLUMN IF NOT EXISTS assignee_only boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_task_assignees_user ON task_assignees (user_id);
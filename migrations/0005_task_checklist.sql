@@ -0,0 +1,22 @@
+-- Checklist items (subtasks) under a task, with their own order, done state and
+-- optional fire value credited through the transactions ledger.
+
+CREATE TABLE IF NOT EXISTS task_checklist_items (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  task_id uuid REFERENCES tasks(id) ON DELETE CASCADE,
+  title text NOT NULL,
+  position int NOT NULL DEFAULT 0,
+  done boolean NOT NULL DEFAULT false,
+  done_at timestamptz NULL,
+  value numeric(10,2) NOT NULL DEFAULT 0 CHECK (value >= 0),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  deleted_at timestamptz NULL,
+  version int NOT NULL DEFAULT 1
+);
+
+ALTER TABLE tasks
+  ADD COLUMN IF NOT EXISTS require_checklist boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_task_checklist_items_task ON task_checklist_items (task_id, position);
+CREATE INDEX IF NOT EXISTS idx_task_checklist_items_updated ON task_checklist_items (updated_at);
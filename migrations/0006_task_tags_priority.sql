@@ -0,0 +1,28 @@
+-- Workspace-scoped tags, task priority and indexes for server-side task filters.
+
+CREATE TABLE IF NOT EXISTS tags (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  workspace_id uuid REFERENCES workspaces(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  color text NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  deleted_at timestamptz NULL,
+  version int NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS task_tags (
+  task_id uuid REFERENCES tasks(id) ON DELETE CASCADE,
+  tag_id uuid REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (task_id, tag_id)
+);
+
+-- 0 = none, 1 = low, 2 = medium, 3 = high
+ALTER TABLE tasks
+  ADD COLUMN IF NOT EXISTS priority smallint NOT NULL DEFAULT 0 CHECK (priority BETWEEN 0 AND 3);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_workspace_name ON tags (workspace_id, lower(name)) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_tags_workspace_updated ON tags (workspace_id, updated_at);
+CREATE INDEX IF NOT EXISTS idx_task_tags_tag ON task_tags (tag_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_workspace_priority ON tasks (workspace_id, priority);
+CREATE INDEX IF NOT EXISTS idx_tasks_workspace_goal ON tasks (workspace_id, goal_id);
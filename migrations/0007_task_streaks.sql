@@ -0,0 +1,7 @@
+-- Optional streak bonus for recurring tasks: percent of value credited extra per
+-- full week of consecutive completions (recorded as a separate 'earn').
+
+ALTER TABLE tasks
+  ADD COLUMN IF NOT EXISTS streak_bonus_percent numeric(5,2) NOT NULL DEFAULT 0 CHECK (streak_bonus_percent >= 0);
+
+CREATE INDEX IF NOT EXISTS idx_task_occurrences_done ON task_occurrences (task_id, occurrence_date) WHERE done;
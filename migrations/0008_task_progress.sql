@@ -0,0 +1,15 @@
+-- Measurable tasks: a target amount with a unit. Logged progress pays value in
+-- proportion to target, capped at max_payout_percent (above 100 rewards
+-- overachievement). One-off tasks keep progress on the task row, recurring
+-- tasks per occurrence; paid tracks how much of the payout was credited.
+
+ALTER TABLE tasks
+  ADD COLUMN IF NOT EXISTS target numeric(12,2) CHECK (target > 0),
+  ADD COLUMN IF NOT EXISTS unit text,
+  ADD COLUMN IF NOT EXISTS max_payout_percent numeric(6,2) NOT NULL DEFAULT 100 CHECK (max_payout_percent >= 100),
+  ADD COLUMN IF NOT EXISTS progress numeric(12,2) NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS progress_paid numeric(10,2) NOT NULL DEFAULT 0;
+
+ALTER TABLE task_occurrences
+  ADD COLUMN IF NOT EXISTS progress numeric(12,2) NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS paid numeric(10,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- Opt-in penalties for missed occurrences of recurring tasks. A background job
+-- marks occurrences whose day has ended undone as missed and debits the task's
+-- penalty from the workspace balance. penalty_since keeps enabling a penalty
+-- from charging for days missed before it was switched on.
+
+ALTER TABLE tasks
+  ADD COLUMN IF NOT EXISTS penalty numeric(10,2) NOT NULL DEFAULT 0 CHECK (penalty >= 0),
+  ADD COLUMN IF NOT EXISTS penalty_since timestamptz NULL;
+
+ALTER TABLE task_occurrences
+  ADD COLUMN IF NOT EXISTS missed boolean NOT NULL DEFAULT false;
+
+-- At most one penalty per occurrence, whichever instance gets there first.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_penalty_once
+  ON transactions (entity_id, occurrence_date) WHERE type = 'penalty';
+
+CREATE INDEX IF NOT EXISTS idx_tasks_penalty ON tasks (id) WHERE penalty > 0 AND is_recurring AND deleted_at IS NULL;
@@ -0,0 +1,17 @@
+-- History of background job runs. The unique (job, scheduled_at) pair lets only
+-- one server instance claim each scheduled slot of a job.
+
+CREATE TABLE IF NOT EXISTS job_runs (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  job text NOT NULL,
+  scheduled_at timestamptz NOT NULL,
+  started_at timestamptz NOT NULL DEFAULT now(),
+  finished_at timestamptz NULL,
+  status text NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'succeeded', 'failed')),
+  detail text NOT NULL DEFAULT '',
+  UNIQUE (job, scheduled_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_started ON job_runs (job, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions (expires_at);
+CREATE INDEX IF NOT EXISTS idx_workspace_invites_expires ON workspace_invites (expires_at);
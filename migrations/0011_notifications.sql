@@ -0,0 +1,47 @@
+-- Due-date reminders and the notification outbox.
+-- A reminder fires either at a clock time on the due day (at_time) or a number
+-- of minutes before the end of the due day (minutes_before), in the task's
+-- timezone. Recurring tasks are reminded for every scheduled day.
+
+CREATE TABLE IF NOT EXISTS task_reminders (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  task_id uuid NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  at_time time NULL,
+  minutes_before int NULL CHECK (minutes_before BETWEEN 0 AND 10080),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  CHECK ((at_time IS NULL) <> (minutes_before IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_reminders_task ON task_reminders (task_id);
+
+-- Per-user delivery preferences. Quiet hours are local to timezone and may wrap
+-- past midnight (22:00-07:00).
+CREATE TABLE IF NOT EXISTS notification_settings (
+  user_id uuid PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  timezone text NULL,
+  quiet_hours_start time NULL,
+  quiet_hours_end time NULL,
+  email_enabled boolean NOT NULL DEFAULT false,
+  webhook_url text NULL,
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- One row per recipient and channel. dedupe_key keeps the scheduler from
+-- enqueueing the same reminder twice.
+CREATE TABLE IF NOT EXISTS notification_outbox (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  channel text NOT NULL,
+  kind text NOT NULL,
+  dedupe_key text NOT NULL,
+  payload jsonb NOT NULL DEFAULT '{}',
+  status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+  attempts int NOT NULL DEFAULT 0,
+  next_attempt_at timestamptz NOT NULL DEFAULT now(),
+  last_error text NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  sent_at timestamptz NULL,
+  UNIQUE (user_id, channel, dedupe_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox (next_attempt_at) WHERE status = 'pending';
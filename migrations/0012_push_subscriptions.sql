@@ -0,0 +1,25 @@
+-- Web Push subscriptions and the server's VAPID key pair.
+-- A browser subscription is identified by its endpoint; re-subscribing the same
+-- endpoint (for another user after a logout, or with rotated keys) replaces it.
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  endpoint text NOT NULL UNIQUE,
+  p256dh text NOT NULL,
+  auth text NOT NULL,
+  user_agent text NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  last_used_at timestamptz NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions (user_id);
+
+-- At most one row: the key pair generated on first start when VAPID_PUBLIC_KEY
+-- and VAPID_PRIVATE_KEY are not configured, shared by every replica.
+CREATE TABLE IF NOT EXISTS vapid_keys (
+  id smallint PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+  public_key text NOT NULL,
+  private_key text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,26 @@
+-- Time tracking on tasks. Entries come from start/stop timers or are logged by
+-- hand; an entry without ended_at is a running timer. Recurring tasks track
+-- time per occurrence.
+-- pay_per_hour makes completion pay value per hour tracked instead of value.
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS pay_per_hour boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS time_entries (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  workspace_id uuid NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  task_id uuid NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  occurrence_date date NULL,
+  started_at timestamptz NOT NULL,
+  ended_at timestamptz NULL,
+  note text NOT NULL DEFAULT '',
+  source text NOT NULL DEFAULT 'manual' CHECK (source IN ('timer', 'manual')),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_time_entries_task ON time_entries (task_id, occurrence_date);
+CREATE INDEX IF NOT EXISTS idx_time_entries_workspace_started ON time_entries (workspace_id, started_at);
+-- One running timer per user and task.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries (task_id, user_id) WHERE ended_at IS NULL;
@@ -0,0 +1,13 @@
+-- Blocked-by relationships between tasks of one workspace. A task cannot be
+-- completed while a task blocking it is not done, unless forced. The API
+-- rejects edges that would form a cycle.
+
+CREATE TABLE IF NOT EXISTS task_dependencies (
+  task_id uuid NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  blocked_by_id uuid NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (task_id, blocked_by_id),
+  CHECK (task_id <> blocked_by_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_blocked_by ON task_dependencies (blocked_by_id);
@@ -0,0 +1,29 @@
+-- Occurrences of recurring tasks carry a status instead of the done/missed
+-- booleans. A skipped occurrence is excused: it is neither charged as missed
+-- nor breaks a streak. Workspace vacations excuse every occurrence in the range.
+
+ALTER TABLE task_occurrences
+  ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'done', 'skipped', 'missed')),
+  ADD COLUMN IF NOT EXISTS skip_reason text NULL,
+  ADD COLUMN IF NOT EXISTS skipped_by uuid NULL REFERENCES users(id) ON DELETE SET NULL;
+
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'task_occurrences' AND column_name = 'done') THEN
+    UPDATE task_occurrences SET status = CASE WHEN done THEN 'done' WHEN missed THEN 'missed' ELSE 'pending' END;
+    ALTER TABLE task_occurrences DROP COLUMN done, DROP COLUMN missed;
+  END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS workspace_vacations (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  workspace_id uuid NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  start_date date NOT NULL,
+  end_date date NOT NULL,
+  reason text NOT NULL DEFAULT '',
+  created_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  CHECK (end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_vacations_range ON workspace_vacations (workspace_id, start_date, end_date);
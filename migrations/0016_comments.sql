@@ -0,0 +1,31 @@
+-- Discussion threads on tasks and goals. Comments are soft-deleted so that sync
+-- clients learn about deletions; every edit keeps the previous body in
+-- comment_edits. mention_ids records the members notified by @mentions.
+
+CREATE TABLE IF NOT EXISTS comments (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  workspace_id uuid NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  entity_type text NOT NULL CHECK (entity_type IN ('task', 'goal')),
+  entity_id uuid NOT NULL,
+  author_id uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+  body text NOT NULL,
+  mention_ids uuid[] NOT NULL DEFAULT '{}',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  edited_at timestamptz NULL,
+  deleted_at timestamptz NULL,
+  version int NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_entity ON comments (entity_type, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_workspace_updated ON comments (workspace_id, updated_at);
+
+CREATE TABLE IF NOT EXISTS comment_edits (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  comment_id uuid NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+  body text NOT NULL,
+  edited_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+  edited_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_edits_comment ON comment_edits (comment_id, edited_at);
@@ -0,0 +1,27 @@
+-- Uploaded files and their attachment to tasks, achievements and rewards. The
+-- blob itself lives in the configured storage backend under storage_key.
+
+CREATE TABLE IF NOT EXISTS uploads (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  workspace_id uuid NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  uploaded_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+  storage_key text NOT NULL UNIQUE,
+  content_type text NOT NULL,
+  size_bytes bigint NOT NULL,
+  width int NULL,
+  height int NULL,
+  original_name text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_workspace ON uploads (workspace_id, created_at);
+
+CREATE TABLE IF NOT EXISTS attachments (
+  upload_id uuid NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+  entity_type text NOT NULL CHECK (entity_type IN ('task', 'achievement', 'reward')),
+  entity_id uuid NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (entity_type, entity_id, upload_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_upload ON attachments (upload_id);
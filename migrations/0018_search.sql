@@ -0,0 +1,28 @@
+-- Full-text search over titles and descriptions. Content mixes Russian and
+-- English, so each document is indexed with both configurations: titles weigh
+-- more (A) than descriptions (B).
+
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('russian', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+  setweight(to_tsvector('russian', coalesce(description, '')), 'B') || setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('russian', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+  setweight(to_tsvector('russian', coalesce(description, '')), 'B') || setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('russian', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+  setweight(to_tsvector('russian', coalesce(description, '')), 'B') || setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+ALTER TABLE achievements ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('russian', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+  setweight(to_tsvector('russian', coalesce(description, '')), 'B') || setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_goals_search ON goals USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_tasks_search ON tasks USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_rewards_search ON rewards USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_achievements_search ON achievements USING gin (search_vector);
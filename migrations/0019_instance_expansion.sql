@@ -0,0 +1,5 @@
+-- Task instances are expanded in SQL: recurring tasks of a workspace are
+-- looked up through a partial index and joined with their occurrences by
+-- (task_id, occurrence_date).
+
+CREATE INDEX IF NOT EXISTS idx_tasks_workspace_recurring ON tasks (workspace_id) WHERE is_recurring AND deleted_at IS NULL;
@@ -0,0 +1,12 @@
+-- Trash: soft-deleted goals, tasks, rewards and achievements can be listed and
+-- restored. Deleting a goal detaches its tasks and remembers the goal in
+-- detached_goal_id so that restoring the goal attaches them again.
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS detached_goal_id uuid NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_detached_goal ON tasks (detached_goal_id) WHERE detached_goal_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_goals_trash ON goals (workspace_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_tasks_trash ON tasks (workspace_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_rewards_trash ON rewards (workspace_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_achievements_trash ON achievements (workspace_id, deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,21 @@
+-- Rescheduling keeps a history instead of overwriting the due date, counts
+-- postponements and can take postpone_decay_percent off the value each time a
+-- task is postponed.
+
+ALTER TABLE tasks
+  ADD COLUMN IF NOT EXISTS postpone_decay_percent numeric(5,2) NOT NULL DEFAULT 0 CHECK (postpone_decay_percent >= 0 AND postpone_decay_percent <= 100),
+  ADD COLUMN IF NOT EXISTS postponed_count int NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS task_reschedules (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  task_id uuid NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  from_date date NULL,
+  to_date date NOT NULL,
+  snooze text NULL,
+  value_before numeric(10,2) NOT NULL,
+  value_after numeric(10,2) NOT NULL,
+  rescheduled_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_reschedules_task ON task_reschedules (task_id, created_at);
@@ -0,0 +1,12 @@
+-- Secret iCalendar feed URLs, one per member and workspace. Only a SHA-256
+-- hash of the token is stored; regenerating replaces it, which revokes the
+-- old URL.
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+  workspace_id uuid NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash text NOT NULL UNIQUE,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  last_used_at timestamptz NULL,
+  PRIMARY KEY (workspace_id, user_id)
+);
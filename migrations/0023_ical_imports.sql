@@ -0,0 +1,11 @@
+-- Tasks imported from iCalendar files remember the UID of the event or todo
+-- they came from, so importing the same file again does not duplicate them.
+
+CREATE TABLE IF NOT EXISTS ical_imports (
+  workspace_id uuid NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  uid text NOT NULL,
+  task_id uuid NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  imported_by uuid NULL REFERENCES users(id) ON DELETE SET NULL,
+  imported_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (workspace_id, uid)
+);